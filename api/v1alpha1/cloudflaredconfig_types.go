@@ -0,0 +1,210 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Protocol is the transport cloudflared connects to the Cloudflare edge with
+// +kubebuilder:validation:Enum=auto;quic;http2
+type Protocol string
+
+const (
+	ProtocolAuto  Protocol = "auto"
+	ProtocolQUIC  Protocol = "quic"
+	ProtocolHTTP2 Protocol = "http2"
+)
+
+// LogLevel is the minimum level of the messages cloudflared logs
+// +kubebuilder:validation:Enum=debug;info;warn;error;fatal
+type LogLevel string
+
+// LogFormat is the format cloudflared writes its logs in
+// +kubebuilder:validation:Enum=default;json
+type LogFormat string
+
+// EdgeIPVersion is the IP version cloudflared connects to the Cloudflare edge with
+// +kubebuilder:validation:Enum=auto;"4";"6"
+type EdgeIPVersion string
+
+// CloudflaredConfigSpec holds the tunnel-level settings of the cloudflared deployment of a Gateway.
+// Unset fields fall back to the cloudflared defaults.
+// https://developers.cloudflare.com/cloudflare-one/connections/connect-networks/configure-tunnels/tunnel-run-parameters/
+type CloudflaredConfigSpec struct {
+	// Protocol is the transport cloudflared connects to the Cloudflare edge with
+	// +kubebuilder:default=auto
+	// +optional
+	Protocol Protocol `json:"protocol,omitempty"`
+
+	// LogLevel is the minimum level of the messages cloudflared logs
+	// +optional
+	LogLevel *LogLevel `json:"logLevel,omitempty"`
+
+	// LogFormat is the format cloudflared writes its logs in
+	// +optional
+	LogFormat *LogFormat `json:"logFormat,omitempty"`
+
+	// LogFile is the name of a file in the log directory of the cloudflared pods that logs are
+	// written to, in addition to stderr
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9._-]+$`
+	// +kubebuilder:validation:MaxLength=253
+	// +optional
+	LogFile *string `json:"logFile,omitempty"`
+
+	// Retries is the maximum number of retries for connection and protocol errors
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Retries *int32 `json:"retries,omitempty"`
+
+	// GracePeriod is how long cloudflared waits for in-flight requests to finish when it is shut down
+	// +optional
+	GracePeriod *metav1.Duration `json:"gracePeriod,omitempty"`
+
+	// HAConnections is the number of connections each cloudflared replica opens to the Cloudflare edge
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	HAConnections *int32 `json:"haConnections,omitempty"`
+
+	// EdgeIPVersion is the IP version cloudflared connects to the Cloudflare edge with
+	// +optional
+	EdgeIPVersion *EdgeIPVersion `json:"edgeIPVersion,omitempty"`
+
+	// Region restricts the Cloudflare data centres cloudflared connects to, leave it unset for the global region
+	// +kubebuilder:validation:Enum=us
+	// +optional
+	Region *string `json:"region,omitempty"`
+
+	// PostQuantum makes cloudflared only use post-quantum key agreements towards the edge, which requires quic
+	// +optional
+	PostQuantum *bool `json:"postQuantum,omitempty"`
+
+	// OriginRequest is the default for the origin settings of every ingress rule of the Gateway.
+	// OriginRequestPolicies and the settings derived from routes override it
+	// +optional
+	OriginRequest *OriginRequest `json:"originRequest,omitempty"`
+
+	// Router deploys an in-cluster router between cloudflared and the Services of the Gateway, which
+	// implements the HTTPRoute features cloudflared can't express, such as header, query parameter and
+	// method matches, weighted backends and request mirroring. Without it those features are ignored
+	// +optional
+	Router *RouterSpec `json:"router,omitempty"`
+
+	// DefaultBackend answers the requests of the Gateway that no route matches, with a 404 when it is unset
+	// +optional
+	DefaultBackend *DefaultBackend `json:"defaultBackend,omitempty"`
+
+	// ReadinessGate holds back the rules of routes whose backend Service has no ready endpoints, such as
+	// routes created before their Deployment is ready, answering their requests with a maintenance response
+	// until the Service has ready endpoints. Rules served by the router are not held back
+	// +optional
+	ReadinessGate *ReadinessGate `json:"readinessGate,omitempty"`
+
+	// Maintenance answers the requests of the Gateway, or of single routes, while they carry the
+	// cloudflare.adamland.xyz/maintenance annotation, with a 503 when it is unset
+	// +optional
+	Maintenance *Maintenance `json:"maintenance,omitempty"`
+
+	// PreviewHostname is a Go template generating a hostname for the HTTPRoutes, GRPCRoutes and TLSRoutes that
+	// attach to a listener of the Gateway without a hostname and set none of their own, such as the route of
+	// a preview environment. It is given the .Route, .Namespace and .Gateway names and the .Domain of the
+	// GatewayClass, e.g. {{.Route}}-{{.Namespace}}.{{.Domain}}, and must generate a hostname within the domain.
+	// Each hostname gets a proxied CNAME record to the tunnel of the Gateway, unless it already has a record
+	// +kubebuilder:validation:MinLength=1
+	// +optional
+	PreviewHostname *string `json:"previewHostname,omitempty"`
+}
+
+// Maintenance holds what answers the requests of a Gateway or route in maintenance, at most one of its fields
+// may be set
+// +kubebuilder:validation:MaxProperties=1
+type Maintenance struct {
+	// Response answers the requests in maintenance with a status code, the test page of cloudflared or a
+	// maintenance Service in the namespace of the Gateway
+	// +optional
+	Response *DefaultBackend `json:"response,omitempty"`
+
+	// Page sends the requests in maintenance to a page hosted outside the cluster, such as a Cloudflare Pages
+	// site, as https://<host>. Requests reach it with its host as Host header
+	// +kubebuilder:validation:Pattern=`^https://[^/]+/?$`
+	// +optional
+	Page *string `json:"page,omitempty"`
+}
+
+// ReadinessGate holds the settings of the readiness gate of a Gateway
+type ReadinessGate struct {
+	// Response answers the requests of the rules held back, with a 503 when it is unset
+	// +optional
+	Response *DefaultBackend `json:"response,omitempty"`
+}
+
+// DefaultBackend is the catch-all of a Gateway, exactly one of its fields must be set
+// +kubebuilder:validation:MinProperties=1
+// +kubebuilder:validation:MaxProperties=1
+type DefaultBackend struct {
+	// StatusCode answers with an empty response of this HTTP status code
+	// +kubebuilder:validation:Minimum=100
+	// +kubebuilder:validation:Maximum=599
+	// +optional
+	StatusCode *int32 `json:"statusCode,omitempty"`
+
+	// HelloWorld answers with the test page of cloudflared when true
+	// +optional
+	HelloWorld *bool `json:"helloWorld,omitempty"`
+
+	// Service sends the requests to a Service in the namespace of the Gateway, such as a branded 404 page
+	// +optional
+	Service *DefaultBackendService `json:"service,omitempty"`
+}
+
+// DefaultBackendService is a port of a Service in the namespace of the Gateway
+type DefaultBackendService struct {
+	// Name is the name of the Service
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+
+	// Port is the port of the Service, which is served over http
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+}
+
+// RouterSpec holds the settings of the in-cluster router of a Gateway
+type RouterSpec struct {
+	// Replicas is the number of router pods
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Image overrides the router image, which defaults to the --router-image flag of the controller
+	// +kubebuilder:validation:MinLength=1
+	// +optional
+	Image *string `json:"image,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:categories=gateway-api
+// +kubebuilder:printcolumn:name="Protocol",type=string,JSONPath=`.spec.protocol`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// CloudflaredConfig is the Schema for the cloudflaredconfigs API. Gateways reference it through
+// spec.infrastructure.parametersRef
+type CloudflaredConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec CloudflaredConfigSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// CloudflaredConfigList contains a list of CloudflaredConfig
+type CloudflaredConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CloudflaredConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CloudflaredConfig{}, &CloudflaredConfigList{})
+}
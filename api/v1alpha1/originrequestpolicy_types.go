@@ -0,0 +1,110 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+)
+
+// ProxyType is the kind of proxy cloudflared runs in front of the origin
+// +kubebuilder:validation:Enum="";socks
+type ProxyType string
+
+const (
+	// ProxyTypeHTTP proxies requests to the origin over HTTP, which is the default
+	ProxyTypeHTTP ProxyType = ""
+	// ProxyTypeSocks makes cloudflared run a SOCKS5 proxy for the clients of the rule
+	ProxyTypeSocks ProxyType = "socks"
+)
+
+// OriginRequest configures how cloudflared connects to an origin. Every field is optional,
+// unset fields are inherited from less specific policies or fall back to the cloudflared defaults.
+// https://developers.cloudflare.com/cloudflare-one/connections/connect-networks/configure-tunnels/local-management/configuration-file/#origin-configuration
+type OriginRequest struct {
+	// ConnectTimeout bounds establishing the TCP connection to the origin
+	// +optional
+	ConnectTimeout *metav1.Duration `json:"connectTimeout,omitempty"`
+	// TLSTimeout bounds the TLS handshake with the origin
+	// +optional
+	TLSTimeout *metav1.Duration `json:"tlsTimeout,omitempty"`
+	// TCPKeepAlive is the keep-alive interval of the TCP connections to the origin
+	// +optional
+	TCPKeepAlive *metav1.Duration `json:"tcpKeepAlive,omitempty"`
+	// NoHappyEyeballs disables falling back from IPv6 to IPv4 when connecting to the origin
+	// +optional
+	NoHappyEyeballs *bool `json:"noHappyEyeballs,omitempty"`
+	// KeepAliveConnections is the maximum number of idle connections kept open to the origin
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	KeepAliveConnections *int32 `json:"keepAliveConnections,omitempty"`
+	// KeepAliveTimeout is how long idle connections to the origin are kept open
+	// +optional
+	KeepAliveTimeout *metav1.Duration `json:"keepAliveTimeout,omitempty"`
+	// HTTPHostHeader sets the Host header of requests to the origin
+	// +optional
+	HTTPHostHeader *string `json:"httpHostHeader,omitempty"`
+	// OriginServerName is the hostname expected on the certificate of the origin
+	// +optional
+	OriginServerName *string `json:"originServerName,omitempty"`
+	// MatchSNIToHost sends the hostname of each request as SNI to the origin
+	// +optional
+	MatchSNIToHost *bool `json:"matchSNItoHost,omitempty"`
+	// NoTLSVerify disables verification of the certificate presented by the origin
+	// +optional
+	NoTLSVerify *bool `json:"noTLSVerify,omitempty"`
+	// DisableChunkedEncoding disables chunked transfer encoding, for origins such as WSGI servers that don't support it
+	// +optional
+	DisableChunkedEncoding *bool `json:"disableChunkedEncoding,omitempty"`
+	// HTTP2Origin makes cloudflared talk HTTP/2 to the origin, which must then be served over https
+	// +optional
+	HTTP2Origin *bool `json:"http2Origin,omitempty"`
+	// ProxyType is the kind of proxy cloudflared runs, use socks for a SOCKS5 proxy
+	// +optional
+	ProxyType *ProxyType `json:"proxyType,omitempty"`
+	// ProxyAddress is the address the proxy of cloudflared listens on
+	// +optional
+	ProxyAddress *string `json:"proxyAddress,omitempty"`
+	// ProxyPort is the port the proxy of cloudflared listens on
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	ProxyPort *int32 `json:"proxyPort,omitempty"`
+}
+
+// OriginRequestPolicySpec defines the desired state of OriginRequestPolicy
+type OriginRequestPolicySpec struct {
+	// TargetRefs are the Gateways, HTTPRoutes and Services in the namespace of the policy it applies to.
+	// Settings are inherited from Gateway to HTTPRoute to Service, the most specific target wins
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=16
+	TargetRefs []gatewayv1alpha2.LocalPolicyTargetReference `json:"targetRefs"`
+
+	// OriginRequest is merged into the originRequest of every ingress rule of the targets
+	OriginRequest OriginRequest `json:"originRequest"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:categories=gateway-api
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// OriginRequestPolicy is the Schema for the originrequestpolicies API
+type OriginRequestPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   OriginRequestPolicySpec      `json:"spec,omitempty"`
+	Status gatewayv1alpha2.PolicyStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// OriginRequestPolicyList contains a list of OriginRequestPolicy
+type OriginRequestPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []OriginRequestPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&OriginRequestPolicy{}, &OriginRequestPolicyList{})
+}
@@ -3,10 +3,20 @@ package main
 import (
 	"crypto/tls"
 	"flag"
+	"fmt"
 	"os"
 
+	cloudflarev1alpha1 "github.com/cyclingwithelephants/cloudflare-gateway-controller/api/v1alpha1"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/k8s"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller/grpc_route"
 	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller/http_route"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller/origin_request_policy"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller/routing"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller/tcp_route"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller/tls_route"
+	gatewaywebhook "github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/webhook"
 
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller/backend_tls_policy"
 	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller/gateway"
 	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller/gateway_class"
 
@@ -18,6 +28,7 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
@@ -25,6 +36,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+	gatewayv1alpha3 "sigs.k8s.io/gateway-api/apis/v1alpha3"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -35,7 +49,11 @@ var (
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
-	utilruntime.Must(gatewayv1.Install(scheme)) // this contains the external API types
+	utilruntime.Must(gatewayv1.Install(scheme))       // this contains the external API types
+	utilruntime.Must(gatewayv1alpha2.Install(scheme)) // experimental channel route kinds e.g. TCPRoute
+	utilruntime.Must(gatewayv1alpha3.Install(scheme)) // experimental channel BackendTLSPolicy
+	utilruntime.Must(gatewayv1beta1.Install(scheme))  // ReferenceGrant
+	utilruntime.Must(cloudflarev1alpha1.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -47,6 +65,7 @@ func main() {
 	var enableHTTP2 bool
 	var tlsOpts []func(*tls.Config)
 	var namespace string
+	var routerImage string
 	flag.StringVar(
 		&metricsAddr,
 		"metrics-bind-address",
@@ -85,6 +104,19 @@ func main() {
 		"default",
 		"the namespace this application is deployed to, and will watch for namespaced resources",
 	)
+	flag.StringVar(
+		&routerImage,
+		"router-image",
+		"",
+		"The image of the routers of Gateways, which is the image of this controller. "+
+			"Without it, Gateways needing a router must set router.image in their CloudflaredConfig",
+	)
+	flag.StringVar(
+		&k8s.ClusterDomain,
+		"cluster-domain",
+		k8s.ClusterDomain,
+		"The DNS domain of the cluster, which Services resolve under as <name>.<namespace>.svc.<domain>",
+	)
 	opts := zap.Options{
 		Development: true,
 	}
@@ -168,19 +200,104 @@ func main() {
 		os.Exit(1)
 	}
 	if err = (&gateway.Reconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:      mgr.GetClient(),
+		Scheme:      mgr.GetScheme(),
+		RouterImage: routerImage,
+		Recorder:    mgr.GetEventRecorderFor("gateway-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Gateway")
 		os.Exit(1)
 	}
 	if err = (&http_route.Reconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("httproute-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "HTTPRoute")
+		os.Exit(1)
+	}
+	// GRPCRoute only joined the standard channel in v1.1.0, so clusters with older CRDs do not serve it
+	if routing.Installed(mgr, &gatewayv1.GRPCRoute{}) {
+		if err = (&grpc_route.Reconciler{
+			Client:   mgr.GetClient(),
+			Scheme:   mgr.GetScheme(),
+			Recorder: mgr.GetEventRecorderFor("grpcroute-controller"),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "GRPCRoute")
+			os.Exit(1)
+		}
+	} else {
+		setupLog.Info("GRPCRoute CRD is not installed, skipping controller", "controller", "GRPCRoute")
+	}
+	// TCPRoute is only part of the experimental channel, so its CRD may not be installed
+	if routing.Installed(mgr, &gatewayv1alpha2.TCPRoute{}) {
+		if err = (&tcp_route.Reconciler{
+			Client:   mgr.GetClient(),
+			Scheme:   mgr.GetScheme(),
+			Recorder: mgr.GetEventRecorderFor("tcproute-controller"),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "TCPRoute")
+			os.Exit(1)
+		}
+	} else {
+		setupLog.Info("TCPRoute CRD is not installed, skipping controller", "controller", "TCPRoute")
+	}
+	// TLSRoute is only part of the experimental channel, so its CRD may not be installed
+	if routing.Installed(mgr, &gatewayv1alpha2.TLSRoute{}) {
+		if err = (&tls_route.Reconciler{
+			Client:   mgr.GetClient(),
+			Scheme:   mgr.GetScheme(),
+			Recorder: mgr.GetEventRecorderFor("tlsroute-controller"),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "TLSRoute")
+			os.Exit(1)
+		}
+	} else {
+		setupLog.Info("TLSRoute CRD is not installed, skipping controller", "controller", "TLSRoute")
+	}
+	// BackendTLSPolicy is only part of the experimental channel, so its CRD may not be installed
+	if routing.Installed(mgr, &gatewayv1alpha3.BackendTLSPolicy{}) {
+		if err = (&backend_tls_policy.Reconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "BackendTLSPolicy")
+			os.Exit(1)
+		}
+	} else {
+		setupLog.Info("BackendTLSPolicy CRD is not installed, skipping controller", "controller", "BackendTLSPolicy")
+	}
+	if err = (&origin_request_policy.Reconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "HTTPRoute")
+		setupLog.Error(err, "unable to create controller", "controller", "OriginRequestPolicy")
 		os.Exit(1)
 	}
+	// the webhooks can be turned off to run the controller locally without certificates
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&gatewaywebhook.GatewayValidator{
+			Client: mgr.GetClient(),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Gateway")
+			os.Exit(1)
+		}
+		routeValidator := &gatewaywebhook.RouteValidator{Client: mgr.GetClient()}
+		for _, route := range []client.Object{
+			&gatewayv1.HTTPRoute{},
+			&gatewayv1.GRPCRoute{},
+			&gatewayv1alpha2.TCPRoute{},
+			&gatewayv1alpha2.TLSRoute{},
+		} {
+			if !routing.Installed(mgr, route) {
+				continue
+			}
+			if err = routeValidator.SetupWithManager(mgr, route); err != nil {
+				setupLog.Error(err, "unable to create webhook", "webhook", fmt.Sprintf("%T", route))
+				os.Exit(1)
+			}
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
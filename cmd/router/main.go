@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/router"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+// The router sits between cloudflared and the Services of a Gateway, and routes requests through the
+// routing table the controller mounts into its pods
+func main() {
+	var listenAddr string
+	var probeAddr string
+	var tablePath string
+	var pollInterval time.Duration
+	flag.StringVar(&listenAddr, "listen-address", ":8080", "The address the router serves requests on.")
+	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.StringVar(&tablePath, "table", "/etc/router/table.json", "The routing table written by the controller.")
+	flag.DurationVar(&pollInterval, "poll-interval", 5*time.Second, "How often the routing table is checked for changes.")
+	opts := zap.Options{}
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	logger := ctrl.Log.WithName("router")
+	ctx := ctrl.SetupSignalHandler()
+
+	r := router.New(logger, http.DefaultTransport)
+	watchErrors := make(chan error, 1)
+	go func() {
+		watchErrors <- r.Watch(ctx, tablePath, pollInterval)
+	}()
+
+	probes := http.NewServeMux()
+	probes.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	servers := []*http.Server{
+		{Addr: listenAddr, Handler: r, ReadHeaderTimeout: 30 * time.Second},
+		{Addr: probeAddr, Handler: probes, ReadHeaderTimeout: 30 * time.Second},
+	}
+	serveErrors := make(chan error, len(servers))
+	for _, server := range servers {
+		go func() {
+			if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				serveErrors <- err
+			}
+		}()
+	}
+
+	exitCode := 0
+	select {
+	case <-ctx.Done():
+	case err := <-watchErrors:
+		if err != nil {
+			logger.Error(err, "failed to load routing table")
+			exitCode = 1
+		}
+	case err := <-serveErrors:
+		logger.Error(err, "failed to serve")
+		exitCode = 1
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	for _, server := range servers {
+		_ = server.Shutdown(shutdownCtx)
+	}
+	cancel()
+	os.Exit(exitCode)
+}
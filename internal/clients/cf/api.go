@@ -9,6 +9,8 @@ import (
 
 const (
 	ConfigSourceLocal = "local"
+	// TunnelDomain is the domain under which every tunnel is reachable as <tunnel id>.cfargotunnel.com
+	TunnelDomain = "cfargotunnel.com"
 )
 
 type Api struct {
@@ -84,6 +86,11 @@ func (api *Api) TunnelExists(tunnelName string) (exists bool, tunnel cloudflare.
 	return true, tunnels[0], nil
 }
 
+// TunnelHostname returns the hostname that DNS records should CNAME to in order to route through the tunnel
+func TunnelHostname(tunnelID string) string {
+	return tunnelID + "." + TunnelDomain
+}
+
 func newTunnelCreateParams(name string, secret string) cloudflare.TunnelCreateParams {
 	return cloudflare.TunnelCreateParams{
 		Name:      name,
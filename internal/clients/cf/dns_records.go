@@ -0,0 +1,91 @@
+package cf
+
+import (
+	"sort"
+
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/pkg/errors"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// dnsRecordCommentPrefix marks the DNS records of a zone that are managed by this controller
+	dnsRecordCommentPrefix = "managed by cloudflare-gateway-controller, "
+)
+
+// DNSRecordOwner returns the comment of the DNS records managed for a Gateway, which tells them apart from the
+// records of other Gateways and the records managed outside the cluster
+func DNSRecordOwner(gatewayNamespace string, gatewayName string) string {
+	return dnsRecordCommentPrefix + edgeRuleRefPrefix + shortHash(gatewayNamespace+"/"+gatewayName)
+}
+
+// SyncDNSRecords makes the DNS records owned by owner in the zone equal to proxied CNAME records from each of
+// hostnames to target. Hostnames that already have a record not owned by owner are left to that record
+func (api *Api) SyncDNSRecords(zoneID string, owner string, target string, hostnames []string) error {
+	zone := cloudflare.ZoneIdentifier(zoneID)
+	owned, _, err := api.Client.ListDNSRecords(api.Ctx, zone, cloudflare.ListDNSRecordsParams{Comment: owner})
+	if err != nil {
+		var forbidden *cloudflare.AuthorizationError
+		if errors.As(err, &forbidden) && len(hostnames) == 0 {
+			// tokens without access to the DNS records of the zone can't have created any records to clean up
+			return nil
+		}
+		return errors.Wrap(err, "failed to list dns records")
+	}
+
+	desired := map[string]bool{}
+	for _, hostname := range hostnames {
+		desired[hostname] = true
+	}
+	existing := map[string]bool{}
+	for _, record := range owned {
+		if !desired[record.Name] || existing[record.Name] {
+			if err := api.Client.DeleteDNSRecord(api.Ctx, zone, record.ID); err != nil {
+				return errors.Wrapf(err, "failed to delete the dns record of %s", record.Name)
+			}
+			continue
+		}
+		existing[record.Name] = true
+		if record.Type == "CNAME" && record.Content == target && ptr.Deref(record.Proxied, false) {
+			continue
+		}
+		if _, err := api.Client.UpdateDNSRecord(api.Ctx, zone, cloudflare.UpdateDNSRecordParams{
+			ID:      record.ID,
+			Type:    "CNAME",
+			Name:    record.Name,
+			Content: target,
+			Proxied: ptr.To(true),
+			Comment: ptr.To(owner),
+		}); err != nil {
+			return errors.Wrapf(err, "failed to update the dns record of %s", record.Name)
+		}
+	}
+
+	missing := make([]string, 0, len(desired))
+	for hostname := range desired {
+		if !existing[hostname] {
+			missing = append(missing, hostname)
+		}
+	}
+	sort.Strings(missing)
+	for _, hostname := range missing {
+		others, _, err := api.Client.ListDNSRecords(api.Ctx, zone, cloudflare.ListDNSRecordsParams{Name: hostname})
+		if err != nil {
+			return errors.Wrapf(err, "failed to list the dns records of %s", hostname)
+		}
+		if len(others) > 0 {
+			// the hostname is pointed somewhere outside of this controller, which is not overwritten
+			continue
+		}
+		if _, err := api.Client.CreateDNSRecord(api.Ctx, zone, cloudflare.CreateDNSRecordParams{
+			Type:    "CNAME",
+			Name:    hostname,
+			Content: target,
+			Proxied: ptr.To(true),
+			Comment: owner,
+		}); err != nil {
+			return errors.Wrapf(err, "failed to create the dns record of %s", hostname)
+		}
+	}
+	return nil
+}
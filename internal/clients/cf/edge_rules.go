@@ -0,0 +1,106 @@
+package cf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+const (
+	// edgeRuleRefPrefix marks the rules of zone rulesets that are managed by this controller
+	edgeRuleRefPrefix = "cgc-"
+)
+
+// EdgeRuleOwner returns the prefix of the refs of the ruleset rules managed for a Gateway, every Gateway
+// owns its rules in a ruleset shared with other Gateways and with rules managed outside the cluster
+func EdgeRuleOwner(gatewayNamespace string, gatewayName string) string {
+	return edgeRuleRefPrefix + shortHash(gatewayNamespace+"/"+gatewayName) + "-"
+}
+
+// EdgeRuleRef returns a stable ref for a rule of owner, identified by key
+func EdgeRuleRef(owner string, key string) string {
+	return owner + shortHash(key)
+}
+
+func shortHash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ZoneID returns the id of the zone of domain
+func (api *Api) ZoneID(domain string) (string, error) {
+	zoneID, err := api.Client.ZoneIDByName(domain)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to find zone %s", domain)
+	}
+	return zoneID, nil
+}
+
+// SyncEdgeRules makes the rules owned by owner in the entrypoint ruleset of phase equal to rules, keeping
+// every other rule of the ruleset in place. The ruleset is only written when the owned rules changed
+func (api *Api) SyncEdgeRules(zoneID string, phase cloudflare.RulesetPhase, owner string, rules []cloudflare.RulesetRule) error {
+	zone := cloudflare.ZoneIdentifier(zoneID)
+	existing, err := api.Client.GetEntrypointRuleset(api.Ctx, zone, string(phase))
+	if err != nil {
+		var notFound *cloudflare.NotFoundError
+		var forbidden *cloudflare.AuthorizationError
+		switch {
+		case errors.As(err, &notFound):
+			// the ruleset of a phase only exists once a rule was added to it
+		case errors.As(err, &forbidden) && len(rules) == 0:
+			// tokens without access to the rulesets of the zone can't have created any rules to clean up
+			return nil
+		default:
+			return errors.Wrapf(err, "failed to get the %s ruleset", phase)
+		}
+	}
+
+	var kept, owned []cloudflare.RulesetRule
+	for _, rule := range existing.Rules {
+		if strings.HasPrefix(rule.Ref, owner) {
+			owned = append(owned, rule)
+			continue
+		}
+		// the ruleset is written as a whole, so the rules of others are sent back unchanged
+		rule.Version = nil
+		rule.LastUpdated = nil
+		kept = append(kept, rule)
+	}
+	if sameEdgeRules(owned, rules) {
+		return nil
+	}
+
+	if _, err := api.Client.UpdateEntrypointRuleset(api.Ctx, zone, cloudflare.UpdateEntrypointRulesetParams{
+		Phase: string(phase),
+		Rules: append(kept, rules...),
+	}); err != nil {
+		return errors.Wrapf(err, "failed to update the %s ruleset", phase)
+	}
+	return nil
+}
+
+// sameEdgeRules compares the parts of the rules this controller sets, ignoring the fields filled in by Cloudflare
+func sameEdgeRules(existing []cloudflare.RulesetRule, desired []cloudflare.RulesetRule) bool {
+	if len(existing) != len(desired) {
+		return false
+	}
+	for i := range existing {
+		if existing[i].Ref != desired[i].Ref ||
+			existing[i].Action != desired[i].Action ||
+			existing[i].Expression != desired[i].Expression ||
+			existing[i].Description != desired[i].Description ||
+			!equality.Semantic.DeepEqual(existing[i].ActionParameters, desired[i].ActionParameters) {
+			return false
+		}
+	}
+	return true
+}
+
+// QuoteString quotes a value as a string literal of the Cloudflare rules language
+func QuoteString(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
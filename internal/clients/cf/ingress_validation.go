@@ -0,0 +1,102 @@
+package cf
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	httpStatusServicePrefix = "http_status:"
+	helloWorldService       = "hello_world"
+)
+
+// serviceSchemes are the schemes of the origin service URLs cloudflared proxies to
+var serviceSchemes = map[string]bool{
+	"http":  true,
+	"https": true,
+	"tcp":   true,
+	"ssh":   true,
+}
+
+// InvalidConfigError is returned for tunnel configs cloudflared would refuse to start with
+type InvalidConfigError struct {
+	Problems []string
+}
+
+func (e *InvalidConfigError) Error() string {
+	return "invalid cloudflared config: " + strings.Join(e.Problems, "; ")
+}
+
+// validateIngress applies the checks cloudflared runs on the ingress rules of its config when it starts,
+// cloudflared exits on the first rule it rejects
+func validateIngress(ingress []IngressConfig) []string {
+	var problems []string
+	for i, rule := range ingress {
+		if problem := validateHostname(rule.Hostname); problem != "" {
+			problems = append(problems, fmt.Sprintf("ingress[%d]: %s", i, problem))
+		}
+		if _, err := regexp.Compile(rule.Path); err != nil {
+			problems = append(problems, fmt.Sprintf("ingress[%d]: path %q is not a valid regular expression: %s", i, rule.Path, err))
+		}
+		if problem := validateService(rule.Service); problem != "" {
+			problems = append(problems, fmt.Sprintf("ingress[%d]: %s", i, problem))
+		}
+		// cloudflared matches every hostname with a bare wildcard
+		catchAll := (rule.Hostname == "" || rule.Hostname == "*") && rule.Path == ""
+		last := i == len(ingress)-1
+		if last && !catchAll {
+			problems = append(problems, fmt.Sprintf("ingress[%d]: the last rule must match every request, without a hostname or path", i))
+		}
+		if !last && catchAll {
+			problems = append(problems, fmt.Sprintf("ingress[%d]: only the last rule may match every request", i))
+		}
+	}
+	return problems
+}
+
+// validateHostname returns why cloudflared rejects a hostname, a wildcard may only lead the hostname
+func validateHostname(hostname string) string {
+	if strings.Contains(hostname, ":") {
+		return fmt.Sprintf("hostname %q must not contain a port", hostname)
+	}
+	if strings.LastIndex(hostname, "*") > 0 {
+		return fmt.Sprintf("hostname %q may only use a wildcard as its left-most label, e.g. *.example.com", hostname)
+	}
+	return ""
+}
+
+// validateService returns why cloudflared rejects the service of an ingress rule
+func validateService(service string) string {
+	switch {
+	case service == helloWorldService:
+		return ""
+	case strings.HasPrefix(service, httpStatusServicePrefix):
+		code, err := strconv.Atoi(strings.TrimPrefix(service, httpStatusServicePrefix))
+		if err != nil || code < 100 || code > 999 {
+			return fmt.Sprintf("service %q must be a valid HTTP status code", service)
+		}
+		return ""
+	case strings.HasPrefix(service, "unix:"), strings.HasPrefix(service, "unix+tls:"):
+		if strings.TrimPrefix(strings.TrimPrefix(service, "unix+tls:"), "unix:") == "" {
+			return fmt.Sprintf("service %q must be the path of a unix socket", service)
+		}
+		return ""
+	}
+	u, err := url.Parse(service)
+	if err != nil {
+		return fmt.Sprintf("service %q is not a valid URL: %s", service, err)
+	}
+	if !serviceSchemes[u.Scheme] {
+		return fmt.Sprintf("service %q must use one of the schemes http, https, tcp, ssh or unix, or be %sNNN or %s", service, httpStatusServicePrefix, helloWorldService)
+	}
+	if u.Host == "" {
+		return fmt.Sprintf("service %q must have a hostname", service)
+	}
+	if u.Path != "" && u.Path != "/" {
+		return fmt.Sprintf("service %q must not have a path, cloudflared can't proxy to a different path on the origin", service)
+	}
+	return ""
+}
@@ -0,0 +1,54 @@
+package cf
+
+import "testing"
+
+func TestValidateHostname(t *testing.T) {
+	tests := []struct {
+		name     string
+		hostname string
+		valid    bool
+	}{
+		{name: "Hostnames", hostname: "a.example.com", valid: true},
+		{name: "Wildcard subdomains", hostname: "*.example.com", valid: true},
+		{name: "Bare wildcards", hostname: "*", valid: true},
+		{name: "Wildcards leading the left-most label", hostname: "*a.example.com", valid: true},
+		{name: "Wildcards outside of the left-most label", hostname: "a.*.example.com", valid: false},
+		{name: "Trailing wildcards", hostname: "example.*", valid: false},
+		{name: "Hostnames with a port", hostname: "a.example.com:443", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if problem := validateHostname(tt.hostname); (problem == "") != tt.valid {
+				t.Errorf("validateHostname(%q) = %q, want valid %v", tt.hostname, problem, tt.valid)
+			}
+		})
+	}
+}
+
+func TestValidateIngressBareWildcards(t *testing.T) {
+	tests := []struct {
+		name    string
+		ingress []IngressConfig
+		valid   bool
+	}{
+		{
+			name:    "Bare wildcards as the last rule",
+			ingress: []IngressConfig{{Hostname: "a.example.com", Service: "http_status:200"}, {Hostname: "*", Service: "http_status:404"}},
+			valid:   true,
+		},
+		{
+			name:    "Bare wildcards before the last rule",
+			ingress: []IngressConfig{{Hostname: "*", Service: "http_status:200"}, {Service: "http_status:404"}},
+			valid:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if problems := validateIngress(tt.ingress); (len(problems) == 0) != tt.valid {
+				t.Errorf("validateIngress() = %v, want valid %v", problems, tt.valid)
+			}
+		})
+	}
+}
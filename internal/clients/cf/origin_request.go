@@ -0,0 +1,84 @@
+package cf
+
+// OriginRequestConfig configures how cloudflared connects to the origin of an ingress rule.
+// Unset fields fall back to the cloudflared defaults.
+// https://developers.cloudflare.com/cloudflare-one/connections/connect-networks/configure-tunnels/local-management/configuration-file/#origin-configuration
+type OriginRequestConfig struct {
+	// ConnectTimeout bounds establishing the TCP connection to the origin, as a Go duration e.g. "30s"
+	ConnectTimeout string `json:"connectTimeout,omitempty"`
+	// TLSTimeout bounds the TLS handshake with the origin, as a Go duration
+	TLSTimeout string `json:"tlsTimeout,omitempty"`
+	// TCPKeepAlive is the keep-alive interval of the TCP connections to the origin, as a Go duration
+	TCPKeepAlive string `json:"tcpKeepAlive,omitempty"`
+	// NoHappyEyeballs disables falling back from IPv6 to IPv4 when connecting to the origin
+	NoHappyEyeballs *bool `json:"noHappyEyeballs,omitempty"`
+	// KeepAliveConnections is the maximum number of idle connections kept open to the origin
+	KeepAliveConnections *int `json:"keepAliveConnections,omitempty"`
+	// KeepAliveTimeout is how long idle connections to the origin are kept open, as a Go duration
+	KeepAliveTimeout string `json:"keepAliveTimeout,omitempty"`
+	// HTTPHostHeader sets the Host header of requests to the origin
+	HTTPHostHeader string `json:"httpHostHeader,omitempty"`
+	// OriginServerName is the hostname cloudflared expects on the certificate of the origin, and sends as SNI
+	OriginServerName string `json:"originServerName,omitempty"`
+	// MatchSNIToHost makes cloudflared send the hostname of the request as SNI, which covers wildcard hostnames
+	MatchSNIToHost *bool `json:"matchSNItoHost,omitempty"`
+	// CAPool is the path of a PEM bundle of CAs to verify the origin certificate against,
+	// in addition to the system CAs
+	CAPool string `json:"caPool,omitempty"`
+	// NoTLSVerify disables verification of the certificate presented by the origin
+	NoTLSVerify *bool `json:"noTLSVerify,omitempty"`
+	// DisableChunkedEncoding disables chunked transfer encoding towards the origin
+	DisableChunkedEncoding *bool `json:"disableChunkedEncoding,omitempty"`
+	// HTTP2Origin makes cloudflared talk HTTP/2 to the origin, which must then be served over https
+	HTTP2Origin *bool `json:"http2Origin,omitempty"`
+	// ProxyType is the kind of proxy cloudflared runs, an empty string for HTTP or socks
+	ProxyType *string `json:"proxyType,omitempty"`
+	// ProxyAddress is the address the proxy of cloudflared listens on
+	ProxyAddress string `json:"proxyAddress,omitempty"`
+	// ProxyPort is the port the proxy of cloudflared listens on
+	ProxyPort *int `json:"proxyPort,omitempty"`
+}
+
+// MergeOriginRequest returns base with every field that is set on override replaced. Neither argument is modified,
+// and the result is nil when both are
+func MergeOriginRequest(base *OriginRequestConfig, override *OriginRequestConfig) *OriginRequestConfig {
+	if base == nil && override == nil {
+		return nil
+	}
+	merged := &OriginRequestConfig{}
+	if base != nil {
+		*merged = *base
+	}
+	if override == nil {
+		return merged
+	}
+	mergeString(&merged.ConnectTimeout, override.ConnectTimeout)
+	mergeString(&merged.TLSTimeout, override.TLSTimeout)
+	mergeString(&merged.TCPKeepAlive, override.TCPKeepAlive)
+	mergePointer(&merged.NoHappyEyeballs, override.NoHappyEyeballs)
+	mergePointer(&merged.KeepAliveConnections, override.KeepAliveConnections)
+	mergeString(&merged.KeepAliveTimeout, override.KeepAliveTimeout)
+	mergeString(&merged.HTTPHostHeader, override.HTTPHostHeader)
+	mergeString(&merged.OriginServerName, override.OriginServerName)
+	mergePointer(&merged.MatchSNIToHost, override.MatchSNIToHost)
+	mergeString(&merged.CAPool, override.CAPool)
+	mergePointer(&merged.NoTLSVerify, override.NoTLSVerify)
+	mergePointer(&merged.DisableChunkedEncoding, override.DisableChunkedEncoding)
+	mergePointer(&merged.HTTP2Origin, override.HTTP2Origin)
+	mergePointer(&merged.ProxyType, override.ProxyType)
+	mergeString(&merged.ProxyAddress, override.ProxyAddress)
+	mergePointer(&merged.ProxyPort, override.ProxyPort)
+	return merged
+}
+
+func mergeString(field *string, override string) {
+	if override != "" {
+		*field = override
+	}
+}
+
+func mergePointer[T any](field **T, override *T) {
+	if override != nil {
+		*field = override
+	}
+}
@@ -0,0 +1,40 @@
+package cf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeOriginRequest(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name     string
+		base     *OriginRequestConfig
+		override *OriginRequestConfig
+		want     *OriginRequestConfig
+	}{
+		{
+			name: "Both unset",
+			want: nil,
+		},
+		{
+			name: "Only base",
+			base: &OriginRequestConfig{ConnectTimeout: "10s"},
+			want: &OriginRequestConfig{ConnectTimeout: "10s"},
+		},
+		{
+			name:     "Set fields override",
+			base:     &OriginRequestConfig{ConnectTimeout: "10s", NoTLSVerify: &yes, HTTPHostHeader: "base.example.com"},
+			override: &OriginRequestConfig{ConnectTimeout: "5s", NoTLSVerify: &no},
+			want:     &OriginRequestConfig{ConnectTimeout: "5s", NoTLSVerify: &no, HTTPHostHeader: "base.example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MergeOriginRequest(tt.base, tt.override); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeOriginRequest() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
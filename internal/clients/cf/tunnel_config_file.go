@@ -1,8 +1,8 @@
 package cf
 
 import (
-	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/k8s"
 	"github.com/pkg/errors"
@@ -20,25 +20,42 @@ var (
 )
 
 type TunnelConfigFile struct {
-	TunnelId            string          `json:"tunnel"`
-	CredentialsFilePath string          `json:"credentials-file"`
-	Ingress             []IngressConfig `json:"ingress"`
+	TunnelId            string `json:"tunnel"`
+	CredentialsFilePath string `json:"credentials-file"`
+	// OriginRequest is the default origin configuration of every ingress rule
+	OriginRequest *OriginRequestConfig `json:"originRequest,omitempty"`
+	Ingress       []IngressConfig      `json:"ingress"`
 }
 
 type IngressConfig struct {
 	Hostname string `json:"hostname,omitempty"`
-	Service  string `json:"service"`
+	// Path is a regular expression matched against the request path, an empty path matches every request
+	Path          string               `json:"path,omitempty"`
+	Service       string               `json:"service"`
+	OriginRequest *OriginRequestConfig `json:"originRequest,omitempty"`
 }
 
-func NewTunnelConfigFile(tunnelId string, ingressConfig []IngressConfig) (*TunnelConfigFile, error) {
+// NewTunnelConfigFile builds the config of a tunnel. defaultService answers the requests that none of the ingress
+// rules match, IngressDefaultBackend when it is empty
+func NewTunnelConfigFile(
+	tunnelId string,
+	originRequest *OriginRequestConfig,
+	ingressConfig []IngressConfig,
+	defaultService string,
+) (*TunnelConfigFile, error) {
 	// inject default backend config if one doesn't exist
 	length := len(ingressConfig)
-	if length == 0 || ingressConfig[(length-1)].Hostname != "" {
-		ingressConfig = append(ingressConfig, IngressDefaultBackendConfig)
+	if length == 0 || ingressConfig[(length-1)].Hostname != "" || ingressConfig[(length-1)].Path != "" {
+		defaultBackend := IngressDefaultBackendConfig
+		if defaultService != "" {
+			defaultBackend.Service = defaultService
+		}
+		ingressConfig = append(ingressConfig, defaultBackend)
 	}
 	tunnelConfig := &TunnelConfigFile{
 		TunnelId:            tunnelId,
 		CredentialsFilePath: k8s.DeploymentCredentialFilePath,
+		OriginRequest:       originRequest,
 		Ingress:             ingressConfig,
 	}
 	if err := tunnelConfig.validate(); err != nil {
@@ -54,8 +71,8 @@ func (t *TunnelConfigFile) validate() error {
 	if len(t.Ingress) == 0 {
 		return errors.New("Ingress is empty")
 	}
-	if t.Ingress[(len(t.Ingress)-1)].Hostname != "" {
-		return errors.New(fmt.Sprintf("Last ingress rule must be a catch-all with no hostname identifier e.g. `- service: %s`", IngressDefaultBackend))
+	if problems := validateIngress(t.Ingress); len(problems) > 0 {
+		return &InvalidConfigError{Problems: problems}
 	}
 	return nil
 }
@@ -65,30 +82,11 @@ func (t *TunnelConfigFile) validate() error {
 // - routes with a FQDN for the Hostname field domain e.g. an.example.com (these go at the beginning)
 // - routes with a wildcard value for the Hostname field e.g. *.example.com (these go after the first group)
 // - a single route with no Hostname field, this is the catch-all (this must always go at the end)
+// Routes that share a hostname are ordered so that the longest path is tried first, with
+// routes that match every path coming last
 func Sort(routes []IngressConfig) []IngressConfig {
-	// Sort with custom logic
 	sort.SliceStable(routes, func(i, j int) bool {
-		// Check for the catch-all route (Hostname is empty)
-		if routes[i].Hostname == "" {
-			return false
-		}
-		if routes[j].Hostname == "" {
-			return true
-		}
-
-		// Wildcards should go after fully qualified domains
-		isWildcardI := routes[i].Hostname[0:2] == "*."
-		isWildcardJ := routes[j].Hostname[0:2] == "*."
-
-		if isWildcardI && !isWildcardJ {
-			return false
-		}
-		if !isWildcardI && isWildcardJ {
-			return true
-		}
-
-		// Otherwise, sort lexicographically (for FQDNs or two wildcards)
-		return routes[i].Hostname < routes[j].Hostname
+		return Less(routes[i], routes[j])
 	})
 
 	// Ensure catch-all route (if present) is placed at the end
@@ -99,3 +97,36 @@ func Sort(routes []IngressConfig) []IngressConfig {
 
 	return routes
 }
+
+// Less reports whether route a must be matched by cloudflared before route b, see Sort
+func Less(a IngressConfig, b IngressConfig) bool {
+	// Check for the catch-all route (Hostname is empty)
+	if a.Hostname == "" {
+		return false
+	}
+	if b.Hostname == "" {
+		return true
+	}
+
+	// Wildcards should go after fully qualified domains
+	isWildcardA := strings.HasPrefix(a.Hostname, "*.")
+	isWildcardB := strings.HasPrefix(b.Hostname, "*.")
+
+	if isWildcardA && !isWildcardB {
+		return false
+	}
+	if !isWildcardA && isWildcardB {
+		return true
+	}
+
+	// Otherwise, sort lexicographically (for FQDNs or two wildcards)
+	if a.Hostname != b.Hostname {
+		return a.Hostname < b.Hostname
+	}
+
+	// More specific paths must be matched before less specific ones for the same hostname
+	if len(a.Path) != len(b.Path) {
+		return len(a.Path) > len(b.Path)
+	}
+	return a.Path < b.Path
+}
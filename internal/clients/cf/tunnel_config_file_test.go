@@ -3,6 +3,8 @@ package cf
 import (
 	"reflect"
 	"testing"
+
+	"github.com/pkg/errors"
 )
 
 func TestSort(t *testing.T) {
@@ -59,6 +61,19 @@ func TestSort(t *testing.T) {
 				{Hostname: "", Service: "service4"}, // catch-all at the end
 			},
 		},
+		{
+			name: "Paths for the same hostname sorted longest first",
+			routes: []IngressConfig{
+				{Hostname: "a.example.com", Service: "service1"},
+				{Hostname: "a.example.com", Path: "^/api(/.*)?$", Service: "service2"},
+				{Hostname: "a.example.com", Path: "^/api/v2(/.*)?$", Service: "service3"},
+			},
+			want: []IngressConfig{
+				{Hostname: "a.example.com", Path: "^/api/v2(/.*)?$", Service: "service3"},
+				{Hostname: "a.example.com", Path: "^/api(/.*)?$", Service: "service2"},
+				{Hostname: "a.example.com", Service: "service1"},
+			},
+		},
 		{
 			name: "Only catch-all route",
 			routes: []IngressConfig{
@@ -84,3 +99,165 @@ func TestSort(t *testing.T) {
 		})
 	}
 }
+
+func TestNewTunnelConfigFile(t *testing.T) {
+	tests := []struct {
+		name           string
+		ingress        []IngressConfig
+		defaultService string
+		wantIngress    int
+		wantDefault    string
+		wantInvalid    bool
+	}{
+		{
+			name: "Valid services and a catch-all is added",
+			ingress: []IngressConfig{
+				{Hostname: "a.example.com", Path: "^/api(/.*)?$", Service: "http://api.default.svc.cluster.local:80"},
+				{Hostname: "a.example.com", Service: "https://web.default.svc.cluster.local"},
+				{Hostname: "*.example.com", Service: "http_status:301"},
+				{Hostname: "ssh.example.com", Service: "ssh://bastion.default.svc.cluster.local:22"},
+				{Hostname: "db.example.com", Service: "tcp://db.default.svc.cluster.local:5432"},
+				{Hostname: "socket.example.com", Service: "unix:/var/run/app.sock"},
+				{Hostname: "hello.example.com", Service: "hello_world"},
+			},
+			wantIngress: 8,
+			wantDefault: IngressDefaultBackend,
+		},
+		{
+			name:           "A default service",
+			ingress:        []IngressConfig{{Hostname: "a.example.com", Service: "http://a.default.svc.cluster.local"}},
+			defaultService: "http://not-found.default.svc.cluster.local:8080",
+			wantIngress:    2,
+			wantDefault:    "http://not-found.default.svc.cluster.local:8080",
+		},
+		{
+			name:           "Routes matching every request replace the default service",
+			ingress:        []IngressConfig{{Service: "http://a.default.svc.cluster.local"}},
+			defaultService: "hello_world",
+			wantIngress:    1,
+			wantDefault:    "http://a.default.svc.cluster.local",
+		},
+		{
+			name:           "Invalid default services",
+			ingress:        []IngressConfig{{Hostname: "a.example.com", Service: "http://a.default.svc.cluster.local"}},
+			defaultService: "http_status:42",
+			wantInvalid:    true,
+		},
+		{
+			name: "A last rule with a path is not a catch-all",
+			ingress: []IngressConfig{
+				{Path: "^/api(/.*)?$", Service: "http://api.default.svc.cluster.local"},
+			},
+			wantIngress: 2,
+			wantDefault: IngressDefaultBackend,
+		},
+		{
+			name:        "Wildcards outside of the left-most label",
+			ingress:     []IngressConfig{{Hostname: "a.*.example.com", Service: "http://a.default.svc.cluster.local"}},
+			wantInvalid: true,
+		},
+		{
+			name:        "Wildcards leading the left-most label",
+			ingress:     []IngressConfig{{Hostname: "*a.example.com", Service: "http://a.default.svc.cluster.local"}},
+			wantIngress: 2,
+			wantDefault: IngressDefaultBackend,
+		},
+		{
+			name:        "Wildcards after the start of the left-most label",
+			ingress:     []IngressConfig{{Hostname: "a*.example.com", Service: "http://a.default.svc.cluster.local"}},
+			wantInvalid: true,
+		},
+		{
+			name:        "Hostnames with a port",
+			ingress:     []IngressConfig{{Hostname: "a.example.com:443", Service: "http://a.default.svc.cluster.local"}},
+			wantInvalid: true,
+		},
+		{
+			name:        "Unsupported service schemes",
+			ingress:     []IngressConfig{{Hostname: "a.example.com", Service: "ftp://a.default.svc.cluster.local"}},
+			wantInvalid: true,
+		},
+		{
+			name:        "Invalid status codes",
+			ingress:     []IngressConfig{{Hostname: "a.example.com", Service: "http_status:abc"}},
+			wantInvalid: true,
+		},
+		{
+			name:        "Services with a path",
+			ingress:     []IngressConfig{{Hostname: "a.example.com", Service: "http://a.default.svc.cluster.local/app"}},
+			wantInvalid: true,
+		},
+		{
+			name:        "Paths that don't compile",
+			ingress:     []IngressConfig{{Hostname: "a.example.com", Path: "^/(api$", Service: "http://a.default.svc.cluster.local"}},
+			wantInvalid: true,
+		},
+		{
+			name: "Catch-all rules before the last rule",
+			ingress: []IngressConfig{
+				{Service: "http://a.default.svc.cluster.local"},
+				{Hostname: "a.example.com", Service: "http://a.default.svc.cluster.local"},
+			},
+			wantInvalid: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := NewTunnelConfigFile("tunnel", nil, tt.ingress, tt.defaultService)
+			var invalid *InvalidConfigError
+			if got := errors.As(err, &invalid); got != tt.wantInvalid {
+				t.Fatalf("NewTunnelConfigFile() error = %v, want invalid %v", err, tt.wantInvalid)
+			}
+			if tt.wantInvalid {
+				return
+			}
+			if len(config.Ingress) != tt.wantIngress {
+				t.Fatalf("len(Ingress) = %d, want %d", len(config.Ingress), tt.wantIngress)
+			}
+			if got := config.Ingress[len(config.Ingress)-1].Service; got != tt.wantDefault {
+				t.Errorf("default service = %q, want %q", got, tt.wantDefault)
+			}
+		})
+	}
+}
+
+func TestTunnelConfigFileYAML(t *testing.T) {
+	config, err := NewTunnelConfigFile("tunnel", &OriginRequestConfig{ConnectTimeout: "30s"}, []IngressConfig{
+		{
+			Hostname:      "a.example.com",
+			Path:          "^/api(/.*)?$",
+			Service:       "http://api.default.svc.cluster.local:80",
+			OriginRequest: &OriginRequestConfig{HTTPHostHeader: "true"},
+		},
+		{Hostname: "*.example.com", Service: "http_status:301"},
+	}, "")
+	if err != nil {
+		t.Fatalf("NewTunnelConfigFile() error = %v", err)
+	}
+	want := `tunnel: tunnel
+credentials-file: /etc/cloudflared/creds/creds.json
+originRequest:
+  connectTimeout: 30s
+ingress:
+  # HTTPRoute default/api rules[0], listener https
+  - hostname: a.example.com
+    path: ^/api(/.*)?$
+    service: http://api.default.svc.cluster.local:80
+    originRequest:
+      httpHostHeader: "true"
+  # HTTPRoute default/web rules[1], listener https
+  - hostname: '*.example.com'
+    service: http_status:301
+  # default backend, for requests no other rule matches
+  - service: http_status:404
+`
+
+	got, err := config.YAML([]string{"HTTPRoute default/api rules[0], listener https", "HTTPRoute default/web rules[1], listener https"})
+	if err != nil {
+		t.Fatalf("YAML() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("YAML() =\n%s\nwant\n%s", got, want)
+	}
+}
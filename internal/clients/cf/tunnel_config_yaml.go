@@ -0,0 +1,65 @@
+package cf
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// defaultBackendSource is the provenance of the catch-all rule NewTunnelConfigFile adds
+	defaultBackendSource = "default backend, for requests no other rule matches"
+)
+
+// YAML renders the config as YAML with the provenance of each ingress rule as a comment above it, so that the
+// ConfigMap tells where every rule came from. sources are the provenance of the rules of Ingress in order, the
+// rules after them are the catch-all rule NewTunnelConfigFile adds. The output only depends on the config, keys
+// are in the order of the fields of the config structs
+func (t *TunnelConfigFile) YAML(sources []string) (string, error) {
+	// going through JSON keeps the field names and omitempty rules of the json tags cloudflared reads
+	data, err := json.Marshal(t)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to serialize config")
+	}
+	document := &yaml.Node{}
+	if err := yaml.Unmarshal(data, document); err != nil {
+		return "", errors.Wrap(err, "failed to parse config")
+	}
+	blockStyle(document)
+
+	root := document.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "ingress" {
+			continue
+		}
+		for j, rule := range root.Content[i+1].Content {
+			if j < len(sources) {
+				rule.HeadComment = sources[j]
+			} else {
+				rule.HeadComment = defaultBackendSource
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(document); err != nil {
+		return "", errors.Wrap(err, "failed to render config")
+	}
+	if err := encoder.Close(); err != nil {
+		return "", errors.Wrap(err, "failed to render config")
+	}
+	return out.String(), nil
+}
+
+// blockStyle drops the flow style and quoting of the nodes parsed from JSON, the encoder then only quotes the
+// strings that need it
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
@@ -0,0 +1,72 @@
+package cf
+
+import (
+	"strconv"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/k8s"
+)
+
+const (
+	DefaultProtocol = "auto"
+)
+
+// TunnelSettings are the tunnel-level settings of cloudflared. They are passed as flags of `cloudflared tunnel`
+// rather than written to the config file, so that changing them rolls out new cloudflared pods.
+// Unset fields fall back to the cloudflared defaults
+// https://developers.cloudflare.com/cloudflare-one/connections/connect-networks/configure-tunnels/tunnel-run-parameters/
+type TunnelSettings struct {
+	// Protocol is the transport to the Cloudflare edge, auto when empty
+	Protocol string
+	// LogLevel is the minimum level of the messages cloudflared logs
+	LogLevel string
+	// LogFormat is the format of the logs, default or json
+	LogFormat string
+	// LogFile is the name of a file in k8s.DeploymentLogDir the logs are also written to
+	LogFile string
+	// Retries is the maximum number of retries for connection and protocol errors
+	Retries *int
+	// GracePeriod is how long cloudflared waits for in-flight requests on shutdown, as a Go duration
+	GracePeriod string
+	// HAConnections is the number of connections to the Cloudflare edge
+	HAConnections *int
+	// EdgeIPVersion is the IP version used to connect to the Cloudflare edge, auto, 4 or 6
+	EdgeIPVersion string
+	// Region restricts the Cloudflare data centres that are connected to
+	Region string
+	// PostQuantum enforces post-quantum key agreements towards the Cloudflare edge
+	PostQuantum bool
+}
+
+// Args returns the flags of `cloudflared tunnel` for the settings, in a stable order
+func (s TunnelSettings) Args() []string {
+	protocol := s.Protocol
+	if protocol == "" {
+		protocol = DefaultProtocol
+	}
+	args := []string{"--protocol", protocol}
+	args = appendFlag(args, "--loglevel", s.LogLevel)
+	args = appendFlag(args, "--output", s.LogFormat)
+	if s.LogFile != "" {
+		args = append(args, "--logfile", k8s.DeploymentLogDir+"/"+s.LogFile)
+	}
+	if s.Retries != nil {
+		args = append(args, "--retries", strconv.Itoa(*s.Retries))
+	}
+	args = appendFlag(args, "--grace-period", s.GracePeriod)
+	if s.HAConnections != nil {
+		args = append(args, "--ha-connections", strconv.Itoa(*s.HAConnections))
+	}
+	args = appendFlag(args, "--edge-ip-version", s.EdgeIPVersion)
+	args = appendFlag(args, "--region", s.Region)
+	if s.PostQuantum {
+		args = append(args, "--post-quantum")
+	}
+	return args
+}
+
+func appendFlag(args []string, flag string, value string) []string {
+	if value == "" {
+		return args
+	}
+	return append(args, flag, value)
+}
@@ -0,0 +1,55 @@
+package cf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTunnelSettingsArgs(t *testing.T) {
+	retries, connections := 3, 2
+	tests := []struct {
+		name     string
+		settings TunnelSettings
+		want     []string
+	}{
+		{
+			name: "Defaults",
+			want: []string{"--protocol", "auto"},
+		},
+		{
+			name: "Every setting",
+			settings: TunnelSettings{
+				Protocol:      "quic",
+				LogLevel:      "debug",
+				LogFormat:     "json",
+				LogFile:       "cloudflared.log",
+				Retries:       &retries,
+				GracePeriod:   "1m0s",
+				HAConnections: &connections,
+				EdgeIPVersion: "6",
+				Region:        "us",
+				PostQuantum:   true,
+			},
+			want: []string{
+				"--protocol", "quic",
+				"--loglevel", "debug",
+				"--output", "json",
+				"--logfile", "/var/log/cloudflared/cloudflared.log",
+				"--retries", "3",
+				"--grace-period", "1m0s",
+				"--ha-connections", "2",
+				"--edge-ip-version", "6",
+				"--region", "us",
+				"--post-quantum",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.settings.Args(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Args() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
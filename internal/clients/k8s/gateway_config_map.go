@@ -17,7 +17,12 @@ func BuildTunnelConfigMap(
 	deploymentName string,
 	namespace string,
 	configFile string,
+	caBundles map[string]string,
 ) (*corev1.ConfigMap, error) {
+	data := map[string]string{ConfigYamlFileName: configFile}
+	for key, bundle := range caBundles {
+		data[key] = bundle
+	}
 	return &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      ConfigMapName(deploymentName),
@@ -26,6 +31,6 @@ func BuildTunnelConfigMap(
 				"app.kubernetes.io/deploymentName": deploymentName,
 			},
 		},
-		Data: map[string]string{ConfigYamlFileName: configFile},
+		Data: data,
 	}, nil
 }
@@ -1,6 +1,8 @@
 package k8s
 
 import (
+	"time"
+
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -9,17 +11,35 @@ import (
 )
 
 const (
-	DeploymentConfigFilePath     = "/etc/cloudflared/config/config.yaml"
+	DeploymentConfigDir          = "/etc/cloudflared/config"
+	DeploymentConfigFilePath     = DeploymentConfigDir + "/" + ConfigYamlFileName
 	DeploymentCredentialFilePath = "/etc/cloudflared/creds/creds.json"
+	// DeploymentLogDir is a writable directory cloudflared can keep a log file in
+	DeploymentLogDir = "/var/log/cloudflared"
 )
 
-func BuildTunnelDeployment(deploymentName string, namespace string, tunnelId string) *appsv1.Deployment {
+// BuildTunnelDeployment builds the cloudflared deployment of a Gateway. tunnelArgs are the flags of `cloudflared tunnel`,
+// and gracePeriod is how long cloudflared drains connections on shutdown, zero for the cloudflared default
+func BuildTunnelDeployment(deploymentName string, namespace string, tunnelId string, tunnelArgs []string, gracePeriod time.Duration) *appsv1.Deployment {
 	replicas := int32(1)
 	labels := map[string]string{
 		"app.kubernetes.io/deploymentName": deploymentName,
 	}
 	maxSurge := intstr.FromString("1")
 	maxUnavailable := intstr.FromString("0")
+	args := append([]string{"tunnel"}, tunnelArgs...)
+	args = append(args,
+		"--config", DeploymentConfigFilePath,
+		"--metrics", "0.0.0.0:2000",
+		"run",
+		"test",
+	)
+	var terminationGracePeriodSeconds *int64
+	if gracePeriod > 0 {
+		// give cloudflared time to drain before the kubelet kills it
+		seconds := int64((gracePeriod + 10*time.Second).Seconds())
+		terminationGracePeriodSeconds = &seconds
+	}
 	return &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      deploymentName,
@@ -45,17 +65,11 @@ func BuildTunnelDeployment(deploymentName string, namespace string, tunnelId str
 					Labels: labels,
 				},
 				Spec: corev1.PodSpec{
+					TerminationGracePeriodSeconds: terminationGracePeriodSeconds,
 					Containers: []corev1.Container{{
 						Image: "cloudflare/cloudflared:latest",
 						Name:  "cloudflared",
-						Args: []string{
-							"tunnel",
-							"--protocol", "auto",
-							"--config", DeploymentConfigFilePath,
-							"--metrics", "0.0.0.0:2000",
-							"run",
-							"test",
-						},
+						Args:  args,
 						LivenessProbe: &corev1.Probe{
 							ProbeHandler: corev1.ProbeHandler{
 								HTTPGet: &corev1.HTTPGetAction{
@@ -76,12 +90,15 @@ func BuildTunnelDeployment(deploymentName string, namespace string, tunnelId str
 						},
 						VolumeMounts: []corev1.VolumeMount{{
 							Name:      "config",
-							MountPath: "/etc/cloudflared/config",
+							MountPath: DeploymentConfigDir,
 							ReadOnly:  true,
 						}, {
 							Name:      "creds",
 							MountPath: "/etc/cloudflared/creds",
 							ReadOnly:  true,
+						}, {
+							Name:      "logs",
+							MountPath: DeploymentLogDir,
 						}},
 						Resources: corev1.ResourceRequirements{
 							Requests: corev1.ResourceList{"memory": resource.MustParse("30Mi"), "cpu": resource.MustParse("10m")},
@@ -97,16 +114,27 @@ func BuildTunnelDeployment(deploymentName string, namespace string, tunnelId str
 						Name: "config",
 						VolumeSource: corev1.VolumeSource{
 							ConfigMap: &corev1.ConfigMapVolumeSource{
+								// the whole ConfigMap is mounted, as it carries the CA bundles of BackendTLSPolicies next to the config
 								LocalObjectReference: corev1.LocalObjectReference{Name: ConfigMapName(deploymentName)},
-								Items: []corev1.KeyToPath{{
-									Key:  "config.yaml",
-									Path: "config.yaml",
-								}},
 							},
 						},
+					}, {
+						Name: "logs",
+						VolumeSource: corev1.VolumeSource{
+							EmptyDir: &corev1.EmptyDirVolumeSource{},
+						},
 					}},
 				},
 			},
 		},
 	}
 }
+
+// DeploymentIsReady reports whether the deployment controller has caught up with the latest
+// spec and at least one cloudflared replica is available to serve the tunnel
+func DeploymentIsReady(deployment *appsv1.Deployment) bool {
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return false
+	}
+	return deployment.Status.AvailableReplicas > 0
+}
@@ -0,0 +1,172 @@
+package k8s
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	RouterPort          = 8080
+	RouterProbePort     = 8081
+	RouterTableDir      = "/etc/router"
+	RouterTableFileName = "table.json"
+	// RouterBinary is the path of the router in the controller image
+	RouterBinary = "/router"
+)
+
+// RouterName is the name of the router Deployment, Service and ConfigMap of a Gateway
+func RouterName(deploymentName string) string {
+	return deploymentName + "-router"
+}
+
+// RouterServiceURL is the cloudflared service of the ingress rules that go through the router of a Gateway
+func RouterServiceURL(deploymentName string, namespace string) string {
+	return fmt.Sprintf("http://%s:%d", ServiceHostname(RouterName(deploymentName), namespace), RouterPort)
+}
+
+func routerLabels(deploymentName string) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/deploymentName": RouterName(deploymentName),
+	}
+}
+
+// BuildRouterConfigMap builds the ConfigMap holding the routing table of the router of a Gateway
+func BuildRouterConfigMap(deploymentName string, namespace string, table string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      RouterName(deploymentName),
+			Namespace: namespace,
+			Labels:    routerLabels(deploymentName),
+		},
+		Data: map[string]string{RouterTableFileName: table},
+	}
+}
+
+// BuildRouterService builds the Service cloudflared reaches the router of a Gateway through
+func BuildRouterService(deploymentName string, namespace string) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      RouterName(deploymentName),
+			Namespace: namespace,
+			Labels:    routerLabels(deploymentName),
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: routerLabels(deploymentName),
+			Ports: []corev1.ServicePort{{
+				Name:       "http",
+				Port:       RouterPort,
+				TargetPort: intstr.FromString("http"),
+				Protocol:   corev1.ProtocolTCP,
+			}},
+		},
+	}
+}
+
+// BuildRouterDeployment builds the router deployment of a Gateway. The router reloads its routing table
+// when the ConfigMap changes, so table updates don't roll the deployment
+func BuildRouterDeployment(deploymentName string, namespace string, image string, replicas int32) *appsv1.Deployment {
+	labels := routerLabels(deploymentName)
+	maxSurge := intstr.FromString("1")
+	maxUnavailable := intstr.FromString("0")
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      RouterName(deploymentName),
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Strategy: appsv1.DeploymentStrategy{
+				Type: appsv1.RollingUpdateDeploymentStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateDeployment{
+					MaxUnavailable: &maxUnavailable,
+					MaxSurge:       &maxSurge,
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					SecurityContext: &corev1.PodSecurityContext{
+						RunAsNonRoot: ptr.To(true),
+					},
+					Containers: []corev1.Container{{
+						Image:   image,
+						Name:    "router",
+						Command: []string{RouterBinary},
+						Args: []string{
+							fmt.Sprintf("--listen-address=:%d", RouterPort),
+							fmt.Sprintf("--health-probe-bind-address=:%d", RouterProbePort),
+							"--table=" + RouterTableDir + "/" + RouterTableFileName,
+						},
+						LivenessProbe: &corev1.Probe{
+							ProbeHandler: corev1.ProbeHandler{
+								HTTPGet: &corev1.HTTPGetAction{
+									Path: "/healthz",
+									Port: intstr.FromString("probes"),
+								},
+							},
+							InitialDelaySeconds: 5,
+							PeriodSeconds:       10,
+						},
+						ReadinessProbe: &corev1.Probe{
+							ProbeHandler: corev1.ProbeHandler{
+								HTTPGet: &corev1.HTTPGetAction{
+									Path: "/healthz",
+									Port: intstr.FromString("probes"),
+								},
+							},
+							PeriodSeconds: 5,
+						},
+						Ports: []corev1.ContainerPort{
+							{
+								Name:          "http",
+								ContainerPort: RouterPort,
+								Protocol:      corev1.ProtocolTCP,
+							},
+							{
+								Name:          "probes",
+								ContainerPort: RouterProbePort,
+								Protocol:      corev1.ProtocolTCP,
+							},
+						},
+						SecurityContext: &corev1.SecurityContext{
+							AllowPrivilegeEscalation: ptr.To(false),
+							ReadOnlyRootFilesystem:   ptr.To(true),
+							Capabilities: &corev1.Capabilities{
+								Drop: []corev1.Capability{"ALL"},
+							},
+						},
+						VolumeMounts: []corev1.VolumeMount{{
+							Name:      "table",
+							MountPath: RouterTableDir,
+							ReadOnly:  true,
+						}},
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{"memory": resource.MustParse("30Mi"), "cpu": resource.MustParse("10m")},
+							Limits:   corev1.ResourceList{"memory": resource.MustParse("256Mi"), "cpu": resource.MustParse("500m")},
+						},
+					}},
+					Volumes: []corev1.Volume{{
+						Name: "table",
+						VolumeSource: corev1.VolumeSource{
+							ConfigMap: &corev1.ConfigMapVolumeSource{
+								LocalObjectReference: corev1.LocalObjectReference{Name: RouterName(deploymentName)},
+							},
+						},
+					}},
+				},
+			},
+		},
+	}
+}
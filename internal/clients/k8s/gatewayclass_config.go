@@ -1,8 +1,80 @@
 package k8s
 
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
 type GatewayClassConfig struct {
 	CloudflareApiToken  string
 	Domain              string
 	EmailAddress        string
 	CloudflareAccountId string
 }
+
+// LoadGatewayClassConfig reads the settings of the GatewayClass of a Gateway from the Secret its parametersRef points at
+func LoadGatewayClassConfig(ctx context.Context, c client.Reader, gateway *gatewayv1.Gateway) (GatewayClassConfig, error) {
+	if gateway == nil {
+		return GatewayClassConfig{}, errors.New("nil gateway")
+	}
+	gatewayClassName := gateway.Spec.GatewayClassName
+	if gatewayClassName == "" {
+		return GatewayClassConfig{}, errors.New("gateway class name is empty")
+	}
+
+	gatewayClass := &gatewayv1.GatewayClass{}
+	err := c.Get(ctx, client.ObjectKey{Name: string(gatewayClassName)}, gatewayClass)
+	if err != nil {
+		return GatewayClassConfig{}, errors.Wrap(err, "failed to get gateway class")
+	}
+	if gatewayClass.Spec.ParametersRef == nil {
+		return GatewayClassConfig{}, errors.New("gateway class has no parametersRef")
+	}
+
+	var namespace string
+	if gatewayClass.Spec.ParametersRef.Namespace == nil {
+		namespace = "default"
+	} else {
+		namespace = string(*gatewayClass.Spec.ParametersRef.Namespace)
+	}
+	secret := &corev1.Secret{}
+	err = c.Get(
+		ctx,
+		client.ObjectKey{
+			Name:      gatewayClass.Spec.ParametersRef.Name,
+			Namespace: namespace,
+		},
+		secret,
+	)
+	if err != nil {
+		return GatewayClassConfig{}, errors.Wrap(err, "failed to get secret")
+	}
+
+	cloudflareApiToken := string(secret.Data["api_token"])
+	if cloudflareApiToken == "" {
+		return GatewayClassConfig{}, errors.New("cloudflare api token is empty")
+	}
+	domain := string(secret.Data["domain"])
+	if domain == "" {
+		return GatewayClassConfig{}, errors.New("domain is empty")
+	}
+	emailAddress := string(secret.Data["email"])
+	if emailAddress == "" {
+		return GatewayClassConfig{}, errors.New("email is empty")
+	}
+	cloudflareAccountId := string(secret.Data["account_id"])
+	if cloudflareAccountId == "" {
+		return GatewayClassConfig{}, errors.New("cloudflare account id is empty")
+	}
+
+	return GatewayClassConfig{
+		CloudflareApiToken:  cloudflareApiToken,
+		Domain:              domain,
+		EmailAddress:        emailAddress,
+		CloudflareAccountId: cloudflareAccountId,
+	}, nil
+}
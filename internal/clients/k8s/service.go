@@ -0,0 +1,19 @@
+package k8s
+
+import (
+	"fmt"
+)
+
+// ClusterDomain is the DNS domain of the cluster, Services resolve as <name>.<namespace>.svc.<ClusterDomain>.
+// It is set from the --cluster-domain flag of the controller
+var ClusterDomain = "cluster.local"
+
+// ServiceHostname is the cluster DNS name of a Service
+func ServiceHostname(name string, namespace string) string {
+	return fmt.Sprintf("%s.%s.svc.%s", name, namespace, ClusterDomain)
+}
+
+// PodHostname is the stable cluster DNS name of a pod with a hostname behind a headless Service, as the pods of StatefulSets have
+func PodHostname(hostname string, service string, namespace string) string {
+	return hostname + "." + ServiceHostname(service, namespace)
+}
@@ -0,0 +1,82 @@
+package backend_tls_policy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller/routing"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha3 "sigs.k8s.io/gateway-api/apis/v1alpha3"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ReconciliationLoop represents the data used for a single reconciliation loop
+type ReconciliationLoop struct {
+	logger logr.Logger
+}
+
+// Reconciler reconciles a BackendTLSPolicy object
+type Reconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Loop   *ReconciliationLoop
+}
+
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=backendtlspolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=backendtlspolicies/status,verbs=get;update;patch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+// BackendTLSPolicies are applied to the backends of routes by the Gateway reconciler, which
+// switches them to https. This reconciler reports which Gateways applied the policy.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.19.0/pkg/reconcile
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	defaultResult := ctrl.Result{Requeue: true, RequeueAfter: 60 * time.Second}
+	r.Loop = &ReconciliationLoop{
+		logger: log.FromContext(ctx),
+	}
+	r.Loop.logger.Info(fmt.Sprintf("Reconciling BackendTLSPolicy: %s", req.NamespacedName))
+
+	policy := &gatewayv1alpha3.BackendTLSPolicy{}
+	if err := r.Get(ctx, req.NamespacedName, policy); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	observedStatus := policy.Status.DeepCopy()
+
+	key := routing.PolicyKey{Kind: routing.KindBackendTLSPolicy, NamespacedName: types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name}}
+	if err := routing.SetPolicyAncestors(ctx, r.Client, key, &policy.Status, policy.Generation); err != nil {
+		r.Loop.logger.Error(err, "failed to determine backendTLSPolicy status")
+		return defaultResult, err
+	}
+
+	if equality.Semantic.DeepEqual(observedStatus, &policy.Status) {
+		return ctrl.Result{}, nil
+	}
+	if err := r.Status().Update(ctx, policy); err != nil {
+		r.Loop.logger.Error(err, "failed to update backendTLSPolicy status")
+		return defaultResult, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	enqueuePolicies := routing.EnqueuePolicies(mgr.GetClient(), &gatewayv1alpha3.BackendTLSPolicyList{})
+	builder := ctrl.NewControllerManagedBy(mgr).
+		For(&gatewayv1alpha3.BackendTLSPolicy{}).
+		Watches(&gatewayv1.Gateway{}, enqueuePolicies).
+		Watches(&corev1.ConfigMap{}, routing.EnqueueCACertificatePolicies(mgr.GetClient()))
+	return routing.WatchRoutes(mgr, builder, enqueuePolicies).Complete(r)
+}
@@ -7,19 +7,29 @@ import (
 	"time"
 
 	"github.com/cloudflare/cloudflare-go"
+	cloudflarev1alpha1 "github.com/cyclingwithelephants/cloudflare-gateway-controller/api/v1alpha1"
 	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
 	k8s2 "github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/k8s"
 	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller/routing"
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha3 "sigs.k8s.io/gateway-api/apis/v1alpha3"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 )
 
 // ReconciliationLoop represents the data used for a single reconciliation loop
@@ -30,7 +40,11 @@ type ReconciliationLoop struct {
 	tunnelID         string
 	tunnelSecret     string
 	accountID        string
+	domain           string
 	api              *cf.Api
+	gateway          *gatewayv1.Gateway
+	observedStatus   *gatewayv1.GatewayStatus
+	cloudflared      cloudflarev1alpha1.CloudflaredConfigSpec
 }
 
 // Reconciler reconciles a Gateway object
@@ -38,6 +52,10 @@ type Reconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 	Loop   *ReconciliationLoop
+	// RouterImage is the image of the routers of Gateways that don't override it
+	RouterImage string
+	// Recorder records the events of the Gateways, such as them going into maintenance
+	Recorder record.EventRecorder
 }
 
 func (r *Reconciler) isMine(ctx context.Context, gateway *gatewayv1.Gateway) (bool, error) {
@@ -45,7 +63,7 @@ func (r *Reconciler) isMine(ctx context.Context, gateway *gatewayv1.Gateway) (bo
 		return false, errors.New("returned nil gateway")
 	}
 
-	var gatewayClass *gatewayv1.GatewayClass
+	gatewayClass := &gatewayv1.GatewayClass{}
 	if err := r.Get(
 		ctx,
 		client.ObjectKey{Name: string(gateway.Spec.GatewayClassName)},
@@ -62,83 +80,34 @@ func (r *Reconciler) isMine(ctx context.Context, gateway *gatewayv1.Gateway) (bo
 	return gatewayClass.Spec.ControllerName == controller.Name, nil
 }
 
-func (r *Reconciler) getConfigFromGatewayClass(ctx context.Context, gateway *gatewayv1.Gateway) (k8s2.GatewayClassConfig, error) {
-	if gateway == nil {
-		return k8s2.GatewayClassConfig{}, errors.New("nil gateway")
-	}
-	gatewayClassName := gateway.Spec.GatewayClassName
-	if gatewayClassName == "" {
-		return k8s2.GatewayClassConfig{}, errors.New("gateway class name is empty")
-	}
-
-	gatewayClass := &gatewayv1.GatewayClass{}
-	err := r.Client.Get(ctx, client.ObjectKey{Name: string(gatewayClassName)}, gatewayClass)
-	if err != nil {
-		return k8s2.GatewayClassConfig{}, errors.Wrap(err, "failed to get gateway class")
-	}
-
-	var namespace string
-	if gatewayClass.Spec.ParametersRef.Namespace == nil {
-		namespace = "default"
-	} else {
-		namespace = string(*gatewayClass.Spec.ParametersRef.Namespace)
-	}
-	secret := &corev1.Secret{}
-	err = r.Client.Get(
-		ctx,
-		client.ObjectKey{
-			Name:      gatewayClass.Spec.ParametersRef.Name,
-			Namespace: namespace,
-		},
-		secret,
+func (r *Reconciler) ensureTunnelDeployment() (*appsv1.Deployment, error) {
+	existingDeployment := &appsv1.Deployment{}
+	expectedDeployment := k8s2.BuildTunnelDeployment(
+		r.Loop.GatewayName,
+		r.Loop.GatewayNamespace,
+		r.Loop.tunnelID,
+		tunnelSettings(r.Loop.cloudflared).Args(),
+		gracePeriod(r.Loop.cloudflared),
 	)
-	if err != nil {
-		return k8s2.GatewayClassConfig{}, errors.Wrap(err, "failed to get secret")
-	}
-
-	cloudflareApiToken := string(secret.Data["api_token"])
-	if cloudflareApiToken == "" {
-		return k8s2.GatewayClassConfig{}, errors.New("cloudflare api token is empty")
+	if err := controllerutil.SetControllerReference(r.Loop.gateway, expectedDeployment, r.Scheme); err != nil {
+		return nil, errors.Wrap(err, "failed to set owner reference on deployment")
 	}
-	domain := string(secret.Data["domain"])
-	if domain == "" {
-		return k8s2.GatewayClassConfig{}, errors.New("domain is empty")
-	}
-	emailAddress := string(secret.Data["email"])
-	if emailAddress == "" {
-		return k8s2.GatewayClassConfig{}, errors.New("email is empty")
-	}
-	cloudflareAccountId := string(secret.Data["account_id"])
-	if cloudflareAccountId == "" {
-		return k8s2.GatewayClassConfig{}, errors.New("cloudflare account id is empty")
-	}
-
-	return k8s2.GatewayClassConfig{
-		CloudflareApiToken:  cloudflareApiToken,
-		Domain:              domain,
-		EmailAddress:        emailAddress,
-		CloudflareAccountId: cloudflareAccountId,
-	}, nil
-}
-
-func (r *Reconciler) ensureTunnelDeployment() error {
-	existingDeployment := &appsv1.Deployment{}
-	expectedDeployment := k8s2.BuildTunnelDeployment(r.Loop.GatewayName, r.Loop.GatewayNamespace, r.Loop.tunnelID)
 
 	if err := r.Client.Get(context.Background(), client.ObjectKey{Name: expectedDeployment.Name, Namespace: r.Loop.GatewayNamespace}, existingDeployment); err != nil {
 		if !apierrors.IsNotFound(err) {
-			return errors.Wrap(err, "failed to get existing deployment")
+			return nil, errors.Wrap(err, "failed to get existing deployment")
 		}
 		r.Loop.logger.Info("existing deployment not found, creating")
 		if err := r.Client.Create(context.Background(), expectedDeployment); err != nil {
-			return errors.Wrap(err, "failed to create expectedDeployment")
+			return nil, errors.Wrap(err, "failed to create expectedDeployment")
 		}
 	} else {
+		expectedDeployment.ResourceVersion = existingDeployment.ResourceVersion
 		if err := r.Client.Update(context.Background(), expectedDeployment); err != nil {
-			return errors.Wrap(err, "failed to update expectedDeployment")
+			return nil, errors.Wrap(err, "failed to update expectedDeployment")
 		}
 	}
-	return nil
+	return expectedDeployment, nil
 }
 
 func (r *Reconciler) ensureCloudflareTunnel() (tunnel cloudflare.Tunnel, err error) {
@@ -170,6 +139,9 @@ func (r *Reconciler) ensureTunnelSecret() error {
 	if err != nil {
 		return errors.Wrap(err, "failed to serialize tunnel secret")
 	}
+	if err := controllerutil.SetControllerReference(r.Loop.gateway, secret, r.Scheme); err != nil {
+		return errors.Wrap(err, "failed to set owner reference on secret")
+	}
 
 	if err := r.Client.Get(context.Background(), client.ObjectKey{Name: secret.Name, Namespace: r.Loop.GatewayNamespace}, secret); err != nil {
 		if !apierrors.IsNotFound(err) {
@@ -183,54 +155,174 @@ func (r *Reconciler) ensureTunnelSecret() error {
 	return nil
 }
 
-func (r *Reconciler) createInitialConfigMapIfNotExists(ctx context.Context) error {
-	existingConfigMap := &corev1.ConfigMap{}
-	err := r.Client.Get(
-		ctx,
-		client.ObjectKey{
-			Name:      k8s2.ConfigMapName(r.Loop.GatewayName),
-			Namespace: r.Loop.GatewayNamespace,
-		},
-		existingConfigMap,
-	)
-	if err == nil {
-		r.Loop.logger.Info("existing configmap already exists")
-		return nil
-	}
-	if err != nil && !apierrors.IsNotFound(err) {
-		return errors.Wrap(err, "failed to get existing configmap")
-	}
+// ensureConfigMap writes the routing table of the Gateway into the cloudflared config,
+// only writing the ConfigMap when the rendered config changed
+func (r *Reconciler) ensureConfigMap(ctx context.Context, result *routing.Result) error {
 	configFile, err := cf.NewTunnelConfigFile(
 		r.Loop.tunnelID,
-		[]cf.IngressConfig{},
+		defaultOriginRequest(r.Loop.cloudflared),
+		result.Ingress(),
+		defaultBackendService(r.Loop.cloudflared, r.Loop.GatewayNamespace),
 	)
 	if err != nil {
 		return errors.Wrap(err, "failed to create tunnel config file")
 	}
-	configFileJsonBytes, err := json.Marshal(configFile)
+	configFileYaml, err := configFile.YAML(result.Sources())
 	if err != nil {
-		return errors.Wrap(err, "failed to serialize config")
+		return err
 	}
 	newConfigMap, err := k8s2.BuildTunnelConfigMap(
 		r.Loop.GatewayName,
 		r.Loop.GatewayNamespace,
-		string(configFileJsonBytes),
+		configFileYaml,
+		result.CABundles,
 	)
 	if err != nil {
 		return errors.Wrap(err, "failed to generate configmap definition")
 	}
-	if err := r.Client.Create(ctx, newConfigMap); err != nil {
-		return errors.Wrap(err, "failed to create configmap")
+	if err := controllerutil.SetControllerReference(r.Loop.gateway, newConfigMap, r.Scheme); err != nil {
+		return errors.Wrap(err, "failed to set owner reference on configmap")
+	}
+
+	existingConfigMap := &corev1.ConfigMap{}
+	err = r.Client.Get(ctx, client.ObjectKeyFromObject(newConfigMap), existingConfigMap)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to get existing configmap")
+	}
+	if apierrors.IsNotFound(err) {
+		r.Loop.logger.Info("existing configmap not found, creating")
+		if err := r.Client.Create(ctx, newConfigMap); err != nil {
+			return errors.Wrap(err, "failed to create configmap")
+		}
+		return nil
+	}
+
+	// if there's no change, we don't perform an update
+	if equality.Semantic.DeepEqual(existingConfigMap.Data, newConfigMap.Data) {
+		return nil
+	}
+	existingConfigMap.Data = newConfigMap.Data
+	if err := r.Client.Update(ctx, existingConfigMap); err != nil {
+		return errors.Wrap(err, "failed to update configmap")
+	}
+	return nil
+}
+
+// ensureRouter deploys the router of the Gateway with its routing table, or removes it when the Gateway has none
+func (r *Reconciler) ensureRouter(ctx context.Context, result *routing.Result) error {
+	if result.RouterTable == nil {
+		for _, obj := range []client.Object{
+			&appsv1.Deployment{}, &corev1.Service{}, &corev1.ConfigMap{},
+		} {
+			obj.SetName(k8s2.RouterName(r.Loop.GatewayName))
+			obj.SetNamespace(r.Loop.GatewayNamespace)
+			if err := r.Client.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+				return errors.Wrap(err, "failed to delete router")
+			}
+		}
+		return nil
+	}
+
+	table, err := json.Marshal(result.RouterTable)
+	if err != nil {
+		return errors.Wrap(err, "failed to serialize routing table")
+	}
+	configMap := k8s2.BuildRouterConfigMap(r.Loop.GatewayName, r.Loop.GatewayNamespace, string(table))
+	if err := r.applyRouterObject(ctx, configMap, func(existing client.Object) {
+		existing.(*corev1.ConfigMap).Data = configMap.Data
+	}); err != nil {
+		return err
+	}
+
+	service := k8s2.BuildRouterService(r.Loop.GatewayName, r.Loop.GatewayNamespace)
+	if err := r.applyRouterObject(ctx, service, func(existing client.Object) {
+		// the cluster IP and the defaults of the Service are left as the API server set them
+		existing.(*corev1.Service).Spec.Selector = service.Spec.Selector
+		existing.(*corev1.Service).Spec.Ports = service.Spec.Ports
+	}); err != nil {
+		return err
+	}
+
+	spec := ptr.Deref(r.Loop.cloudflared.Router, cloudflarev1alpha1.RouterSpec{})
+	image := r.RouterImage
+	if spec.Image != nil {
+		image = *spec.Image
+	}
+	if image == "" {
+		return errors.New("the router has no image, set --router-image on the controller or router.image in the CloudflaredConfig")
+	}
+	deployment := k8s2.BuildRouterDeployment(r.Loop.GatewayName, r.Loop.GatewayNamespace, image, ptr.Deref(spec.Replicas, 1))
+	return r.applyRouterObject(ctx, deployment, func(existing client.Object) {
+		existing.(*appsv1.Deployment).Spec = deployment.Spec
+	})
+}
+
+// applyRouterObject creates a router object owned by the Gateway, or updates the existing one through update
+func (r *Reconciler) applyRouterObject(ctx context.Context, obj client.Object, update func(existing client.Object)) error {
+	existing := obj.DeepCopyObject().(client.Object)
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, existing, func() error {
+		update(existing)
+		existing.SetLabels(obj.GetLabels())
+		return controllerutil.SetControllerReference(r.Loop.gateway, existing, r.Scheme)
+	}); err != nil {
+		return errors.Wrapf(err, "failed to apply router %T", obj)
+	}
+	return nil
+}
+
+// ensureEdgeRules makes the rules of the Gateway in the rulesets of its zone match the filters of its routes
+// that cloudflared can't apply
+func (r *Reconciler) ensureEdgeRules(result *routing.Result) error {
+	empty := true
+	for _, rules := range result.EdgeRules {
+		empty = empty && len(rules) == 0
+	}
+	zoneID, err := r.Loop.api.ZoneID(r.Loop.domain)
+	if err != nil {
+		if empty {
+			// tokens that can't read the zone can't have created rules that need cleaning up
+			r.Loop.logger.Info("skipping edge rule cleanup", "reason", err.Error())
+			return nil
+		}
+		return err
+	}
+	owner := cf.EdgeRuleOwner(r.Loop.GatewayNamespace, r.Loop.GatewayName)
+	for _, phase := range routing.EdgePhases {
+		if err := r.Loop.api.SyncEdgeRules(zoneID, phase, owner, result.EdgeRules[phase]); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// countEdgeRules returns the number of rules the Gateway owns in the rulesets of its zone
+func countEdgeRules(result *routing.Result) int {
+	count := 0
+	for _, rules := range result.EdgeRules {
+		count += len(rules)
+	}
+	return count
+}
+
 // +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gateways,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gateways/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gateways/finalizers,verbs=update
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups="",resources=secrets,verbs=create;list;delete
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=discovery.k8s.io,resources=endpointslices,verbs=get;list;watch
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=grpcroutes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=tcproutes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=tlsroutes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=backendtlspolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=referencegrants,verbs=get;list;watch
+// +kubebuilder:rbac:groups=cloudflare.adamland.xyz,resources=originrequestpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=cloudflare.adamland.xyz,resources=cloudflaredconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=multicluster.x-k8s.io,resources=serviceimports,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -253,6 +345,15 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	if !gateway.DeletionTimestamp.IsZero() {
+		// only Gateways of this controller carry the finalizer, so whose Gateway it is needs no checking
+		if err := r.finalize(ctx, gateway); err != nil {
+			r.Loop.logger.Error(err, "failed to finalize gateway")
+			return defaultResult, err
+		}
+		return ctrl.Result{}, nil
+	}
+
 	isMine, err := r.isMine(ctx, gateway)
 	if err != nil {
 		r.Loop.logger.Error(err, "failed to check if the httpRoute is mine")
@@ -264,18 +365,39 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, nil
 	}
 
+	if controllerutil.AddFinalizer(gateway, gatewayFinalizer) {
+		if err := r.Update(ctx, gateway); err != nil {
+			r.Loop.logger.Error(err, "failed to add finalizer")
+			return defaultResult, err
+		}
+	}
+
 	// gateway cfTunnelExists
 	r.Loop.GatewayName = gateway.ObjectMeta.Name
 	r.Loop.GatewayNamespace = gateway.ObjectMeta.Namespace
-	gatewayClassConfig, err := r.getConfigFromGatewayClass(ctx, gateway)
+	r.Loop.gateway = gateway
+	r.Loop.observedStatus = gateway.Status.DeepCopy()
+	gatewayClassConfig, err := k8s2.LoadGatewayClassConfig(ctx, r.Client, gateway)
 	if err != nil {
 		r.Loop.logger.Error(err, "failed to get gateway class config")
+		setCondition(gateway, gatewayv1.GatewayConditionAccepted, metav1.ConditionFalse, gatewayv1.GatewayReasonInvalidParameters, err.Error())
+		r.notProgrammed(ctx, gateway, gatewayv1.GatewayReasonInvalid, "the GatewayClass parameters could not be loaded")
 		return defaultResult, nil
 	}
+	cloudflared, err := r.getCloudflaredConfig(ctx, gateway)
+	if err != nil {
+		r.Loop.logger.Error(err, "failed to get cloudflared config")
+		setCondition(gateway, gatewayv1.GatewayConditionAccepted, metav1.ConditionFalse, gatewayv1.GatewayReasonInvalidParameters, err.Error())
+		r.notProgrammed(ctx, gateway, gatewayv1.GatewayReasonInvalid, "the Gateway parameters could not be loaded")
+		return defaultResult, nil
+	}
+	r.Loop.cloudflared = cloudflared
+	accept(gateway)
 
 	tunnelSecret, err := k8s2.GenerateRandomString(32)
 	if err != nil {
 		r.Loop.logger.Error(err, "failed to generate tunnel secret")
+		r.notProgrammed(ctx, gateway, gatewayv1.GatewayReasonPending, errors.Wrap(err, "failed to generate tunnel secret").Error())
 		return defaultResult, nil
 	}
 	r.Loop.tunnelSecret = tunnelSecret
@@ -287,39 +409,100 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	)
 	if err != nil {
 		r.Loop.logger.Error(err, "failed to create cloudflare api")
+		r.notProgrammed(ctx, gateway, gatewayv1.GatewayReasonPending, errors.Wrap(err, "failed to create cloudflare api").Error())
 		return defaultResult, nil
 	}
 	r.Loop.api = api
+	r.Loop.domain = gatewayClassConfig.Domain
 
 	tunnel, err := r.ensureCloudflareTunnel()
 	if err != nil {
 		r.Loop.logger.Error(err, "failed to deploy tunnel")
+		r.notProgrammed(ctx, gateway, gatewayv1.GatewayReasonPending, errors.Wrap(err, "failed to ensure cloudflare tunnel").Error())
 		return defaultResult, nil
 	}
 	r.Loop.tunnelID = tunnel.ID
 
-	if err := r.createInitialConfigMapIfNotExists(ctx); err != nil {
-		r.Loop.logger.Error(err, "failed to create initial configmap")
+	result, err := routing.NewBuilder(r.Client, r.Loop.gateway).Build(ctx)
+	if err != nil {
+		r.Loop.logger.Error(err, "failed to build routing table")
+		r.notProgrammed(ctx, gateway, gatewayv1.GatewayReasonPending, errors.Wrap(err, "failed to build routing table").Error())
+		return defaultResult, nil
+	}
+	// the router is in place before cloudflared sends requests to it
+	if err := r.ensureRouter(ctx, result); err != nil {
+		r.Loop.logger.Error(err, "failed to ensure router")
+		r.notProgrammed(ctx, gateway, gatewayv1.GatewayReasonPending, errors.Wrap(err, "failed to ensure router").Error())
 		return defaultResult, nil
 	}
+	if err := r.ensureConfigMap(ctx, result); err != nil {
+		r.Loop.logger.Error(err, "failed to ensure configmap")
+		var invalid *cf.InvalidConfigError
+		if errors.As(err, &invalid) {
+			// cloudflared keeps running with the config it has, rather than crash looping on this one
+			r.notProgrammed(ctx, gateway, gatewayv1.GatewayReasonInvalid, invalid.Error()+", the previous config is kept")
+			return defaultResult, nil
+		}
+		r.notProgrammed(ctx, gateway, gatewayv1.GatewayReasonPending, errors.Wrap(err, "failed to render cloudflared config").Error())
+		return defaultResult, nil
+	}
+	setDefaultBackend(gateway, result, defaultBackendService(r.Loop.cloudflared, r.Loop.GatewayNamespace))
+	r.setMaintenance(gateway, routing.MaintenanceService(r.Loop.cloudflared, r.Loop.GatewayNamespace))
 
 	if err := r.ensureTunnelSecret(); err != nil {
 		r.Loop.logger.Error(err, "failed to create tunnel secret")
+		r.notProgrammed(ctx, gateway, gatewayv1.GatewayReasonPending, errors.Wrap(err, "failed to create tunnel credentials").Error())
 		return defaultResult, nil
 	}
-	if err := r.ensureTunnelDeployment(); err != nil {
+	deployment, err := r.ensureTunnelDeployment()
+	if err != nil {
 		r.Loop.logger.Error(err, "failed to ensure tunnel deployment")
+		r.notProgrammed(ctx, gateway, gatewayv1.GatewayReasonPending, errors.Wrap(err, "failed to ensure cloudflared deployment").Error())
+		return defaultResult, nil
+	}
+
+	// the zone is synced once the tunnel is provisioned, so that tokens without access to the zone only cost the
+	// routes the features that need it
+	err = r.ensureEdgeRules(result)
+	if err != nil {
+		r.Loop.logger.Error(err, "failed to ensure edge rules")
+		err = errors.Wrap(err, "failed to ensure zone ruleset rules")
+	}
+	setSynced(gateway, GatewayConditionEdgeRules, err, fmt.Sprintf("the rulesets of the zone hold the %d rules of the routes", countEdgeRules(result)))
+	err = r.ensureDNSRecords(result)
+	if err != nil {
+		r.Loop.logger.Error(err, "failed to ensure dns records")
+		err = errors.Wrap(err, "failed to ensure the dns records of preview hostnames")
+	}
+	setSynced(gateway, GatewayConditionDNSRecords, err, fmt.Sprintf("the zone holds the DNS records of the %d preview hostnames of the routes", len(result.PreviewHostnames())))
+
+	if !k8s2.DeploymentIsReady(deployment) {
+		r.notProgrammed(ctx, gateway, gatewayv1.GatewayReasonPending, "waiting for the cloudflared deployment to become ready")
 		return defaultResult, nil
 	}
+	r.programmed(ctx, gateway)
 	return defaultResult, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&gatewayv1.Gateway{}).
 		Owns(&corev1.ConfigMap{}).
 		Owns(&corev1.Secret{}).
 		Owns(&appsv1.Deployment{}).
-		Complete(r)
+		Owns(&corev1.Service{}).
+		Watches(&corev1.Service{}, routing.EnqueueServiceGateways(mgr.GetClient())).
+		Watches(&discoveryv1.EndpointSlice{}, routing.EnqueueEndpointSliceGateways(mgr.GetClient())).
+		Watches(&cloudflarev1alpha1.OriginRequestPolicy{}, routing.EnqueueManagedGateways(mgr.GetClient())).
+		Watches(&cloudflarev1alpha1.CloudflaredConfig{}, routing.EnqueueManagedGateways(mgr.GetClient()))
+	if routing.Installed(mgr, &gatewayv1alpha3.BackendTLSPolicy{}) {
+		builder = builder.
+			Watches(&gatewayv1alpha3.BackendTLSPolicy{}, routing.EnqueueManagedGateways(mgr.GetClient())).
+			Watches(&corev1.ConfigMap{}, routing.EnqueueCACertificateGateways(mgr.GetClient()))
+	}
+	if routing.Installed(mgr, &gatewayv1beta1.ReferenceGrant{}) {
+		builder = builder.Watches(&gatewayv1beta1.ReferenceGrant{}, routing.EnqueueReferenceGrantGateways(mgr.GetClient()))
+	}
+	return routing.WatchRoutes(mgr, builder, routing.EnqueueGateways()).Complete(r)
 }
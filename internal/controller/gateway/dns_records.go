@@ -0,0 +1,36 @@
+package gateway
+
+import (
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller/routing"
+	"github.com/go-logr/logr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// ensureDNSRecords makes the DNS records of the Gateway in its zone match the preview hostnames of its routes,
+// each a proxied CNAME to the tunnel of the Gateway
+func (r *Reconciler) ensureDNSRecords(result *routing.Result) error {
+	owner := cf.DNSRecordOwner(r.Loop.GatewayNamespace, r.Loop.GatewayName)
+	target := cf.TunnelHostname(r.Loop.tunnelID)
+	return syncDNSRecords(r.Loop.logger, r.Loop.api, r.Loop.domain, owner, target, result.PreviewHostnames())
+}
+
+// removeDNSRecords removes every DNS record a Gateway owns in its zone
+func removeDNSRecords(logger logr.Logger, api *cf.Api, domain string, gateway *gatewayv1.Gateway) error {
+	return syncDNSRecords(logger, api, domain, cf.DNSRecordOwner(gateway.Namespace, gateway.Name), "", nil)
+}
+
+// syncDNSRecords makes the DNS records owned by owner in the zone of domain equal to CNAME records from each of
+// hostnames to target
+func syncDNSRecords(logger logr.Logger, api *cf.Api, domain string, owner string, target string, hostnames []string) error {
+	zoneID, err := api.ZoneID(domain)
+	if err != nil {
+		if len(hostnames) == 0 {
+			// tokens that can't read the zone can't have created records that need cleaning up
+			logger.Info("skipping dns record cleanup", "reason", err.Error())
+			return nil
+		}
+		return err
+	}
+	return api.SyncDNSRecords(zoneID, owner, target, hostnames)
+}
@@ -0,0 +1,179 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	"github.com/go-logr/logr"
+	"k8s.io/utils/ptr"
+)
+
+// fakeDNS serves the zone and DNS record endpoints of the Cloudflare API for a single zone
+type fakeDNS struct {
+	mu      sync.Mutex
+	records []cloudflare.DNSRecord
+	nextID  int
+}
+
+func (z *fakeDNS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Path == "/zones" {
+		writeZones(w)
+		return
+	}
+	id, _ := strings.CutPrefix(r.URL.Path, "/zones/zone/dns_records")
+	id = strings.TrimPrefix(id, "/")
+	switch r.Method {
+	case http.MethodGet:
+		var found []cloudflare.DNSRecord
+		for _, record := range z.records {
+			if comment := r.URL.Query().Get("comment"); comment != "" && record.Comment != comment {
+				continue
+			}
+			if name := r.URL.Query().Get("name"); name != "" && record.Name != name {
+				continue
+			}
+			found = append(found, record)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"success":     true,
+			"result":      found,
+			"result_info": cloudflare.ResultInfo{Page: 1, PerPage: 100, TotalPages: 1, Count: len(found), Total: len(found)},
+		})
+	case http.MethodPost:
+		var record cloudflare.DNSRecord
+		_ = json.NewDecoder(r.Body).Decode(&record)
+		z.nextID++
+		record.ID = fmt.Sprintf("created-%d", z.nextID)
+		z.records = append(z.records, record)
+		_ = json.NewEncoder(w).Encode(map[string]any{"success": true, "result": record})
+	case http.MethodPatch:
+		var update cloudflare.DNSRecord
+		_ = json.NewDecoder(r.Body).Decode(&update)
+		for i := range z.records {
+			if z.records[i].ID == id {
+				z.records[i].Type = update.Type
+				z.records[i].Content = update.Content
+				z.records[i].Proxied = update.Proxied
+				_ = json.NewEncoder(w).Encode(map[string]any{"success": true, "result": z.records[i]})
+			}
+		}
+	case http.MethodDelete:
+		for i := range z.records {
+			if z.records[i].ID == id {
+				z.records = append(z.records[:i], z.records[i+1:]...)
+				break
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"success": true, "result": map[string]string{"id": id}})
+	}
+}
+
+func TestSyncDNSRecords(t *testing.T) {
+	owner := cf.DNSRecordOwner("default", "public")
+	other := cf.DNSRecordOwner("default", "internal")
+	target := cf.TunnelHostname("tunnel")
+	record := func(id string, name string, content string, comment string) cloudflare.DNSRecord {
+		return cloudflare.DNSRecord{ID: id, Type: "CNAME", Name: name, Content: content, Proxied: ptr.To(true), Comment: comment}
+	}
+
+	tests := []struct {
+		name      string
+		records   []cloudflare.DNSRecord
+		hostnames []string
+		// want holds the records of the zone after the sync, as name -> content (comment)
+		want []string
+	}{
+		{
+			name:      "Preview hostnames without records",
+			hostnames: []string{"pr-1-previews.example.com", "pr-2-previews.example.com"},
+			want: []string{
+				"pr-1-previews.example.com -> " + target + " (" + owner + ")",
+				"pr-2-previews.example.com -> " + target + " (" + owner + ")",
+			},
+		},
+		{
+			name: "Routes that lost their preview hostname",
+			records: []cloudflare.DNSRecord{
+				record("1", "pr-1-previews.example.com", target, owner),
+				record("2", "pr-2-previews.example.com", target, owner),
+				record("3", "pr-3-previews.example.com", "other.cfargotunnel.com", other),
+			},
+			hostnames: []string{"pr-2-previews.example.com"},
+			want: []string{
+				"pr-2-previews.example.com -> " + target + " (" + owner + ")",
+				"pr-3-previews.example.com -> other.cfargotunnel.com (" + other + ")",
+			},
+		},
+		{
+			name: "Records pointing somewhere else",
+			records: []cloudflare.DNSRecord{
+				record("1", "pr-1-previews.example.com", "old.cfargotunnel.com", owner),
+			},
+			hostnames: []string{"pr-1-previews.example.com"},
+			want: []string{
+				"pr-1-previews.example.com -> " + target + " (" + owner + ")",
+			},
+		},
+		{
+			name: "Hostnames with records managed outside the cluster",
+			records: []cloudflare.DNSRecord{
+				record("1", "pr-1-previews.example.com", "origin.example.net", ""),
+			},
+			hostnames: []string{"pr-1-previews.example.com"},
+			want: []string{
+				"pr-1-previews.example.com -> origin.example.net ()",
+			},
+		},
+		{
+			name: "Deleted Gateways",
+			records: []cloudflare.DNSRecord{
+				record("1", "pr-1-previews.example.com", target, owner),
+				record("2", "pr-2-previews.example.com", "other.cfargotunnel.com", other),
+			},
+			want: []string{
+				"pr-2-previews.example.com -> other.cfargotunnel.com (" + other + ")",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone := &fakeDNS{records: tt.records}
+			server := httptest.NewServer(zone)
+			defer server.Close()
+			client, err := cloudflare.NewWithAPIToken("token", cloudflare.BaseURL(server.URL))
+			if err != nil {
+				t.Fatal(err)
+			}
+			api := &cf.Api{Client: client, Ctx: context.Background()}
+
+			if err := syncDNSRecords(logr.Discard(), api, "example.com", owner, target, tt.hostnames); err != nil {
+				t.Fatalf("syncDNSRecords() error = %v", err)
+			}
+
+			var got []string
+			for _, record := range zone.records {
+				if record.Type != "CNAME" || !ptr.Deref(record.Proxied, false) {
+					t.Errorf("record %s is a %s record, proxied %v, want a proxied CNAME", record.Name, record.Type, record.Proxied)
+				}
+				got = append(got, fmt.Sprintf("%s -> %s (%s)", record.Name, record.Content, record.Comment))
+			}
+			sort.Strings(got)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("records = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
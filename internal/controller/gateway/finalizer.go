@@ -0,0 +1,88 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	k8s2 "github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/k8s"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller/routing"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	// gatewayFinalizer holds deleted Gateways until what they own in their zone is removed: the rules of its
+	// rulesets, which would otherwise keep applying the filters of their routes to the traffic of the zone, and
+	// the DNS records of their preview hostnames
+	gatewayFinalizer = "cloudflare.adamland.xyz/zone-cleanup"
+
+	// eventReasonZoneCleanupSkipped is used when a deleted Gateway is let go without cleaning up its zone, as its
+	// GatewayClass parameters are gone or invalid
+	eventReasonZoneCleanupSkipped = "ZoneCleanupSkipped"
+)
+
+// finalize removes the rules and DNS records a deleted Gateway owns in its zone, then removes its finalizer
+func (r *Reconciler) finalize(ctx context.Context, gateway *gatewayv1.Gateway) error {
+	if !controllerutil.ContainsFinalizer(gateway, gatewayFinalizer) {
+		return nil
+	}
+	config, err := k8s2.LoadGatewayClassConfig(ctx, r.Client, gateway)
+	if err != nil {
+		if !parametersGone(err) {
+			return errors.Wrap(err, "failed to get gateway class config")
+		}
+		// the GatewayClass or its Secret is often deleted along with its Gateways, which would hang forever
+		// waiting for parameters that won't come back
+		r.Loop.logger.Error(err, "skipping zone cleanup of deleted gateway")
+		r.Recorder.Eventf(gateway, corev1.EventTypeWarning, eventReasonZoneCleanupSkipped,
+			"the rules and DNS records of the Gateway are left in its zone, its GatewayClass parameters could not be loaded: %s", err)
+		return r.removeFinalizer(ctx, gateway)
+	}
+	api, err := cf.NewAPI(ctx, config.CloudflareApiToken, config.CloudflareAccountId)
+	if err != nil {
+		return errors.Wrap(err, "failed to create cloudflare api")
+	}
+	if err := removeEdgeRules(r.Loop.logger, api, config.Domain, gateway); err != nil {
+		return err
+	}
+	if err := removeDNSRecords(r.Loop.logger, api, config.Domain, gateway); err != nil {
+		return err
+	}
+	return r.removeFinalizer(ctx, gateway)
+}
+
+func (r *Reconciler) removeFinalizer(ctx context.Context, gateway *gatewayv1.Gateway) error {
+	controllerutil.RemoveFinalizer(gateway, gatewayFinalizer)
+	if err := r.Update(ctx, gateway); err != nil {
+		return errors.Wrap(err, "failed to remove finalizer")
+	}
+	return nil
+}
+
+// parametersGone reports whether the GatewayClass parameters of a Gateway failed to load for good: the
+// GatewayClass or its Secret is not found, or the parameters are invalid. Other API errors may pass
+func parametersGone(err error) bool {
+	var status apierrors.APIStatus
+	return apierrors.IsNotFound(err) || !errors.As(err, &status)
+}
+
+// removeEdgeRules removes every rule a Gateway owns in the rulesets of its zone
+func removeEdgeRules(logger logr.Logger, api *cf.Api, domain string, gateway *gatewayv1.Gateway) error {
+	zoneID, err := api.ZoneID(domain)
+	if err != nil {
+		// tokens that can't read the zone can't have created rules that need cleaning up
+		logger.Info("skipping edge rule cleanup", "reason", err.Error())
+		return nil
+	}
+	owner := cf.EdgeRuleOwner(gateway.Namespace, gateway.Name)
+	for _, phase := range routing.EdgePhases {
+		if err := api.SyncEdgeRules(zoneID, phase, owner, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller/routing"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// fakeZone serves the zone and ruleset endpoints of the Cloudflare API for a single zone, recording the rulesets
+// written to it by phase
+type fakeZone struct {
+	mu       sync.Mutex
+	rulesets map[string][]cloudflare.RulesetRule
+	updated  map[string][]cloudflare.RulesetRule
+}
+
+// writeZones answers the zone lookups of the Cloudflare API with the zone of example.com
+func writeZones(w http.ResponseWriter) {
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"success":     true,
+		"result":      []cloudflare.Zone{{ID: "zone", Name: "example.com"}},
+		"result_info": cloudflare.ResultInfo{Page: 1, PerPage: 50, TotalPages: 1, Count: 1, Total: 1},
+	})
+}
+
+func (z *fakeZone) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Path == "/zones" {
+		writeZones(w)
+		return
+	}
+	phase, ok := strings.CutPrefix(r.URL.Path, "/zones/zone/rulesets/phases/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	phase = strings.TrimSuffix(phase, "/entrypoint")
+	switch r.Method {
+	case http.MethodGet:
+		rules, ok := z.rulesets[phase]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"success": false,
+				"errors":  []cloudflare.ResponseInfo{{Code: 10003, Message: "not found"}},
+			})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"success": true, "result": cloudflare.Ruleset{Phase: phase, Rules: rules}})
+	case http.MethodPut:
+		var params cloudflare.UpdateEntrypointRulesetParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		z.updated[phase] = params.Rules
+		z.rulesets[phase] = params.Rules
+		_ = json.NewEncoder(w).Encode(map[string]any{"success": true, "result": cloudflare.Ruleset{Phase: phase, Rules: params.Rules}})
+	}
+}
+
+func TestRemoveEdgeRules(t *testing.T) {
+	gateway := &gatewayv1.Gateway{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "public"}}
+	owner := cf.EdgeRuleOwner(gateway.Namespace, gateway.Name)
+	other := cf.EdgeRuleOwner("default", "internal")
+	zone := &fakeZone{
+		rulesets: map[string][]cloudflare.RulesetRule{
+			string(routing.RedirectPhase): {
+				{Ref: owner + "redirect", Expression: "true", Action: "redirect"},
+				{Ref: other + "redirect", Expression: "true", Action: "redirect"},
+				{Ref: "managed-outside-the-cluster", Expression: "true", Action: "redirect"},
+			},
+			string(routing.RequestHeaderPhase): {
+				{Ref: owner + "header", Expression: "true", Action: "rewrite"},
+			},
+			string(routing.ResponseHeaderPhase): {
+				{Ref: other + "header", Expression: "true", Action: "rewrite"},
+			},
+		},
+		updated: map[string][]cloudflare.RulesetRule{},
+	}
+	server := httptest.NewServer(zone)
+	defer server.Close()
+	client, err := cloudflare.NewWithAPIToken("token", cloudflare.BaseURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := &cf.Api{Client: client, Ctx: context.Background()}
+
+	if err := removeEdgeRules(logr.Discard(), api, "example.com", gateway); err != nil {
+		t.Fatalf("removeEdgeRules() error = %v", err)
+	}
+
+	want := map[string][]string{
+		string(routing.RedirectPhase):      {other + "redirect", "managed-outside-the-cluster"},
+		string(routing.RequestHeaderPhase): {},
+	}
+	if len(zone.updated) != len(want) {
+		t.Errorf("updated the rulesets of %d phases, want %d: %v", len(zone.updated), len(want), zone.updated)
+	}
+	for phase, refs := range want {
+		rules, ok := zone.updated[phase]
+		if !ok {
+			t.Errorf("the %s ruleset was not updated", phase)
+			continue
+		}
+		if len(rules) != len(refs) {
+			t.Errorf("the %s ruleset has %d rules, want %v", phase, len(rules), refs)
+			continue
+		}
+		for i, ref := range refs {
+			if rules[i].Ref != ref {
+				t.Errorf("the %s ruleset has rule %s at %d, want %s", phase, rules[i].Ref, i, ref)
+			}
+		}
+	}
+}
+
+func TestParametersGone(t *testing.T) {
+	gatewayClass := schema.GroupResource{Group: gatewayv1.GroupName, Resource: "gatewayclasses"}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "GatewayClasses not found",
+			err:  errors.Wrap(apierrors.NewNotFound(gatewayClass, "cloudflare"), "failed to get gateway class"),
+			want: true,
+		},
+		{
+			name: "Invalid parameters",
+			err:  errors.New("cloudflare api token is empty"),
+			want: true,
+		},
+		{
+			name: "Unavailable API servers",
+			err:  errors.Wrap(apierrors.NewServiceUnavailable("try again"), "failed to get secret"),
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parametersGone(tt.err); got != tt.want {
+				t.Errorf("parametersGone() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	cloudflarev1alpha1 "github.com/cyclingwithelephants/cloudflare-gateway-controller/api/v1alpha1"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller/routing"
+	"github.com/pkg/errors"
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// getCloudflaredConfig returns the validated settings of the CloudflaredConfig of the gateway, see routing.LoadCloudflaredConfig
+func (r *Reconciler) getCloudflaredConfig(ctx context.Context, gateway *gatewayv1.Gateway) (cloudflarev1alpha1.CloudflaredConfigSpec, error) {
+	spec, err := routing.LoadCloudflaredConfig(ctx, r.Client, gateway)
+	if err != nil {
+		return cloudflarev1alpha1.CloudflaredConfigSpec{}, err
+	}
+	if err := validateCloudflaredConfig(spec); err != nil {
+		return cloudflarev1alpha1.CloudflaredConfigSpec{}, errors.Wrapf(
+			err, "%s %s is invalid", routing.KindCloudflaredConfig, gateway.Spec.Infrastructure.ParametersRef.Name,
+		)
+	}
+	return spec, nil
+}
+
+// validateCloudflaredConfig catches the combinations of settings cloudflared refuses to start with
+func validateCloudflaredConfig(spec cloudflarev1alpha1.CloudflaredConfigSpec) error {
+	if ptr.Deref(spec.PostQuantum, false) && spec.Protocol == cloudflarev1alpha1.ProtocolHTTP2 {
+		return errors.New("postQuantum requires the quic protocol")
+	}
+	if spec.GracePeriod != nil && spec.GracePeriod.Duration < 0 {
+		return errors.New("gracePeriod must not be negative")
+	}
+	if err := validateBackend("defaultBackend", spec.DefaultBackend); err != nil {
+		return err
+	}
+	if spec.ReadinessGate != nil {
+		if err := validateBackend("readinessGate.response", spec.ReadinessGate.Response); err != nil {
+			return err
+		}
+	}
+	if maintenance := spec.Maintenance; maintenance != nil {
+		if maintenance.Response != nil && maintenance.Page != nil {
+			return errors.New("maintenance must set at most one of response and page")
+		}
+		if err := validateBackend("maintenance.response", maintenance.Response); err != nil {
+			return err
+		}
+		if maintenance.Page != nil {
+			page, err := url.Parse(*maintenance.Page)
+			if err != nil || page.Scheme != "https" || page.Host == "" || strings.Trim(page.Path, "/") != "" {
+				return errors.Errorf("maintenance.page %q must be an https URL without a path", *maintenance.Page)
+			}
+		}
+	}
+	if spec.PreviewHostname != nil {
+		tmpl, err := template.New("previewHostname").Option("missingkey=error").Parse(*spec.PreviewHostname)
+		if err != nil {
+			return errors.Wrap(err, "previewHostname is not a valid template")
+		}
+		if err := tmpl.Execute(io.Discard, routing.PreviewHostnameData{}); err != nil {
+			return errors.Wrap(err, "previewHostname may only use .Route, .Namespace, .Gateway and .Domain")
+		}
+	}
+	return nil
+}
+
+// validateBackend checks that a backend of a CloudflaredConfig, when set, sets exactly one way of answering
+func validateBackend(field string, backend *cloudflarev1alpha1.DefaultBackend) error {
+	if backend == nil {
+		return nil
+	}
+	set := 0
+	for _, isSet := range []bool{backend.StatusCode != nil, ptr.Deref(backend.HelloWorld, false), backend.Service != nil} {
+		if isSet {
+			set++
+		}
+	}
+	if set != 1 {
+		return errors.Errorf("%s must set exactly one of statusCode, helloWorld and service", field)
+	}
+	return nil
+}
+
+// tunnelSettings converts the settings of a CloudflaredConfig into the flags of cloudflared
+func tunnelSettings(spec cloudflarev1alpha1.CloudflaredConfigSpec) cf.TunnelSettings {
+	settings := cf.TunnelSettings{
+		Protocol:      string(spec.Protocol),
+		LogLevel:      string(ptr.Deref(spec.LogLevel, "")),
+		LogFormat:     string(ptr.Deref(spec.LogFormat, "")),
+		LogFile:       ptr.Deref(spec.LogFile, ""),
+		EdgeIPVersion: string(ptr.Deref(spec.EdgeIPVersion, "")),
+		Region:        ptr.Deref(spec.Region, ""),
+		PostQuantum:   ptr.Deref(spec.PostQuantum, false),
+	}
+	if spec.Retries != nil {
+		settings.Retries = ptr.To(int(*spec.Retries))
+	}
+	if spec.GracePeriod != nil {
+		settings.GracePeriod = spec.GracePeriod.Duration.String()
+	}
+	if spec.HAConnections != nil {
+		settings.HAConnections = ptr.To(int(*spec.HAConnections))
+	}
+	return settings
+}
+
+// gracePeriod returns how long cloudflared drains connections on shutdown, zero for the cloudflared default
+func gracePeriod(spec cloudflarev1alpha1.CloudflaredConfigSpec) time.Duration {
+	if spec.GracePeriod == nil {
+		return 0
+	}
+	return spec.GracePeriod.Duration
+}
+
+// defaultBackendService returns the cloudflared service of the default backend of the Gateway, empty for the
+// default of cf.NewTunnelConfigFile
+func defaultBackendService(spec cloudflarev1alpha1.CloudflaredConfigSpec, namespace string) string {
+	return routing.BackendService(spec.DefaultBackend, namespace)
+}
+
+// defaultOriginRequest returns the origin settings every ingress rule of the Gateway inherits
+func defaultOriginRequest(spec cloudflarev1alpha1.CloudflaredConfigSpec) *cf.OriginRequestConfig {
+	if spec.OriginRequest == nil {
+		return nil
+	}
+	return routing.OriginRequestConfig(*spec.OriginRequest)
+}
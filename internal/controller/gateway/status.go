@@ -0,0 +1,244 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller/routing"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	// GatewayConditionDefaultBackend reports what answers the requests of the Gateway that no route matches
+	GatewayConditionDefaultBackend gatewayv1.GatewayConditionType = "DefaultBackend"
+	// GatewayReasonConfigured is used when the default backend of the CloudflaredConfig of the Gateway is in effect
+	GatewayReasonConfigured gatewayv1.GatewayConditionReason = "Configured"
+	// GatewayReasonNotConfigured is used when requests no route matches are answered with a 404
+	GatewayReasonNotConfigured gatewayv1.GatewayConditionReason = "NotConfigured"
+	// GatewayReasonRoute is used when a route matches every request, leaving nothing to the default backend
+	GatewayReasonRoute gatewayv1.GatewayConditionReason = "Route"
+
+	// GatewayConditionMaintenance is set to True while the Gateway carries routing.AnnotationMaintenance, it is
+	// absent otherwise
+	GatewayConditionMaintenance gatewayv1.GatewayConditionType = "Maintenance"
+	// GatewayReasonMaintenance is used while every route of the Gateway answers with the maintenance response
+	GatewayReasonMaintenance gatewayv1.GatewayConditionReason = "Maintenance"
+
+	// GatewayConditionEdgeRules reports whether the rules of the Gateway in the rulesets of its zone match the
+	// filters of its routes that cloudflared can't apply. The tunnel is served either way
+	GatewayConditionEdgeRules gatewayv1.GatewayConditionType = "EdgeRules"
+	// GatewayConditionDNSRecords reports whether the DNS records of the Gateway in its zone match the preview
+	// hostnames of its routes. The tunnel is served either way
+	GatewayConditionDNSRecords gatewayv1.GatewayConditionType = "DNSRecords"
+	// GatewayReasonSynced is used when what the Gateway owns in its zone matches its routes
+	GatewayReasonSynced gatewayv1.GatewayConditionReason = "Synced"
+	// GatewayReasonSyncFailed is used when what the Gateway owns in its zone could not be updated, such as with an
+	// API token that only has access to tunnels
+	GatewayReasonSyncFailed gatewayv1.GatewayConditionReason = "SyncFailed"
+)
+
+func setCondition(
+	gateway *gatewayv1.Gateway,
+	conditionType gatewayv1.GatewayConditionType,
+	status metav1.ConditionStatus,
+	reason gatewayv1.GatewayConditionReason,
+	message string,
+) {
+	meta.SetStatusCondition(&gateway.Status.Conditions, metav1.Condition{
+		Type:               string(conditionType),
+		Status:             status,
+		ObservedGeneration: gateway.Generation,
+		Reason:             string(reason),
+		Message:            message,
+	})
+}
+
+func setListenerCondition(
+	gateway *gatewayv1.Gateway,
+	listenerStatus *gatewayv1.ListenerStatus,
+	conditionType gatewayv1.ListenerConditionType,
+	status metav1.ConditionStatus,
+	reason gatewayv1.ListenerConditionReason,
+	message string,
+) {
+	meta.SetStatusCondition(&listenerStatus.Conditions, metav1.Condition{
+		Type:               string(conditionType),
+		Status:             status,
+		ObservedGeneration: gateway.Generation,
+		Reason:             string(reason),
+		Message:            message,
+	})
+}
+
+// accept sets the Accepted condition from the validity of the listeners. The Gateway is only
+// rejected outright when none of its listeners can be served by cloudflared
+func accept(gateway *gatewayv1.Gateway) {
+	var invalid []gatewayv1.SectionName
+	for _, listener := range gateway.Spec.Listeners {
+		if len(routing.SupportedKinds(listener)) == 0 {
+			invalid = append(invalid, listener.Name)
+		}
+	}
+	switch {
+	case len(invalid) == 0:
+		setCondition(gateway, gatewayv1.GatewayConditionAccepted, metav1.ConditionTrue, gatewayv1.GatewayReasonAccepted, "Gateway is accepted")
+	case len(invalid) < len(gateway.Spec.Listeners):
+		setCondition(gateway, gatewayv1.GatewayConditionAccepted, metav1.ConditionTrue, gatewayv1.GatewayReasonListenersNotValid, fmt.Sprintf("listeners %v are not supported", invalid))
+	default:
+		setCondition(gateway, gatewayv1.GatewayConditionAccepted, metav1.ConditionFalse, gatewayv1.GatewayReasonListenersNotValid, "none of the listeners are supported")
+	}
+}
+
+// unsupportedMessage explains why a listener can't be served by cloudflared
+func unsupportedMessage(listener gatewayv1.Listener) string {
+	if listener.Protocol == gatewayv1.TLSProtocolType {
+		return "TLS listeners are only supported in Passthrough mode, Cloudflare terminates client TLS at its edge"
+	}
+	return fmt.Sprintf("protocol %s is not supported", listener.Protocol)
+}
+
+// listenerStatuses builds the status of every listener, carrying over existing conditions
+// so that their transition times are only bumped when they actually change
+func listenerStatuses(gateway *gatewayv1.Gateway, attachedRoutes map[gatewayv1.SectionName]int32) []gatewayv1.ListenerStatus {
+	programmed := meta.IsStatusConditionTrue(gateway.Status.Conditions, string(gatewayv1.GatewayConditionProgrammed))
+	existing := map[gatewayv1.SectionName]gatewayv1.ListenerStatus{}
+	for _, listenerStatus := range gateway.Status.Listeners {
+		existing[listenerStatus.Name] = listenerStatus
+	}
+
+	statuses := make([]gatewayv1.ListenerStatus, 0, len(gateway.Spec.Listeners))
+	for _, listener := range gateway.Spec.Listeners {
+		allowed, invalid := routing.AllowedKinds(listener)
+		listenerStatus := gatewayv1.ListenerStatus{
+			Name:           listener.Name,
+			SupportedKinds: append([]gatewayv1.RouteGroupKind{}, allowed...),
+			AttachedRoutes: attachedRoutes[listener.Name],
+			Conditions:     existing[listener.Name].Conditions,
+		}
+
+		if len(routing.SupportedKinds(listener)) == 0 {
+			message := unsupportedMessage(listener)
+			setListenerCondition(gateway, &listenerStatus, gatewayv1.ListenerConditionAccepted, metav1.ConditionFalse, gatewayv1.ListenerReasonUnsupportedProtocol, message)
+			setListenerCondition(gateway, &listenerStatus, gatewayv1.ListenerConditionProgrammed, metav1.ConditionFalse, gatewayv1.ListenerReasonInvalid, message)
+		} else {
+			setListenerCondition(gateway, &listenerStatus, gatewayv1.ListenerConditionAccepted, metav1.ConditionTrue, gatewayv1.ListenerReasonAccepted, "listener is accepted")
+			if programmed {
+				setListenerCondition(gateway, &listenerStatus, gatewayv1.ListenerConditionProgrammed, metav1.ConditionTrue, gatewayv1.ListenerReasonProgrammed, "listener is served by the tunnel")
+			} else {
+				setListenerCondition(gateway, &listenerStatus, gatewayv1.ListenerConditionProgrammed, metav1.ConditionFalse, gatewayv1.ListenerReasonPending, "waiting for the Gateway to be programmed")
+			}
+		}
+
+		if len(invalid) > 0 {
+			setListenerCondition(gateway, &listenerStatus, gatewayv1.ListenerConditionResolvedRefs, metav1.ConditionFalse, gatewayv1.ListenerReasonInvalidRouteKinds, fmt.Sprintf("route kinds %v are not supported", invalid))
+		} else {
+			setListenerCondition(gateway, &listenerStatus, gatewayv1.ListenerConditionResolvedRefs, metav1.ConditionTrue, gatewayv1.ListenerReasonResolvedRefs, "all references are resolved")
+		}
+		setListenerCondition(gateway, &listenerStatus, gatewayv1.ListenerConditionConflicted, metav1.ConditionFalse, gatewayv1.ListenerReasonNoConflicts, "listener does not conflict")
+
+		statuses = append(statuses, listenerStatus)
+	}
+	return statuses
+}
+
+// setDefaultBackend reports what answers the requests of the Gateway that no route matches. A route that matches
+// every request takes the place of the default backend
+func setDefaultBackend(gateway *gatewayv1.Gateway, result *routing.Result, service string) {
+	if len(result.Rules) > 0 {
+		last := result.Rules[len(result.Rules)-1]
+		if last.Ingress.Hostname == "" && last.Ingress.Path == "" {
+			setCondition(gateway, GatewayConditionDefaultBackend, metav1.ConditionFalse, GatewayReasonRoute, fmt.Sprintf(
+				"%s matches every request, so the default backend is not used", last.Source(),
+			))
+			return
+		}
+	}
+	reason := GatewayReasonConfigured
+	if service == "" {
+		service, reason = cf.IngressDefaultBackend, GatewayReasonNotConfigured
+	}
+	setCondition(gateway, GatewayConditionDefaultBackend, metav1.ConditionTrue, reason, fmt.Sprintf(
+		"requests no route matches are answered by %s", service,
+	))
+}
+
+// setMaintenance reports whether the routes of the Gateway answer with the maintenance response, recording an
+// event when the Gateway goes into or leaves maintenance
+func (r *Reconciler) setMaintenance(gateway *gatewayv1.Gateway, service string) {
+	was := meta.IsStatusConditionTrue(r.Loop.observedStatus.Conditions, string(GatewayConditionMaintenance))
+	if !routing.GatewayInMaintenance(gateway) {
+		meta.RemoveStatusCondition(&gateway.Status.Conditions, string(GatewayConditionMaintenance))
+		if was {
+			r.Recorder.Event(gateway, corev1.EventTypeNormal, routing.EventReasonMaintenanceEnded, "requests are sent to the backends of the routes again")
+		}
+		return
+	}
+	message := fmt.Sprintf("requests of every route are answered by %s", service)
+	setCondition(gateway, GatewayConditionMaintenance, metav1.ConditionTrue, GatewayReasonMaintenance, message)
+	if !was {
+		r.Recorder.Event(gateway, corev1.EventTypeNormal, routing.EventReasonMaintenanceStarted, message)
+	}
+}
+
+// setSynced reports how syncing what the Gateway owns in its zone went, with message describing the synced state
+func setSynced(gateway *gatewayv1.Gateway, conditionType gatewayv1.GatewayConditionType, err error, message string) {
+	if err != nil {
+		setCondition(gateway, conditionType, metav1.ConditionFalse, GatewayReasonSyncFailed, err.Error())
+		return
+	}
+	setCondition(gateway, conditionType, metav1.ConditionTrue, GatewayReasonSynced, message)
+}
+
+// notProgrammed records that provisioning stopped before cloudflared could serve the tunnel
+func (r *Reconciler) notProgrammed(
+	ctx context.Context,
+	gateway *gatewayv1.Gateway,
+	reason gatewayv1.GatewayConditionReason,
+	message string,
+) {
+	setCondition(gateway, gatewayv1.GatewayConditionProgrammed, metav1.ConditionFalse, reason, message)
+	r.updateStatus(ctx, gateway)
+}
+
+// programmed records that every provisioning step succeeded and cloudflared is serving the tunnel
+func (r *Reconciler) programmed(ctx context.Context, gateway *gatewayv1.Gateway) {
+	setCondition(
+		gateway,
+		gatewayv1.GatewayConditionProgrammed,
+		metav1.ConditionTrue,
+		gatewayv1.GatewayReasonProgrammed,
+		fmt.Sprintf("cloudflared is serving tunnel %s", r.Loop.tunnelID),
+	)
+	r.updateStatus(ctx, gateway)
+}
+
+// updateStatus writes the Gateway status if it differs from the one observed at the start of
+// the loop. Failures are logged rather than returned so the regular requeue interval still applies
+func (r *Reconciler) updateStatus(ctx context.Context, gateway *gatewayv1.Gateway) {
+	attachedRoutes, err := routing.CountAttachedRoutes(ctx, r.Client, gateway)
+	if err != nil {
+		r.Loop.logger.Error(err, "failed to count attached routes")
+		return
+	}
+	gateway.Status.Listeners = listenerStatuses(gateway, attachedRoutes)
+
+	if r.Loop.tunnelID != "" {
+		addressType := gatewayv1.HostnameAddressType
+		gateway.Status.Addresses = []gatewayv1.GatewayStatusAddress{{
+			Type:  &addressType,
+			Value: cf.TunnelHostname(r.Loop.tunnelID),
+		}}
+	}
+
+	if equality.Semantic.DeepEqual(r.Loop.observedStatus, &gateway.Status) {
+		return
+	}
+	if err := r.Status().Update(ctx, gateway); err != nil {
+		r.Loop.logger.Error(err, "failed to update gateway status")
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"k8s.io/apimachinery/pkg/runtime"
@@ -56,51 +58,48 @@ func (r *Reconciler) validate(ctx context.Context, gatewayClass *gatewayv1.Gatew
 }
 
 func (r *Reconciler) reject(ctx context.Context, gatewayClass *gatewayv1.GatewayClass, message string) error {
-	r.logger.Info("updating status")
-	for i, condition := range gatewayClass.Status.Conditions {
-		if condition.Type != "Accepted" {
-			continue
-		}
-		r.logger.Info("Updating status")
-		gatewayClass.Status.Conditions[i] = metav1.Condition{
-			Type:               "Accepted",
-			Status:             "False",
-			LastTransitionTime: metav1.Time{Time: time.Now()},
-			Reason:             "Invalid",
-			Message:            message,
-		}
-	}
+	observed := gatewayClass.Status.DeepCopy()
+	setAccepted(gatewayClass, metav1.ConditionFalse, gatewayv1.GatewayClassReasonInvalidParameters, message)
+	return r.updateStatus(ctx, gatewayClass, observed)
+}
 
-	if err := r.Status().Update(ctx, gatewayClass); err != nil {
-		r.logger.Info(fmt.Sprintf("Failed to update condition status: %v\n", err))
-		return err
-	}
+func (r *Reconciler) accept(ctx context.Context, gatewayClass *gatewayv1.GatewayClass) error {
+	observed := gatewayClass.Status.DeepCopy()
+	setAccepted(gatewayClass, metav1.ConditionTrue, gatewayv1.GatewayClassReasonAccepted, "GatewayClass is accepted")
+	gatewayClass.Status.SupportedFeatures = supportedFeatures()
+	return r.updateStatus(ctx, gatewayClass, observed)
+}
 
-	return nil
+func setAccepted(
+	gatewayClass *gatewayv1.GatewayClass,
+	status metav1.ConditionStatus,
+	reason gatewayv1.GatewayClassConditionReason,
+	message string,
+) {
+	meta.SetStatusCondition(&gatewayClass.Status.Conditions, metav1.Condition{
+		Type:               string(gatewayv1.GatewayClassConditionStatusAccepted),
+		Status:             status,
+		ObservedGeneration: gatewayClass.Generation,
+		Reason:             string(reason),
+		Message:            message,
+	})
 }
 
-func (r *Reconciler) accept(ctx context.Context, gatewayClass *gatewayv1.GatewayClass) error {
-	r.logger.Info("updating status")
-	for i, condition := range gatewayClass.Status.Conditions {
-		if condition.Type != "Accepted" {
-			continue
-		}
-		r.logger.Info("Updating status")
-		gatewayClass.Status.Conditions[i] = metav1.Condition{
-			Type:               "Accepted",
-			Status:             "True",
-			LastTransitionTime: metav1.Time{Time: time.Now()},
-			Reason:             "Accepted",
-			Message:            "this is a test acceptance",
-		}
-		gatewayClass.ObjectMeta.Generation = gatewayClass.ObjectMeta.Generation + 1
+// updateStatus only writes the status when it differs from what was observed, which keeps
+// the status update from retriggering reconciliation of an unchanged GatewayClass
+func (r *Reconciler) updateStatus(
+	ctx context.Context,
+	gatewayClass *gatewayv1.GatewayClass,
+	observed *gatewayv1.GatewayClassStatus,
+) error {
+	if equality.Semantic.DeepEqual(observed, &gatewayClass.Status) {
+		return nil
 	}
-
+	r.logger.Info("updating status")
 	if err := r.Status().Update(ctx, gatewayClass); err != nil {
 		r.logger.Info(fmt.Sprintf("Failed to update condition status: %v\n", err))
 		return err
 	}
-
 	return nil
 }
 
@@ -0,0 +1,37 @@
+package gateway_class
+
+import (
+	"sort"
+
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	"sigs.k8s.io/gateway-api/pkg/features"
+)
+
+// implementedFeatures are the Gateway API features every Gateway of this controller implements. The features that
+// need the router of a Gateway, such as method and query parameter matching, request mirrors and backend request
+// header modifiers, are left out as the router is optional, see CloudflaredConfig spec.router
+var implementedFeatures = []features.FeatureName{
+	features.SupportGateway,
+	features.SupportHTTPRoute,
+	features.SupportHTTPRouteHostRewrite,
+	features.SupportHTTPRoutePathRedirect,
+	features.SupportHTTPRoutePathRewrite,
+	features.SupportHTTPRoutePortRedirect,
+	features.SupportHTTPRouteResponseHeaderModification,
+	features.SupportHTTPRouteSchemeRedirect,
+	features.SupportGRPCRoute,
+	features.SupportTLSRoute,
+}
+
+// supportedFeatures returns the implemented features in the form, and the ascending order by
+// name, that the GatewayClass status requires
+func supportedFeatures() []gatewayv1.SupportedFeature {
+	supported := make([]gatewayv1.SupportedFeature, 0, len(implementedFeatures))
+	for _, feature := range implementedFeatures {
+		supported = append(supported, gatewayv1.SupportedFeature{Name: gatewayv1.FeatureName(feature)})
+	}
+	sort.Slice(supported, func(i, j int) bool {
+		return supported[i].Name < supported[j].Name
+	})
+	return supported
+}
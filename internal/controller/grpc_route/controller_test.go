@@ -0,0 +1,16 @@
+package grpc_route
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+)
+
+var _ = Describe("GRPCRoute Controller", func() {
+	Context("When reconciling a resource", func() {
+
+		It("should successfully reconcile the resource", func() {
+
+			// TODO(user): Add more specific assertions depending on your controller's reconciliation logic.
+			// Example: If you expect a certain status condition after reconciliation, verify it here.
+		})
+	})
+})
@@ -2,19 +2,19 @@ package http_route
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"time"
 
-	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
-	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/k8s"
-	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller/routing"
 	"github.com/go-logr/logr"
-	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -22,11 +22,8 @@ import (
 
 // ReconciliationLoop represents the data used for a single reconciliation loop
 type ReconciliationLoop struct {
-	logger           logr.Logger
-	GatewayName      string
-	GatewayNamespace string
-	// tunnelSecret     string
-	// accountID        string
+	logger logr.Logger
+	status *routing.StatusSetter
 }
 
 // Reconciler reconciles a HTTPRoute object
@@ -34,119 +31,8 @@ type Reconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 	Loop   *ReconciliationLoop
-}
-
-func (r *Reconciler) isMine(ctx context.Context, httpRoute *gatewayv1.HTTPRoute) (bool, error) {
-	if httpRoute == nil {
-		return false, errors.New("nil HTTPRoute")
-	}
-	var gateway *gatewayv1.Gateway
-	if err := r.Get(
-		ctx,
-		client.ObjectKey{
-			Namespace: string(*httpRoute.Spec.ParentRefs[0].Namespace),
-			Name:      string(httpRoute.Spec.ParentRefs[0].Name),
-		},
-		gateway,
-	); err != nil {
-		return false, errors.Wrap(err, "failed to get gateway")
-	}
-	if gateway == nil {
-		return false, errors.New("returned nil gateway")
-	}
-
-	var gatewayClass *gatewayv1.GatewayClass
-	if err := r.Get(
-		ctx,
-		client.ObjectKey{Name: string(gateway.Spec.GatewayClassName)},
-		gatewayClass,
-	); err != nil {
-		return false, errors.Wrap(err, "failed to get gateway")
-	}
-	if gatewayClass == nil {
-		return false, errors.New("returned nil gatewayClass")
-	}
-	if gatewayClass.Spec.ControllerName == "" {
-		return false, errors.New("returned gatewayClass has no controllerName")
-	}
-	return gatewayClass.Spec.ControllerName == controller.Name, nil
-}
-
-func (r *Reconciler) getConfigMap(ctx context.Context, configMapName client.ObjectKey) (*corev1.ConfigMap, error) {
-	configMap := &corev1.ConfigMap{}
-	err := r.Client.Get(
-		ctx,
-		configMapName,
-		configMap,
-	)
-	if err != nil {
-		return &corev1.ConfigMap{}, errors.Wrap(err, "failed to get existing configmap")
-	}
-	return configMap, nil
-}
-
-func (r *Reconciler) upsertConfigMap(
-	ctx context.Context,
-	configMap *corev1.ConfigMap,
-	newConfig []cf.IngressConfig,
-) error {
-
-	existingConfigString := configMap.Data[k8s.ConfigYamlFileName]
-	var existingTunnelConfig cf.TunnelConfigFile
-	err := json.Unmarshal([]byte(existingConfigString), &existingTunnelConfig)
-	if err != nil {
-		return errors.Wrap(err, "failed to unmarshal existing configmap")
-	}
-
-	// check which routes need to be updated or added
-	var routesToAdd []cf.IngressConfig
-	for _, newRoute := range newConfig {
-		for _, existingRoute := range existingTunnelConfig.Ingress {
-			if newRoute.Hostname != existingRoute.Hostname {
-				continue
-			}
-			if newRoute.Service != existingRoute.Service {
-				// an existing hostname is pointing to a new backing service
-				existingRoute.Service = newRoute.Service
-				continue
-			}
-			// this is a totally new route that needs to be added to the config file
-			routesToAdd = append(routesToAdd, newRoute)
-		}
-	}
-
-	newTunnelConfigFile := existingTunnelConfig
-	newTunnelConfigFile.Ingress = append(existingTunnelConfig.Ingress, routesToAdd...)
-	cf.Sort(newTunnelConfigFile.Ingress)
-	newConfigBytes, err := json.Marshal(newTunnelConfigFile)
-	if err != nil {
-		return errors.Wrap(err, "failed to marshal new configmap")
-	}
-	newConfigString := string(newConfigBytes)
-
-	// if there's no change, we don't perform an update
-	if existingConfigString == newConfigString {
-		return nil
-	}
-
-	configMap.Data[k8s.ConfigYamlFileName] = newConfigString
-	return r.Update(ctx, configMap)
-}
-
-func (r *Reconciler) buildRoutingFragment(route *gatewayv1.HTTPRoute) []cf.IngressConfig {
-	ingressConfigs := make([]cf.IngressConfig, len(route.Spec.Hostnames)+1)
-	for i, hostname := range route.Spec.Hostnames {
-		ingressConfigs[i] = cf.IngressConfig{
-			Hostname: string(hostname),
-			Service: fmt.Sprintf(
-				"http://%s.%s.svc.cluster.local:%s",
-				string(route.Spec.Rules[0].BackendRefs[0].Name),
-				string(*route.Spec.Rules[0].BackendRefs[0].Namespace),
-				string(*route.Spec.Rules[0].BackendRefs[0].Port),
-			),
-		}
-	}
-	return ingressConfigs
+	// Recorder records the events of the HTTPRoutes, such as them going into maintenance
+	Recorder record.EventRecorder
 }
 
 // +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;patch;delete
@@ -155,10 +41,8 @@ func (r *Reconciler) buildRoutingFragment(route *gatewayv1.HTTPRoute) []cf.Ingre
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the HTTPRoute object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
+// The rules of every HTTPRoute are rendered into the cloudflared config by the Gateway
+// reconciler, this reconciler reports on the route how that rendering went.
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.19.0/pkg/reconcile
@@ -166,17 +50,19 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	defaultResult := ctrl.Result{Requeue: true, RequeueAfter: 60 * time.Second}
 	r.Loop = &ReconciliationLoop{
 		logger: log.FromContext(ctx),
+		status: routing.NewStatusSetter(r.Client),
 	}
-	r.Loop.logger.Info(fmt.Sprintf("Reconciling Gateway: %s", req.NamespacedName))
+	r.Loop.logger.Info(fmt.Sprintf("Reconciling HTTPRoute: %s", req.NamespacedName))
 
-	var httpRoute *gatewayv1.HTTPRoute
+	httpRoute := &gatewayv1.HTTPRoute{}
 	if err := r.Get(ctx, req.NamespacedName, httpRoute); err != nil {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
+	observedStatus := httpRoute.Status.DeepCopy()
 
-	isMine, err := r.isMine(ctx, httpRoute)
+	isMine, err := r.Loop.status.SetParentStatuses(ctx, routing.FromHTTPRoute(httpRoute), &httpRoute.Status.RouteStatus, httpRoute.Generation)
 	if err != nil {
-		r.Loop.logger.Error(err, "failed to check if the httpRoute is mine")
+		r.Loop.logger.Error(err, "failed to determine httpRoute status")
 		return defaultResult, err
 	}
 	if !isMine {
@@ -185,25 +71,28 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, nil
 	}
 
-	configMap, err := r.getConfigMap(ctx, req.NamespacedName)
-	if err != nil {
-		r.Loop.logger.Error(err, "failed to get configmap")
-		return defaultResult, err
+	if equality.Semantic.DeepEqual(observedStatus, &httpRoute.Status) {
+		return ctrl.Result{}, nil
 	}
-
-	if err := r.upsertConfigMap(ctx, configMap, r.buildRoutingFragment(httpRoute)); err != nil {
-		r.Loop.logger.Error(err, "failed to upsert configmap")
+	if err := r.Status().Update(ctx, httpRoute); err != nil {
+		r.Loop.logger.Error(err, "failed to update httpRoute status")
 		return defaultResult, err
 	}
+	routing.RecordMaintenance(r.Recorder, httpRoute, &observedStatus.RouteStatus, &httpRoute.Status.RouteStatus)
 
 	return ctrl.Result{}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		// Uncomment the following line adding a pointer to an instance of the controlled resource as an argument
+	enqueueRoutes := routing.EnqueueRoutes(mgr.GetClient(), routing.KindHTTPRoute)
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&gatewayv1.HTTPRoute{}).
-		Owns(&corev1.ConfigMap{}).
-		Complete(r)
+		Watches(&gatewayv1.Gateway{}, enqueueRoutes).
+		Watches(&corev1.Service{}, routing.EnqueueServiceRoutes(mgr.GetClient(), routing.KindHTTPRoute)).
+		Watches(&discoveryv1.EndpointSlice{}, routing.EnqueueEndpointSliceRoutes(mgr.GetClient(), routing.KindHTTPRoute))
+	if routing.Installed(mgr, &gatewayv1beta1.ReferenceGrant{}) {
+		builder = builder.Watches(&gatewayv1beta1.ReferenceGrant{}, routing.EnqueueReferenceGrantRoutes(mgr.GetClient(), routing.KindHTTPRoute))
+	}
+	return routing.WatchRoutes(mgr, builder, enqueueRoutes).Complete(r)
 }
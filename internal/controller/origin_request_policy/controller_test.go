@@ -0,0 +1,16 @@
+package origin_request_policy
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+)
+
+var _ = Describe("OriginRequestPolicy Controller", func() {
+	Context("When reconciling a resource", func() {
+
+		It("should successfully reconcile the resource", func() {
+
+			// TODO(user): Add more specific assertions depending on your controller's reconciliation logic.
+			// Example: If you expect a certain status condition after reconciliation, verify it here.
+		})
+	})
+})
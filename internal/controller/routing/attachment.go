@@ -0,0 +1,349 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+)
+
+const (
+	KindGateway   = gatewayv1.Kind("Gateway")
+	KindHTTPRoute = gatewayv1.Kind("HTTPRoute")
+	KindGRPCRoute = gatewayv1.Kind("GRPCRoute")
+	KindTCPRoute  = gatewayv1.Kind("TCPRoute")
+	KindTLSRoute  = gatewayv1.Kind("TLSRoute")
+)
+
+// Route is the kind-agnostic view of a Gateway API route that is needed to work out
+// which listeners of a Gateway it attaches to
+type Route struct {
+	Kind              gatewayv1.Kind
+	Namespace         string
+	Name              string
+	CreationTimestamp metav1.Time
+	ParentRefs        []gatewayv1.ParentReference
+	Hostnames         []gatewayv1.Hostname
+	Annotations       map[string]string
+	// Backends are the Services the backendRefs of the route point at
+	Backends []types.NamespacedName
+}
+
+func FromHTTPRoute(route *gatewayv1.HTTPRoute) Route {
+	return Route{
+		Kind:              KindHTTPRoute,
+		Namespace:         route.Namespace,
+		Name:              route.Name,
+		CreationTimestamp: route.CreationTimestamp,
+		Annotations:       route.Annotations,
+		ParentRefs:        route.Spec.ParentRefs,
+		Hostnames:         route.Spec.Hostnames,
+		Backends:          httpRouteBackends(route),
+	}
+}
+
+func FromGRPCRoute(route *gatewayv1.GRPCRoute) Route {
+	return Route{
+		Kind:              KindGRPCRoute,
+		Namespace:         route.Namespace,
+		Name:              route.Name,
+		CreationTimestamp: route.CreationTimestamp,
+		Annotations:       route.Annotations,
+		ParentRefs:        route.Spec.ParentRefs,
+		Hostnames:         route.Spec.Hostnames,
+		Backends:          grpcRouteBackends(route),
+	}
+}
+
+func FromTLSRoute(route *gatewayv1alpha2.TLSRoute) Route {
+	return Route{
+		Kind:              KindTLSRoute,
+		Namespace:         route.Namespace,
+		Name:              route.Name,
+		CreationTimestamp: route.CreationTimestamp,
+		Annotations:       route.Annotations,
+		ParentRefs:        route.Spec.ParentRefs,
+		Hostnames:         route.Spec.Hostnames,
+		Backends:          tlsRouteBackends(route),
+	}
+}
+
+// FromTCPRoute returns the view of a TCPRoute, which has no hostnames of its own and is
+// published on the hostnames of the listeners it attaches to
+func FromTCPRoute(route *gatewayv1alpha2.TCPRoute) Route {
+	return Route{
+		Kind:              KindTCPRoute,
+		Namespace:         route.Namespace,
+		Name:              route.Name,
+		CreationTimestamp: route.CreationTimestamp,
+		Annotations:       route.Annotations,
+		ParentRefs:        route.Spec.ParentRefs,
+		Backends:          tcpRouteBackends(route),
+	}
+}
+
+// FromObject returns the view of any route kind this controller serves
+func FromObject(obj client.Object) (Route, bool) {
+	switch route := obj.(type) {
+	case *gatewayv1.HTTPRoute:
+		return FromHTTPRoute(route), true
+	case *gatewayv1.GRPCRoute:
+		return FromGRPCRoute(route), true
+	case *gatewayv1alpha2.TCPRoute:
+		return FromTCPRoute(route), true
+	case *gatewayv1alpha2.TLSRoute:
+		return FromTLSRoute(route), true
+	default:
+		return Route{}, false
+	}
+}
+
+func (r Route) String() string {
+	return fmt.Sprintf("%s %s/%s", r.Kind, r.Namespace, r.Name)
+}
+
+// Attachment is the outcome of attaching a single parentRef of a route to a Gateway
+type Attachment struct {
+	// Listeners are the names of the listeners the route attached to
+	Listeners []gatewayv1.SectionName
+	// Reason explains why the route did not attach to any listener, it is empty when it did
+	Reason  gatewayv1.RouteConditionReason
+	Message string
+}
+
+func (a Attachment) Accepted() bool {
+	return len(a.Listeners) > 0
+}
+
+// SupportedKinds returns the route kinds this controller can serve on a listener
+func SupportedKinds(listener gatewayv1.Listener) []gatewayv1.RouteGroupKind {
+	group := gatewayv1.Group(gatewayv1.GroupName)
+	switch listener.Protocol {
+	case gatewayv1.HTTPProtocolType, gatewayv1.HTTPSProtocolType:
+		return []gatewayv1.RouteGroupKind{{Group: &group, Kind: KindHTTPRoute}, {Group: &group, Kind: KindGRPCRoute}}
+	case gatewayv1.TCPProtocolType:
+		return []gatewayv1.RouteGroupKind{{Group: &group, Kind: KindTCPRoute}}
+	case gatewayv1.TLSProtocolType:
+		if !IsPassthrough(listener) {
+			return nil
+		}
+		return []gatewayv1.RouteGroupKind{{Group: &group, Kind: KindTLSRoute}}
+	default:
+		return nil
+	}
+}
+
+// IsPassthrough reports whether a listener leaves TLS to the backend. Listeners default to Terminate
+func IsPassthrough(listener gatewayv1.Listener) bool {
+	return listener.TLS != nil && listener.TLS.Mode != nil && *listener.TLS.Mode == gatewayv1.TLSModePassthrough
+}
+
+// AllowedKinds returns the route kinds a listener accepts, which is the intersection of what
+// the listener asks for and what this controller supports for its protocol
+func AllowedKinds(listener gatewayv1.Listener) (allowed []gatewayv1.RouteGroupKind, invalid []gatewayv1.RouteGroupKind) {
+	supported := SupportedKinds(listener)
+	if listener.AllowedRoutes == nil || len(listener.AllowedRoutes.Kinds) == 0 {
+		return supported, nil
+	}
+	for _, requested := range listener.AllowedRoutes.Kinds {
+		if containsKind(supported, requested) {
+			allowed = append(allowed, requested)
+		} else {
+			invalid = append(invalid, requested)
+		}
+	}
+	return allowed, invalid
+}
+
+func containsKind(kinds []gatewayv1.RouteGroupKind, kind gatewayv1.RouteGroupKind) bool {
+	for _, k := range kinds {
+		if k.Kind == kind.Kind && groupOrDefault(k.Group) == groupOrDefault(kind.Group) {
+			return true
+		}
+	}
+	return false
+}
+
+func groupOrDefault(group *gatewayv1.Group) gatewayv1.Group {
+	if group == nil {
+		return gatewayv1.GroupName
+	}
+	return *group
+}
+
+// TargetsGateway reports whether a parentRef of a route in routeNamespace points at gateway
+func TargetsGateway(ref gatewayv1.ParentReference, routeNamespace string, gateway *gatewayv1.Gateway) bool {
+	if groupOrDefault(ref.Group) != gatewayv1.GroupName {
+		return false
+	}
+	if ref.Kind != nil && *ref.Kind != KindGateway {
+		return false
+	}
+	namespace := routeNamespace
+	if ref.Namespace != nil {
+		namespace = string(*ref.Namespace)
+	}
+	return namespace == gateway.Namespace && string(ref.Name) == gateway.Name
+}
+
+// Attach works out which listeners of gateway the route attaches to through ref,
+// following the attachment rules of the Gateway API
+func Attach(
+	ctx context.Context,
+	c client.Reader,
+	gateway *gatewayv1.Gateway,
+	route Route,
+	ref gatewayv1.ParentReference,
+) (Attachment, error) {
+	attachment := Attachment{}
+	matchedParent := false
+	hostnameMismatch := false
+	for _, listener := range gateway.Spec.Listeners {
+		if ref.SectionName != nil && *ref.SectionName != listener.Name {
+			continue
+		}
+		if ref.Port != nil && *ref.Port != listener.Port {
+			continue
+		}
+		matchedParent = true
+
+		allowed, _ := AllowedKinds(listener)
+		if !containsKind(allowed, gatewayv1.RouteGroupKind{Kind: route.Kind}) {
+			continue
+		}
+		namespaceAllowed, err := namespaceAllowed(ctx, c, gateway, listener, route.Namespace)
+		if err != nil {
+			return Attachment{}, err
+		}
+		if !namespaceAllowed {
+			continue
+		}
+		if !HostnamesIntersect(listener.Hostname, route.Hostnames) {
+			hostnameMismatch = true
+			continue
+		}
+		attachment.Listeners = append(attachment.Listeners, listener.Name)
+	}
+
+	switch {
+	case attachment.Accepted():
+	case !matchedParent:
+		attachment.Reason = gatewayv1.RouteReasonNoMatchingParent
+		attachment.Message = fmt.Sprintf("no listener of Gateway %s/%s matches the parentRef", gateway.Namespace, gateway.Name)
+	case hostnameMismatch:
+		attachment.Reason = gatewayv1.RouteReasonNoMatchingListenerHostname
+		attachment.Message = "none of the route hostnames match a listener hostname"
+	default:
+		attachment.Reason = gatewayv1.RouteReasonNotAllowedByListeners
+		attachment.Message = fmt.Sprintf("no listener of Gateway %s/%s allows %s from namespace %s", gateway.Namespace, gateway.Name, route.Kind, route.Namespace)
+	}
+	return attachment, nil
+}
+
+func namespaceAllowed(
+	ctx context.Context,
+	c client.Reader,
+	gateway *gatewayv1.Gateway,
+	listener gatewayv1.Listener,
+	routeNamespace string,
+) (bool, error) {
+	from := gatewayv1.NamespacesFromSame
+	var selector *metav1.LabelSelector
+	if listener.AllowedRoutes != nil && listener.AllowedRoutes.Namespaces != nil {
+		if listener.AllowedRoutes.Namespaces.From != nil {
+			from = *listener.AllowedRoutes.Namespaces.From
+		}
+		selector = listener.AllowedRoutes.Namespaces.Selector
+	}
+
+	switch from {
+	case gatewayv1.NamespacesFromAll:
+		return true, nil
+	case gatewayv1.NamespacesFromSame:
+		return routeNamespace == gateway.Namespace, nil
+	case gatewayv1.NamespacesFromSelector:
+		if selector == nil {
+			return false, nil
+		}
+		labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+		if err != nil {
+			return false, errors.Wrap(err, "invalid namespace selector")
+		}
+		namespace := &corev1.Namespace{}
+		if err := c.Get(ctx, client.ObjectKey{Name: routeNamespace}, namespace); err != nil {
+			return false, errors.Wrapf(err, "failed to get namespace %s", routeNamespace)
+		}
+		return labelSelector.Matches(labels.Set(namespace.Labels)), nil
+	default:
+		return false, nil
+	}
+}
+
+// HostnamesIntersect reports whether any of the route hostnames can be served by a listener
+// with the given hostname. A listener without a hostname, or a route without hostnames, matches everything
+func HostnamesIntersect(listenerHostname *gatewayv1.Hostname, routeHostnames []gatewayv1.Hostname) bool {
+	if listenerHostname == nil || *listenerHostname == "" || len(routeHostnames) == 0 {
+		return true
+	}
+	for _, hostname := range routeHostnames {
+		if HostnameMatches(string(*listenerHostname), string(hostname)) {
+			return true
+		}
+	}
+	return false
+}
+
+// EffectiveHostnames returns the hostnames a route is served on through a listener, which is
+// the more specific of each pair of overlapping listener and route hostnames
+func EffectiveHostnames(listenerHostname *gatewayv1.Hostname, routeHostnames []gatewayv1.Hostname) []string {
+	if listenerHostname == nil || *listenerHostname == "" {
+		hostnames := make([]string, 0, len(routeHostnames))
+		for _, hostname := range routeHostnames {
+			hostnames = append(hostnames, string(hostname))
+		}
+		return hostnames
+	}
+	if len(routeHostnames) == 0 {
+		return []string{string(*listenerHostname)}
+	}
+	var hostnames []string
+	for _, hostname := range routeHostnames {
+		if !HostnameMatches(string(*listenerHostname), string(hostname)) {
+			continue
+		}
+		if strings.HasPrefix(string(hostname), "*.") && len(*listenerHostname) > len(hostname) {
+			hostnames = append(hostnames, string(*listenerHostname))
+		} else {
+			hostnames = append(hostnames, string(hostname))
+		}
+	}
+	return hostnames
+}
+
+// HostnameMatches reports whether two hostnames, either of which may carry a leading
+// wildcard label, describe an overlapping set of hosts
+func HostnameMatches(a string, b string) bool {
+	if a == b {
+		return true
+	}
+	aWildcard := strings.HasPrefix(a, "*.")
+	bWildcard := strings.HasPrefix(b, "*.")
+	switch {
+	case aWildcard && bWildcard:
+		return strings.HasSuffix(a, b[1:]) || strings.HasSuffix(b, a[1:])
+	case aWildcard:
+		return strings.HasSuffix(b, a[1:])
+	case bWildcard:
+		return strings.HasSuffix(a, b[1:])
+	default:
+		return false
+	}
+}
@@ -0,0 +1,78 @@
+package routing
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestHostnameMatches(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want bool
+	}{
+		{name: "Identical hostnames", a: "a.example.com", b: "a.example.com", want: true},
+		{name: "Different hostnames", a: "a.example.com", b: "b.example.com", want: false},
+		{name: "Wildcard matches subdomain", a: "*.example.com", b: "a.example.com", want: true},
+		{name: "Wildcard matches nested subdomain", a: "a.b.example.com", b: "*.example.com", want: true},
+		{name: "Wildcard does not match apex", a: "*.example.com", b: "example.com", want: false},
+		{name: "Nested wildcards overlap", a: "*.example.com", b: "*.a.example.com", want: true},
+		{name: "Disjoint wildcards", a: "*.example.com", b: "*.example.org", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HostnameMatches(tt.a, tt.b); got != tt.want {
+				t.Errorf("HostnameMatches(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTargetsGateway(t *testing.T) {
+	gateway := &gatewayv1.Gateway{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+	otherNamespace := gatewayv1.Namespace("other")
+	service := gatewayv1.Kind("Service")
+	tests := []struct {
+		name           string
+		ref            gatewayv1.ParentReference
+		routeNamespace string
+		want           bool
+	}{
+		{
+			name:           "Defaults to the route namespace",
+			ref:            gatewayv1.ParentReference{Name: "test"},
+			routeNamespace: "default",
+			want:           true,
+		},
+		{
+			name:           "Route in another namespace without an explicit namespace",
+			ref:            gatewayv1.ParentReference{Name: "test"},
+			routeNamespace: "other",
+			want:           false,
+		},
+		{
+			name:           "Explicit namespace that differs from the gateway",
+			ref:            gatewayv1.ParentReference{Name: "test", Namespace: &otherNamespace},
+			routeNamespace: "default",
+			want:           false,
+		},
+		{
+			name:           "Parent of a different kind",
+			ref:            gatewayv1.ParentReference{Name: "test", Kind: &service},
+			routeNamespace: "default",
+			want:           false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TargetsGateway(tt.ref, tt.routeNamespace, gateway); got != tt.want {
+				t.Errorf("TargetsGateway() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package routing
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// BackendSplit is how the requests of a HTTPRoute rule with several backendRefs are split between them
+type BackendSplit struct {
+	RuleIndex int
+	Backends  []BackendShare
+	// Applied is false when the weights are not honoured, as splitting requests needs the router of the Gateway
+	Applied bool
+}
+
+// BackendShare is the percentage of the requests of a rule that a backendRef receives
+type BackendShare struct {
+	Name    string
+	Percent float64
+}
+
+func (s BackendSplit) String() string {
+	shares := make([]string, 0, len(s.Backends))
+	for _, backend := range s.Backends {
+		shares = append(shares, strconv.FormatFloat(backend.Percent, 'f', -1, 64)+"% "+backend.Name)
+	}
+	message := fmt.Sprintf("rules[%d]: %s", s.RuleIndex, strings.Join(shares, ", "))
+	if !s.Applied {
+		message += " (splitting requests needs the router of the Gateway)"
+	}
+	return message
+}
+
+// weightedBackendRefs returns the backendRefs of a rule that receive requests, zero weights exclude a backendRef
+func weightedBackendRefs(backendRefs []gatewayv1.HTTPBackendRef) []gatewayv1.HTTPBackendRef {
+	var weighted []gatewayv1.HTTPBackendRef
+	for _, ref := range backendRefs {
+		if ptr.Deref(ref.Weight, defaultBackendWeight) > 0 {
+			weighted = append(weighted, ref)
+		}
+	}
+	return weighted
+}
+
+// backendSplit returns the effective split of the requests of a rule between its backendRefs. cloudflared
+// sends everything to the first weighted backendRef, unless the router of the Gateway serves the rule
+func backendSplit(routeNamespace string, ruleIndex int, backendRefs []gatewayv1.HTTPBackendRef, routed bool) BackendSplit {
+	split := BackendSplit{RuleIndex: ruleIndex, Applied: routed}
+	weighted := weightedBackendRefs(backendRefs)
+	var total int32
+	for _, ref := range weighted {
+		total += ptr.Deref(ref.Weight, defaultBackendWeight)
+	}
+	if len(weighted) <= 1 {
+		// a single weighted backendRef needs no router
+		split.Applied = true
+	}
+	first := true
+	for _, ref := range backendRefs {
+		share := BackendShare{Name: backendName(routeNamespace, ref.BackendObjectReference)}
+		if weight := ptr.Deref(ref.Weight, defaultBackendWeight); weight > 0 {
+			switch {
+			case split.Applied:
+				share.Percent = math.Round(float64(weight)*10000/float64(total)) / 100
+			case first:
+				share.Percent = 100
+			}
+			first = false
+		}
+		split.Backends = append(split.Backends, share)
+	}
+	return split
+}
+
+// backendName names a backendRef the way it is written in its route
+func backendName(routeNamespace string, ref gatewayv1.BackendObjectReference) string {
+	name := string(ref.Name)
+	if ref.Namespace != nil && string(*ref.Namespace) != routeNamespace {
+		name = string(*ref.Namespace) + "/" + name
+	}
+	if ref.Port != nil {
+		name += ":" + strconv.Itoa(int(*ref.Port))
+	}
+	return name
+}
@@ -0,0 +1,64 @@
+package routing
+
+import (
+	"testing"
+
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestBackendSplit(t *testing.T) {
+	backend := func(name string, weight *int32) gatewayv1.HTTPBackendRef {
+		return gatewayv1.HTTPBackendRef{BackendRef: gatewayv1.BackendRef{
+			BackendObjectReference: gatewayv1.BackendObjectReference{Name: gatewayv1.ObjectName(name), Port: ptr.To(gatewayv1.PortNumber(80))},
+			Weight:                 weight,
+		}}
+	}
+
+	tests := []struct {
+		name        string
+		backendRefs []gatewayv1.HTTPBackendRef
+		routed      bool
+		want        string
+		wantApplied bool
+	}{
+		{
+			name:        "Weights through the router",
+			backendRefs: []gatewayv1.HTTPBackendRef{backend("stable", ptr.To(int32(90))), backend("canary", ptr.To(int32(10)))},
+			routed:      true,
+			want:        "rules[0]: 90% stable:80, 10% canary:80",
+			wantApplied: true,
+		},
+		{
+			name:        "Uneven weights are rounded",
+			backendRefs: []gatewayv1.HTTPBackendRef{backend("a", nil), backend("b", nil), backend("c", nil)},
+			routed:      true,
+			want:        "rules[0]: 33.33% a:80, 33.33% b:80, 33.33% c:80",
+			wantApplied: true,
+		},
+		{
+			name:        "Blue/green without the router",
+			backendRefs: []gatewayv1.HTTPBackendRef{backend("blue", ptr.To(int32(0))), backend("green", ptr.To(int32(100)))},
+			want:        "rules[0]: 0% blue:80, 100% green:80",
+			wantApplied: true,
+		},
+		{
+			name:        "Weights without the router",
+			backendRefs: []gatewayv1.HTTPBackendRef{backend("stable", ptr.To(int32(90))), backend("canary", ptr.To(int32(10)))},
+			want:        "rules[0]: 100% stable:80, 0% canary:80 (splitting requests needs the router of the Gateway)",
+			wantApplied: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			split := backendSplit("default", 0, tt.backendRefs, tt.routed)
+			if got := split.String(); got != tt.want {
+				t.Errorf("backendSplit() = %q, want %q", got, tt.want)
+			}
+			if split.Applied != tt.wantApplied {
+				t.Errorf("backendSplit() applied = %v, want %v", split.Applied, tt.wantApplied)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/k8s"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+	gatewayv1alpha3 "sigs.k8s.io/gateway-api/apis/v1alpha3"
+)
+
+const (
+	// CACertificateKey is the key of a CA certificate ConfigMap that holds the PEM bundle
+	CACertificateKey = "ca.crt"
+)
+
+// backendTLS is the origin TLS configuration a BackendTLSPolicy applies to the Service it targets
+type backendTLS struct {
+	policy types.NamespacedName
+	status PolicyStatus
+	// caBundle is the PEM bundle of the caCertificateRefs, empty for the well-known system CAs
+	caBundle string
+	hostname string
+}
+
+// CABundleKey returns the key of the gateway ConfigMap the CA bundle of a policy is copied to
+func CABundleKey(policy types.NamespacedName) string {
+	return fmt.Sprintf("ca-%s-%s.crt", policy.Namespace, policy.Name)
+}
+
+// originRequest returns the cloudflared origin settings of the policy
+func (t *backendTLS) originRequest() *cf.OriginRequestConfig {
+	originRequest := &cf.OriginRequestConfig{OriginServerName: t.hostname}
+	if t.caBundle != "" {
+		originRequest.CAPool = path.Join(k8s.DeploymentConfigDir, CABundleKey(t.policy))
+	}
+	return originRequest
+}
+
+func listBackendTLSPolicies(ctx context.Context, c client.Reader) ([]gatewayv1alpha3.BackendTLSPolicy, error) {
+	policies := &gatewayv1alpha3.BackendTLSPolicyList{}
+	if err := c.List(ctx, policies); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to list backendTLSPolicies")
+	}
+	return policies.Items, nil
+}
+
+// backendTLSIndex holds the BackendTLSPolicies of the cluster by the Service they target
+type backendTLSIndex struct {
+	byService map[types.NamespacedName]*backendTLS
+	// conflicted are the policies that lost a Service to an older policy
+	conflicted map[types.NamespacedName]map[types.NamespacedName]PolicyStatus
+}
+
+// loadBackendTLS returns the TLS settings of every Service targeted by a BackendTLSPolicy.
+// When several policies target the same Service the oldest one wins, as with routes
+func loadBackendTLS(ctx context.Context, c client.Reader) (*backendTLSIndex, error) {
+	policies, err := listBackendTLSPolicies(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(policies, func(i, j int) bool {
+		return objectLess(&policies[i], &policies[j])
+	})
+
+	index := &backendTLSIndex{
+		byService:  map[types.NamespacedName]*backendTLS{},
+		conflicted: map[types.NamespacedName]map[types.NamespacedName]PolicyStatus{},
+	}
+	for i := range policies {
+		policy := &policies[i]
+		tls, err := evaluateBackendTLSPolicy(ctx, c, policy)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range policy.Spec.TargetRefs {
+			if ref.Group != "" || ref.Kind != KindService {
+				continue
+			}
+			service := types.NamespacedName{Namespace: policy.Namespace, Name: string(ref.Name)}
+			winner, ok := index.byService[service]
+			if !ok {
+				index.byService[service] = tls
+				continue
+			}
+			if winner.policy == tls.policy {
+				continue
+			}
+			if index.conflicted[service] == nil {
+				index.conflicted[service] = map[types.NamespacedName]PolicyStatus{}
+			}
+			index.conflicted[service][tls.policy] = PolicyStatus{
+				Reason:  gatewayv1alpha2.PolicyReasonConflicted,
+				Message: fmt.Sprintf("service %s is already targeted by BackendTLSPolicy %s", service, winner.policy),
+			}
+		}
+	}
+	return index, nil
+}
+
+// evaluateBackendTLSPolicy validates a policy against what cloudflared can express and resolves its CA bundle
+func evaluateBackendTLSPolicy(ctx context.Context, c client.Reader, policy *gatewayv1alpha3.BackendTLSPolicy) (*backendTLS, error) {
+	tls := &backendTLS{
+		policy:   types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name},
+		hostname: string(policy.Spec.Validation.Hostname),
+		status:   PolicyStatus{Reason: gatewayv1alpha2.PolicyReasonAccepted, Message: "policy is applied to the backends of this Gateway"},
+	}
+	invalid := func(message string) (*backendTLS, error) {
+		tls.status = PolicyStatus{Reason: gatewayv1alpha2.PolicyReasonInvalid, Message: message}
+		return tls, nil
+	}
+
+	for _, ref := range policy.Spec.TargetRefs {
+		if ref.SectionName != nil {
+			return invalid("targeting a port of a Service with sectionName is not supported")
+		}
+	}
+	if len(policy.Spec.Validation.SubjectAltNames) > 0 {
+		return invalid("subjectAltNames are not supported, cloudflared verifies the origin certificate against the hostname")
+	}
+	validation := policy.Spec.Validation
+	if validation.WellKnownCACertificates != nil {
+		if *validation.WellKnownCACertificates != gatewayv1alpha3.WellKnownCACertificatesSystem {
+			return invalid(fmt.Sprintf("wellKnownCACertificates %s is not supported", *validation.WellKnownCACertificates))
+		}
+		return tls, nil
+	}
+
+	bundles := make([]string, 0, len(validation.CACertificateRefs))
+	for _, ref := range validation.CACertificateRefs {
+		if ref.Group != "" || ref.Kind != "ConfigMap" {
+			return invalid(fmt.Sprintf("caCertificateRef %s %s is not a ConfigMap", ref.Kind, ref.Name))
+		}
+		configMap := &corev1.ConfigMap{}
+		if err := c.Get(ctx, client.ObjectKey{Namespace: policy.Namespace, Name: string(ref.Name)}, configMap); err != nil {
+			if apierrors.IsNotFound(err) {
+				return invalid(fmt.Sprintf("caCertificateRef ConfigMap %s does not exist", ref.Name))
+			}
+			return nil, errors.Wrap(err, "failed to get CA certificate configmap")
+		}
+		bundle, ok := configMap.Data[CACertificateKey]
+		if !ok || strings.TrimSpace(bundle) == "" {
+			return invalid(fmt.Sprintf("caCertificateRef ConfigMap %s has no %s", ref.Name, CACertificateKey))
+		}
+		bundles = append(bundles, strings.TrimSpace(bundle)+"\n")
+	}
+	tls.caBundle = strings.Join(bundles, "")
+	return tls, nil
+}
+
+// isService reports whether a backendRef points at a core Service
+func isService(ref gatewayv1.BackendObjectReference) bool {
+	return (ref.Group == nil || *ref.Group == "") && (ref.Kind == nil || *ref.Kind == "Service")
+}
+
+// serviceKey returns the Service a backendRef points at, and nil for backends of other kinds
+func serviceKey(routeNamespace string, ref gatewayv1.BackendObjectReference) *types.NamespacedName {
+	if !isService(ref) {
+		return nil
+	}
+	namespace := routeNamespace
+	if ref.Namespace != nil {
+		namespace = string(*ref.Namespace)
+	}
+	return &types.NamespacedName{Namespace: namespace, Name: string(ref.Name)}
+}
+
+// referencingPolicies returns the BackendTLSPolicies that use configMap as a CA certificate
+func referencingPolicies(ctx context.Context, c client.Reader, configMap client.Object) ([]gatewayv1alpha3.BackendTLSPolicy, error) {
+	policies := &gatewayv1alpha3.BackendTLSPolicyList{}
+	if err := c.List(ctx, policies, client.InNamespace(configMap.GetNamespace())); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to list backendTLSPolicies")
+	}
+	var referencing []gatewayv1alpha3.BackendTLSPolicy
+	for _, policy := range policies.Items {
+		for _, ref := range policy.Spec.Validation.CACertificateRefs {
+			if ref.Group == "" && ref.Kind == "ConfigMap" && string(ref.Name) == configMap.GetName() {
+				referencing = append(referencing, policy)
+				break
+			}
+		}
+	}
+	return referencing, nil
+}
+
+// EnqueueManagedGateways returns a handler that requeues every Gateway of this controller, for
+// objects such as policies that can change the backends of any of them
+func EnqueueManagedGateways(c client.Reader) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, _ client.Object) []reconcile.Request {
+		return managedGatewayRequests(ctx, c)
+	})
+}
+
+// EnqueueCACertificateGateways returns a handler that requeues every Gateway of this controller
+// when a ConfigMap that is used as the CA certificate of a BackendTLSPolicy changes
+func EnqueueCACertificateGateways(c client.Reader) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		policies, err := referencingPolicies(ctx, c, obj)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "failed to find policies referencing configmap")
+			return nil
+		}
+		if len(policies) == 0 {
+			return nil
+		}
+		return managedGatewayRequests(ctx, c)
+	})
+}
+
+func managedGatewayRequests(ctx context.Context, c client.Reader) []reconcile.Request {
+	gateways, err := ManagedGateways(ctx, c)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to list gateways")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(gateways))
+	for _, gateway := range gateways {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&gateway)})
+	}
+	return requests
+}
+
+// EnqueueCACertificatePolicies returns a handler that requeues the BackendTLSPolicies using a changed ConfigMap as CA certificate
+func EnqueueCACertificatePolicies(c client.Reader) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		policies, err := referencingPolicies(ctx, c, obj)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "failed to find policies referencing configmap")
+			return nil
+		}
+		requests := make([]reconcile.Request, 0, len(policies))
+		for _, policy := range policies {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&policy)})
+		}
+		return requests
+	})
+}
@@ -0,0 +1,108 @@
+package routing
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+	gatewayv1alpha3 "sigs.k8s.io/gateway-api/apis/v1alpha3"
+)
+
+// configMapReader serves ConfigMaps from memory
+type configMapReader map[client.ObjectKey]*corev1.ConfigMap
+
+func (r configMapReader) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	configMap, ok := r[key]
+	if !ok {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, key.Name)
+	}
+	configMap.DeepCopyInto(obj.(*corev1.ConfigMap))
+	return nil
+}
+
+func (r configMapReader) List(context.Context, client.ObjectList, ...client.ListOption) error {
+	return nil
+}
+
+func TestEvaluateBackendTLSPolicy(t *testing.T) {
+	reader := configMapReader{
+		{Namespace: "default", Name: "ca"}:    {Data: map[string]string{CACertificateKey: "PEM"}},
+		{Namespace: "default", Name: "empty"}: {Data: map[string]string{}},
+	}
+	policy := func(validation gatewayv1alpha3.BackendTLSPolicyValidation) *gatewayv1alpha3.BackendTLSPolicy {
+		validation.Hostname = "backend.example.com"
+		return &gatewayv1alpha3.BackendTLSPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "policy"},
+			Spec:       gatewayv1alpha3.BackendTLSPolicySpec{Validation: validation},
+		}
+	}
+	caRef := func(name string) []gatewayv1.LocalObjectReference {
+		return []gatewayv1.LocalObjectReference{{Kind: "ConfigMap", Name: gatewayv1.ObjectName(name)}}
+	}
+
+	tests := []struct {
+		name         string
+		policy       *gatewayv1alpha3.BackendTLSPolicy
+		wantReason   gatewayv1alpha2.PolicyConditionReason
+		wantCABundle string
+	}{
+		{
+			name:       "System CAs",
+			policy:     policy(gatewayv1alpha3.BackendTLSPolicyValidation{WellKnownCACertificates: ptr.To(gatewayv1alpha3.WellKnownCACertificatesSystem)}),
+			wantReason: gatewayv1alpha2.PolicyReasonAccepted,
+		},
+		{
+			name:         "CA certificate ConfigMap",
+			policy:       policy(gatewayv1alpha3.BackendTLSPolicyValidation{CACertificateRefs: caRef("ca")}),
+			wantReason:   gatewayv1alpha2.PolicyReasonAccepted,
+			wantCABundle: "PEM\n",
+		},
+		{
+			name:       "Missing CA certificate ConfigMap",
+			policy:     policy(gatewayv1alpha3.BackendTLSPolicyValidation{CACertificateRefs: caRef("missing")}),
+			wantReason: gatewayv1alpha2.PolicyReasonInvalid,
+		},
+		{
+			name:       "CA certificate ConfigMap without ca.crt",
+			policy:     policy(gatewayv1alpha3.BackendTLSPolicyValidation{CACertificateRefs: caRef("empty")}),
+			wantReason: gatewayv1alpha2.PolicyReasonInvalid,
+		},
+		{
+			name: "Subject alt names",
+			policy: policy(gatewayv1alpha3.BackendTLSPolicyValidation{
+				WellKnownCACertificates: ptr.To(gatewayv1alpha3.WellKnownCACertificatesSystem),
+				SubjectAltNames:         []gatewayv1alpha3.SubjectAltName{{Type: gatewayv1alpha3.HostnameSubjectAltNameType, Hostname: "other.example.com"}},
+			}),
+			wantReason: gatewayv1alpha2.PolicyReasonInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tls, err := evaluateBackendTLSPolicy(context.Background(), reader, tt.policy)
+			if err != nil {
+				t.Fatalf("evaluateBackendTLSPolicy() error = %v", err)
+			}
+			if tls.status.Reason != tt.wantReason {
+				t.Errorf("evaluateBackendTLSPolicy() reason = %s, want %s (%s)", tls.status.Reason, tt.wantReason, tls.status.Message)
+			}
+			if tls.caBundle != tt.wantCABundle {
+				t.Errorf("evaluateBackendTLSPolicy() caBundle = %q, want %q", tls.caBundle, tt.wantCABundle)
+			}
+			originRequest := tls.originRequest()
+			if originRequest.OriginServerName != "backend.example.com" {
+				t.Errorf("originServerName = %s, want backend.example.com", originRequest.OriginServerName)
+			}
+			if wantCAPool := tt.wantCABundle != ""; (originRequest.CAPool != "") != wantCAPool {
+				t.Errorf("caPool = %q, want one: %v", originRequest.CAPool, wantCAPool)
+			}
+		})
+	}
+}
@@ -0,0 +1,364 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	cloudflarev1alpha1 "github.com/cyclingwithelephants/cloudflare-gateway-controller/api/v1alpha1"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/k8s"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+)
+
+const (
+	// AppProtocolH2C is the appProtocol of Service ports that speak HTTP/2 without TLS
+	AppProtocolH2C = "kubernetes.io/h2c"
+	// AppProtocolWSS is the appProtocol of Service ports that speak WebSocket over TLS
+	AppProtocolWSS = "kubernetes.io/wss"
+
+	// GroupMultiCluster is the API group of the ServiceImports of the Multi-Cluster Services API
+	GroupMultiCluster = "multicluster.x-k8s.io"
+	// KindServiceImport is the kind of the Services imported from the other clusters of a clusterset
+	KindServiceImport = "ServiceImport"
+	// ClusterSetDomain is the DNS domain ServiceImports resolve under as <name>.<namespace>.svc.<domain>
+	ClusterSetDomain = "clusterset.local"
+	// KindStaticResponse is the kind of the backendRefs of HTTPRoutes that answer every request with a fixed
+	// status code, which the backendRef is named after. It is not backed by any object
+	KindStaticResponse = "StaticResponse"
+	// StaticResponsePrefix prefixes the status code of the cloudflared services answering with a fixed status
+	StaticResponsePrefix = "http_status:"
+)
+
+// UnresolvedRef is a backendRef of a route that does not point at a backend the Gateway can reach
+type UnresolvedRef struct {
+	Reason  gatewayv1.RouteConditionReason
+	Message string
+}
+
+// loadServiceImports returns the keys of every ServiceImport of the cluster, none when the Multi-Cluster Services
+// API is not installed
+func loadServiceImports(ctx context.Context, c client.Reader) (map[types.NamespacedName]bool, error) {
+	serviceImports := &metav1.PartialObjectMetadataList{}
+	serviceImports.SetGroupVersionKind(schema.GroupVersionKind{Group: GroupMultiCluster, Version: "v1alpha1", Kind: KindServiceImport + "List"})
+	if err := c.List(ctx, serviceImports); err != nil {
+		if meta.IsNoMatchError(err) {
+			return map[types.NamespacedName]bool{}, nil
+		}
+		return nil, errors.Wrap(err, "failed to list serviceImports")
+	}
+	keys := make(map[types.NamespacedName]bool, len(serviceImports.Items))
+	for i := range serviceImports.Items {
+		keys[client.ObjectKeyFromObject(&serviceImports.Items[i])] = true
+	}
+	return keys, nil
+}
+
+// loadServices returns every Service of the cluster by its key
+func loadServices(ctx context.Context, c client.Reader) (map[types.NamespacedName]*corev1.Service, error) {
+	services := &corev1.ServiceList{}
+	if err := c.List(ctx, services); err != nil {
+		return nil, errors.Wrap(err, "failed to list services")
+	}
+	byKey := make(map[types.NamespacedName]*corev1.Service, len(services.Items))
+	for i := range services.Items {
+		byKey[client.ObjectKeyFromObject(&services.Items[i])] = &services.Items[i]
+	}
+	return byKey, nil
+}
+
+// backendURL returns the cloudflared service of the backend of ref: the URL of a Service, an ExternalName Service or
+// a ServiceImport, or a fixed status for StaticResponse backends of HTTPRoutes. scheme is the scheme of the route
+// kind, for http the appProtocol of the Service port picks between http and https, which https and
+// kubernetes.io/wss ports are reached with. It returns false, recording why on the route, when ref does not resolve
+// or points into another namespace without a ReferenceGrant allowing it
+func (b *Builder) backendURL(route Route, scheme string, ref gatewayv1.BackendObjectReference) (string, bool) {
+	if !b.referencePermitted(route, ref) {
+		return "", false
+	}
+	namespace := route.Namespace
+	if ref.Namespace != nil {
+		namespace = string(*ref.Namespace)
+	}
+	key := types.NamespacedName{Namespace: namespace, Name: string(ref.Name)}
+	group := ptr.Deref(ref.Group, "")
+	kind := ptr.Deref(ref.Kind, KindService)
+	switch {
+	case group == "" && kind == KindService:
+		return b.serviceURL(route, scheme, key, ref.Port)
+	case group == GroupMultiCluster && kind == KindServiceImport:
+		return b.serviceImportURL(route, scheme, key, ref.Port)
+	case group == gatewayv1.Group(cloudflarev1alpha1.GroupVersion.Group) && kind == KindStaticResponse && route.Kind == KindHTTPRoute:
+		return b.staticResponse(route, ref.Name)
+	default:
+		b.unresolvedRef(route, gatewayv1.RouteReasonInvalidKind, fmt.Sprintf(
+			"backendRefs of kind %s are not supported by %ss, only Services, ServiceImports and StaticResponses of HTTPRoutes are", kindName(group, kind), route.Kind,
+		))
+		return "", false
+	}
+}
+
+// serviceURL returns the URL of a port of a Service, which for ExternalName Services is a port of their external name
+func (b *Builder) serviceURL(route Route, scheme string, service types.NamespacedName, port *gatewayv1.PortNumber) (string, bool) {
+	found, ok := b.services[service]
+	if !ok {
+		b.unresolvedRef(route, gatewayv1.RouteReasonBackendNotFound, fmt.Sprintf("service %s does not exist", service))
+		return "", false
+	}
+	if port == nil {
+		b.unresolvedRef(route, gatewayv1.RouteReasonBackendNotFound, fmt.Sprintf("backendRefs to service %s need a port", service))
+		return "", false
+	}
+	hostname := k8s.ServiceHostname(service.Name, service.Namespace)
+	if found.Spec.Type == corev1.ServiceTypeExternalName {
+		hostname = found.Spec.ExternalName
+	}
+	servicePort := findServicePort(found, int32(*port))
+	if servicePort == nil && (found.Spec.Type != corev1.ServiceTypeExternalName || len(found.Spec.Ports) > 0) {
+		// ExternalName Services don't need to list their ports, the external name is reached on any of them
+		b.unresolvedRef(route, gatewayv1.RouteReasonBackendNotFound, fmt.Sprintf("service %s has no port %d", service, *port))
+		return "", false
+	}
+	if servicePort != nil {
+		scheme = b.portScheme(route, scheme, fmt.Sprintf("port %d of service %s", *port, service), portProtocol(*servicePort))
+	}
+	return backendURL(scheme, hostname, port), true
+}
+
+// serviceImportURL returns the URL of a port of a ServiceImport, which is reached through the clusterset DNS domain
+func (b *Builder) serviceImportURL(route Route, scheme string, serviceImport types.NamespacedName, port *gatewayv1.PortNumber) (string, bool) {
+	if !b.serviceImports[serviceImport] {
+		b.unresolvedRef(route, gatewayv1.RouteReasonBackendNotFound, fmt.Sprintf("serviceImport %s does not exist", serviceImport))
+		return "", false
+	}
+	if port == nil {
+		b.unresolvedRef(route, gatewayv1.RouteReasonBackendNotFound, fmt.Sprintf("backendRefs to serviceImport %s need a port", serviceImport))
+		return "", false
+	}
+	return backendURL(scheme, fmt.Sprintf("%s.%s.svc.%s", serviceImport.Name, serviceImport.Namespace, ClusterSetDomain), port), true
+}
+
+// staticResponse returns the cloudflared service answering with the status code a StaticResponse backend is named after
+func (b *Builder) staticResponse(route Route, name gatewayv1.ObjectName) (string, bool) {
+	code, err := strconv.Atoi(string(name))
+	if err != nil || code < 100 || code > 599 {
+		b.unresolvedRef(route, gatewayv1.RouteReasonBackendNotFound, fmt.Sprintf("staticResponse %s must be named after an HTTP status code, e.g. 503", name))
+		return "", false
+	}
+	return fmt.Sprintf("%s%d", StaticResponsePrefix, code), true
+}
+
+// portScheme returns the scheme a port is reached with from its application protocol, reporting the protocols
+// cloudflared can't speak
+func (b *Builder) portScheme(route Route, scheme string, port string, protocol string) string {
+	switch {
+	case scheme == "http" && protocol == AppProtocolH2C:
+		b.unsupportedField(route, fmt.Sprintf(
+			"%s speaks %s, cloudflared only speaks HTTP/2 to https origins and sends HTTP/1.1", port, AppProtocolH2C,
+		))
+	case scheme == "http" && (protocol == "https" || protocol == AppProtocolWSS):
+		return "https"
+	case route.Kind == KindGRPCRoute && (protocol == "http" || protocol == AppProtocolH2C):
+		b.unsupportedField(route, fmt.Sprintf(
+			"%s speaks %s, cloudflared only speaks HTTP/2 and so gRPC to https origins", port, protocol,
+		))
+	}
+	return scheme
+}
+
+// kindName is the group qualified name of a kind, as in ServiceImport.multicluster.x-k8s.io
+func kindName(group gatewayv1.Group, kind gatewayv1.Kind) string {
+	if group == "" {
+		return string(kind)
+	}
+	return fmt.Sprintf("%s.%s", kind, group)
+}
+
+// unresolvedRef records a backendRef of a route that does not resolve. Routes are rendered once per listener
+// they attach to, so the same backendRef is only recorded once
+func (b *Builder) unresolvedRef(route Route, reason gatewayv1.RouteConditionReason, message string) {
+	key := route.Key()
+	for _, existing := range b.unresolved[key] {
+		if existing.Message == message {
+			return
+		}
+	}
+	b.unresolved[key] = append(b.unresolved[key], UnresolvedRef{Reason: reason, Message: message})
+}
+
+func backendURL(scheme string, hostname string, port *gatewayv1.PortNumber) string {
+	if port == nil {
+		return fmt.Sprintf("%s://%s", scheme, hostname)
+	}
+	return fmt.Sprintf("%s://%s:%d", scheme, hostname, *port)
+}
+
+// findServicePort returns the port of a Service with the given number, nil when it has none
+func findServicePort(service *corev1.Service, number int32) *corev1.ServicePort {
+	for i := range service.Spec.Ports {
+		if service.Spec.Ports[i].Port == number {
+			return &service.Spec.Ports[i]
+		}
+	}
+	return nil
+}
+
+// portProtocol returns the application protocol of a Service port from its appProtocol. Ports without one
+// are named after their protocol by convention, as in https or https-metrics
+func portProtocol(port corev1.ServicePort) string {
+	if protocol := ptr.Deref(port.AppProtocol, ""); protocol != "" {
+		return protocol
+	}
+	name, _, _ := strings.Cut(port.Name, "-")
+	switch name {
+	case "http", "https":
+		return name
+	case "h2c":
+		return AppProtocolH2C
+	default:
+		return ""
+	}
+}
+
+// backendServices returns the Services that backendRefs point at
+func backendServices(routeNamespace string, refs []gatewayv1.BackendObjectReference) []types.NamespacedName {
+	var services []types.NamespacedName
+	for _, ref := range refs {
+		if service := serviceKey(routeNamespace, ref); service != nil {
+			services = append(services, *service)
+		}
+	}
+	return services
+}
+
+// mirrorBackends returns the backends of the RequestMirror filters of filters
+func mirrorBackends(filters []gatewayv1.HTTPRouteFilter) []gatewayv1.BackendObjectReference {
+	var refs []gatewayv1.BackendObjectReference
+	for _, filter := range filters {
+		if filter.Type == gatewayv1.HTTPRouteFilterRequestMirror && filter.RequestMirror != nil {
+			refs = append(refs, filter.RequestMirror.BackendRef)
+		}
+	}
+	return refs
+}
+
+// httpRouteBackends returns the Services of the backendRefs and RequestMirror filters of a HTTPRoute
+func httpRouteBackends(route *gatewayv1.HTTPRoute) []types.NamespacedName {
+	var refs []gatewayv1.BackendObjectReference
+	for _, rule := range route.Spec.Rules {
+		refs = append(refs, mirrorBackends(rule.Filters)...)
+		for _, ref := range rule.BackendRefs {
+			refs = append(refs, ref.BackendObjectReference)
+			refs = append(refs, mirrorBackends(ref.Filters)...)
+		}
+	}
+	return backendServices(route.Namespace, refs)
+}
+
+func grpcRouteBackends(route *gatewayv1.GRPCRoute) []types.NamespacedName {
+	var refs []gatewayv1.BackendObjectReference
+	for _, rule := range route.Spec.Rules {
+		for _, ref := range rule.BackendRefs {
+			refs = append(refs, ref.BackendObjectReference)
+		}
+	}
+	return backendServices(route.Namespace, refs)
+}
+
+func tcpRouteBackends(route *gatewayv1alpha2.TCPRoute) []types.NamespacedName {
+	var refs []gatewayv1.BackendObjectReference
+	for _, rule := range route.Spec.Rules {
+		for _, ref := range rule.BackendRefs {
+			refs = append(refs, ref.BackendObjectReference)
+		}
+	}
+	return backendServices(route.Namespace, refs)
+}
+
+func tlsRouteBackends(route *gatewayv1alpha2.TLSRoute) []types.NamespacedName {
+	var refs []gatewayv1.BackendObjectReference
+	for _, rule := range route.Spec.Rules {
+		for _, ref := range rule.BackendRefs {
+			refs = append(refs, ref.BackendObjectReference)
+		}
+	}
+	return backendServices(route.Namespace, refs)
+}
+
+// routesOfService returns the routes of the cluster with a backendRef to a Service
+func routesOfService(ctx context.Context, c client.Reader, service types.NamespacedName) ([]Route, error) {
+	routes, err := ListRoutes(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	var referencing []Route
+	for _, route := range routes {
+		for _, backend := range route.Backends {
+			if backend == service {
+				referencing = append(referencing, route)
+				break
+			}
+		}
+	}
+	return referencing, nil
+}
+
+// EnqueueServiceRoutes returns a handler that requeues the routes of kind with a backendRef to a changed Service
+func EnqueueServiceRoutes(c client.Reader, kind gatewayv1.Kind) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		routes, err := routesOfService(ctx, c, client.ObjectKeyFromObject(obj))
+		if err != nil {
+			log.FromContext(ctx).Error(err, "failed to find routes referencing service")
+			return nil
+		}
+		var requests []reconcile.Request
+		for _, route := range routes {
+			if route.Kind == kind {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Namespace: route.Namespace, Name: route.Name},
+				})
+			}
+		}
+		return requests
+	})
+}
+
+// EnqueueServiceGateways returns a handler that requeues the parent Gateways of the routes with a backendRef
+// to a changed Service
+func EnqueueServiceGateways(c client.Reader) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		routes, err := routesOfService(ctx, c, client.ObjectKeyFromObject(obj))
+		if err != nil {
+			log.FromContext(ctx).Error(err, "failed to find routes referencing service")
+			return nil
+		}
+		return parentGatewayRequests(routes)
+	})
+}
+
+// parentGatewayRequests returns a request for every Gateway that is a parent of any of routes
+func parentGatewayRequests(routes []Route) []reconcile.Request {
+	seen := map[types.NamespacedName]bool{}
+	var requests []reconcile.Request
+	for _, route := range routes {
+		for _, gateway := range ParentGateways(route) {
+			if !seen[gateway] {
+				seen[gateway] = true
+				requests = append(requests, reconcile.Request{NamespacedName: gateway})
+			}
+		}
+	}
+	return requests
+}
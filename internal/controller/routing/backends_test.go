@@ -0,0 +1,290 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func testService(namespace string, name string, ports ...corev1.ServicePort) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       corev1.ServiceSpec{Ports: ports},
+	}
+}
+
+func testServices(services ...*corev1.Service) map[types.NamespacedName]*corev1.Service {
+	byKey := map[types.NamespacedName]*corev1.Service{}
+	for _, service := range services {
+		byKey[types.NamespacedName{Namespace: service.Namespace, Name: service.Name}] = service
+	}
+	return byKey
+}
+
+func TestBackendURL(t *testing.T) {
+	httpRoute := Route{Kind: KindHTTPRoute, Namespace: "default", Name: "route"}
+	grpcRoute := Route{Kind: KindGRPCRoute, Namespace: "default", Name: "route"}
+	services := testServices(
+		testService("default", "web",
+			corev1.ServicePort{Name: "http", Port: 80},
+			corev1.ServicePort{Name: "https-admin", Port: 8443},
+			corev1.ServicePort{Port: 443, AppProtocol: ptr.To("https")},
+			corev1.ServicePort{Port: 8080, AppProtocol: ptr.To(AppProtocolH2C)},
+			corev1.ServicePort{Port: 8081, AppProtocol: ptr.To("kubernetes.io/ws")},
+			corev1.ServicePort{Port: 8082, AppProtocol: ptr.To(AppProtocolWSS)},
+		),
+		testService("other", "api", corev1.ServicePort{Port: 9000}),
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "external"},
+			Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeExternalName, ExternalName: "api.example.org"},
+		},
+	)
+	serviceImports := map[types.NamespacedName]bool{{Namespace: "default", Name: "shared"}: true}
+	referenceGrants := testReferenceGrants(testReferenceGrant("other", KindHTTPRoute, "default", "api"))
+	ref := func(name string, port *int32) gatewayv1.BackendObjectReference {
+		ref := gatewayv1.BackendObjectReference{Name: gatewayv1.ObjectName(name)}
+		if port != nil {
+			ref.Port = ptr.To(gatewayv1.PortNumber(*port))
+		}
+		return ref
+	}
+	kind := func(group string, kind string, name string, port *int32) gatewayv1.BackendObjectReference {
+		ref := ref(name, port)
+		ref.Group = ptr.To(gatewayv1.Group(group))
+		ref.Kind = ptr.To(gatewayv1.Kind(kind))
+		return ref
+	}
+
+	tests := []struct {
+		name            string
+		route           Route
+		scheme          string
+		ref             gatewayv1.BackendObjectReference
+		want            string
+		wantOK          bool
+		wantReason      gatewayv1.RouteConditionReason
+		wantUnsupported bool
+	}{
+		{
+			name:   "Plain port",
+			route:  httpRoute,
+			scheme: "http",
+			ref:    ref("web", ptr.To(int32(80))),
+			want:   "http://web.default.svc.cluster.local:80",
+			wantOK: true,
+		},
+		{
+			name:   "https appProtocol",
+			route:  httpRoute,
+			scheme: "http",
+			ref:    ref("web", ptr.To(int32(443))),
+			want:   "https://web.default.svc.cluster.local:443",
+			wantOK: true,
+		},
+		{
+			name:   "Port named after https",
+			route:  httpRoute,
+			scheme: "http",
+			ref:    ref("web", ptr.To(int32(8443))),
+			want:   "https://web.default.svc.cluster.local:8443",
+			wantOK: true,
+		},
+		{
+			name:   "WebSocket",
+			route:  httpRoute,
+			scheme: "http",
+			ref:    ref("web", ptr.To(int32(8081))),
+			want:   "http://web.default.svc.cluster.local:8081",
+			wantOK: true,
+		},
+		{
+			name:   "WebSocket over TLS",
+			route:  httpRoute,
+			scheme: "http",
+			ref:    ref("web", ptr.To(int32(8082))),
+			want:   "https://web.default.svc.cluster.local:8082",
+			wantOK: true,
+		},
+		{
+			name:            "h2c is sent HTTP/1.1",
+			route:           httpRoute,
+			scheme:          "http",
+			ref:             ref("web", ptr.To(int32(8080))),
+			want:            "http://web.default.svc.cluster.local:8080",
+			wantOK:          true,
+			wantUnsupported: true,
+		},
+		{
+			name:            "gRPC to an h2c port",
+			route:           grpcRoute,
+			scheme:          "https",
+			ref:             ref("web", ptr.To(int32(8080))),
+			want:            "https://web.default.svc.cluster.local:8080",
+			wantOK:          true,
+			wantUnsupported: true,
+		},
+		{
+			name:   "The scheme of other route kinds is kept",
+			route:  Route{Kind: KindTCPRoute, Namespace: "default", Name: "route"},
+			scheme: "tcp",
+			ref:    ref("web", ptr.To(int32(443))),
+			want:   "tcp://web.default.svc.cluster.local:443",
+			wantOK: true,
+		},
+		{
+			name:   "Service in another namespace",
+			route:  httpRoute,
+			scheme: "http",
+			ref: gatewayv1.BackendObjectReference{
+				Name:      "api",
+				Namespace: ptr.To(gatewayv1.Namespace("other")),
+				Port:      ptr.To(gatewayv1.PortNumber(9000)),
+			},
+			want:   "http://api.other.svc.cluster.local:9000",
+			wantOK: true,
+		},
+		{
+			name:   "Service in another namespace without a ReferenceGrant for the route kind",
+			route:  grpcRoute,
+			scheme: "https",
+			ref: gatewayv1.BackendObjectReference{
+				Name:      "api",
+				Namespace: ptr.To(gatewayv1.Namespace("other")),
+				Port:      ptr.To(gatewayv1.PortNumber(9000)),
+			},
+			wantReason: gatewayv1.RouteReasonRefNotPermitted,
+		},
+		{
+			name:   "Service in another namespace without a ReferenceGrant for its name",
+			route:  httpRoute,
+			scheme: "http",
+			ref: gatewayv1.BackendObjectReference{
+				Name:      "admin",
+				Namespace: ptr.To(gatewayv1.Namespace("other")),
+				Port:      ptr.To(gatewayv1.PortNumber(9000)),
+			},
+			wantReason: gatewayv1.RouteReasonRefNotPermitted,
+		},
+		{
+			name:       "Missing service",
+			route:      httpRoute,
+			scheme:     "http",
+			ref:        ref("missing", ptr.To(int32(80))),
+			wantReason: gatewayv1.RouteReasonBackendNotFound,
+		},
+		{
+			name:       "Missing port",
+			route:      httpRoute,
+			scheme:     "http",
+			ref:        ref("web", ptr.To(int32(9090))),
+			wantReason: gatewayv1.RouteReasonBackendNotFound,
+		},
+		{
+			name:       "No port",
+			route:      httpRoute,
+			scheme:     "http",
+			ref:        ref("web", nil),
+			wantReason: gatewayv1.RouteReasonBackendNotFound,
+		},
+		{
+			name:   "ExternalName service",
+			route:  httpRoute,
+			scheme: "http",
+			ref:    ref("external", ptr.To(int32(8080))),
+			want:   "http://api.example.org:8080",
+			wantOK: true,
+		},
+		{
+			name:   "ServiceImport",
+			route:  httpRoute,
+			scheme: "http",
+			ref:    kind(GroupMultiCluster, KindServiceImport, "shared", ptr.To(int32(80))),
+			want:   "http://shared.default.svc.clusterset.local:80",
+			wantOK: true,
+		},
+		{
+			name:       "Missing ServiceImport",
+			route:      httpRoute,
+			scheme:     "http",
+			ref:        kind(GroupMultiCluster, KindServiceImport, "missing", ptr.To(int32(80))),
+			wantReason: gatewayv1.RouteReasonBackendNotFound,
+		},
+		{
+			name:   "StaticResponse",
+			route:  httpRoute,
+			scheme: "http",
+			ref:    kind("cloudflare.adamland.xyz", KindStaticResponse, "503", nil),
+			want:   "http_status:503",
+			wantOK: true,
+		},
+		{
+			name:       "StaticResponse not named after a status code",
+			route:      httpRoute,
+			scheme:     "http",
+			ref:        kind("cloudflare.adamland.xyz", KindStaticResponse, "maintenance", nil),
+			wantReason: gatewayv1.RouteReasonBackendNotFound,
+		},
+		{
+			name:       "StaticResponse of other route kinds",
+			route:      grpcRoute,
+			scheme:     "https",
+			ref:        kind("cloudflare.adamland.xyz", KindStaticResponse, "503", nil),
+			wantReason: gatewayv1.RouteReasonInvalidKind,
+		},
+		{
+			name:       "Other kinds",
+			route:      httpRoute,
+			scheme:     "http",
+			ref:        kind("apps", "Deployment", "web", ptr.To(int32(80))),
+			wantReason: gatewayv1.RouteReasonInvalidKind,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Builder{
+				services:        services,
+				serviceImports:  serviceImports,
+				referenceGrants: referenceGrants,
+				unsupported:     map[RouteKey][]string{},
+				unresolved:      map[RouteKey][]UnresolvedRef{},
+			}
+			got, ok := b.backendURL(tt.route, tt.scheme, tt.ref)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("backendURL() = %s, %v, want %s, %v", got, ok, tt.want, tt.wantOK)
+			}
+			unresolved := b.unresolved[tt.route.Key()]
+			if tt.wantOK && len(unresolved) != 0 {
+				t.Errorf("unresolved = %v, want none", unresolved)
+			}
+			if !tt.wantOK && (len(unresolved) != 1 || unresolved[0].Reason != tt.wantReason) {
+				t.Errorf("unresolved = %v, want %s", unresolved, tt.wantReason)
+			}
+			if unsupported := b.unsupported[tt.route.Key()]; (len(unsupported) > 0) != tt.wantUnsupported {
+				t.Errorf("unsupported = %v, want unsupported %v", unsupported, tt.wantUnsupported)
+			}
+		})
+	}
+}
+
+func TestBackendURLClusterDomain(t *testing.T) {
+	defaultDomain := k8s.ClusterDomain
+	k8s.ClusterDomain = "example.internal"
+	t.Cleanup(func() { k8s.ClusterDomain = defaultDomain })
+
+	b := &Builder{
+		services:    testServices(testService("default", "web", corev1.ServicePort{Port: 80})),
+		unsupported: map[RouteKey][]string{},
+		unresolved:  map[RouteKey][]UnresolvedRef{},
+	}
+	route := Route{Kind: KindHTTPRoute, Namespace: "default", Name: "route"}
+	got, _ := b.backendURL(route, "http", gatewayv1.BackendObjectReference{Name: "web", Port: ptr.To(gatewayv1.PortNumber(80))})
+	if want := "http://web.default.svc.example.internal:80"; got != want {
+		t.Errorf("backendURL() = %s, want %s", got, want)
+	}
+}
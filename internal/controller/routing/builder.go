@@ -0,0 +1,501 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/cloudflare/cloudflare-go"
+	cloudflarev1alpha1 "github.com/cyclingwithelephants/cloudflare-gateway-controller/api/v1alpha1"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/k8s"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/router"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// Rule is a single cloudflared ingress rule together with where it came from
+type Rule struct {
+	Ingress   cf.IngressConfig
+	Route     Route
+	RuleIndex int
+	Listener  gatewayv1.SectionName
+	// Backend is the Service the rule sends traffic to, nil when it has none
+	Backend *types.NamespacedName
+	// Match, Filters, BackendRefs and Timeouts are those of the HTTPRoute rule the rule was rendered from,
+	// they are empty for other route kinds
+	Match       *gatewayv1.HTTPRouteMatch
+	Filters     []gatewayv1.HTTPRouteFilter
+	BackendRefs []gatewayv1.HTTPBackendRef
+	Timeouts    *gatewayv1.HTTPRouteTimeouts
+	// Router is set for rules that cloudflared hands to the router of the Gateway, which applies their
+	// matches, filters and backends
+	Router bool
+	// Pod is the hostname of the pod of the Backend that rules publishing a single pod send traffic to
+	Pod string
+}
+
+// Source describes where the rule came from: its route, rule and the listener of the Gateway it was rendered for
+func (r Rule) Source() string {
+	source := fmt.Sprintf("%s rules[%d], listener %s", r.Route, r.RuleIndex, r.Listener)
+	if r.Router {
+		source += ", served by the router"
+	}
+	if r.Pod != "" {
+		source += ", pod " + r.Pod
+	}
+	return source
+}
+
+// RouteKey identifies a route of any kind
+type RouteKey struct {
+	Kind      gatewayv1.Kind
+	Namespace string
+	Name      string
+}
+
+func (r Route) Key() RouteKey {
+	return RouteKey{Kind: r.Kind, Namespace: r.Namespace, Name: r.Name}
+}
+
+// Result is the rendered routing table of a Gateway
+type Result struct {
+	// Rules are the rules that survived conflict resolution, in the order cloudflared should match them
+	Rules []Rule
+	// CABundles are the CA bundles of the BackendTLSPolicies used by the rules, by the key of the gateway ConfigMap they go into
+	CABundles map[string]string
+	// EdgeRules are the rules of the zone rulesets that implement the filters cloudflared can't, by phase.
+	// Every phase of EdgePhases is present, even without rules, so that rules of removed filters are cleaned up
+	EdgeRules map[cloudflare.RulesetPhase][]cloudflare.RulesetRule
+	// RouterTable is the routing table of the router of the Gateway, nil when the Gateway has no router
+	RouterTable *router.Table
+	// routed are the rules the router serves that share their hostname and path with an earlier rule of Rules
+	routed    []Rule
+	rendered  map[RouteKey]int
+	conflicts map[RouteKey][]Conflict
+	policies  map[PolicyKey]PolicyStatus
+	// unsupported are the fields of each route that cloudflared ignores or only approximates
+	unsupported map[RouteKey][]string
+	splits      map[RouteKey][]BackendSplit
+	// unresolved are the backendRefs of each route that don't resolve, their rules answer with a 500
+	unresolved map[RouteKey][]UnresolvedRef
+	// readinessGate is set when the Gateway holds back the rules of Services without ready endpoints, waiting
+	// are the Services each route is held back for
+	readinessGate bool
+	waiting       map[RouteKey][]string
+	// maintenance are the routes whose rules answer with the maintenance response
+	maintenance map[RouteKey]bool
+	// previews are the hostnames generated for the routes without any
+	previews map[RouteKey]string
+}
+
+// Ingress returns the cloudflared ingress rules of the result
+func (r *Result) Ingress() []cf.IngressConfig {
+	ingress := make([]cf.IngressConfig, 0, len(r.Rules))
+	for _, rule := range r.Rules {
+		ingress = append(ingress, rule.Ingress)
+	}
+	return ingress
+}
+
+// Sources returns the provenance of the cloudflared ingress rules of the result, in the order of Ingress
+func (r *Result) Sources() []string {
+	sources := make([]string, 0, len(r.Rules))
+	for _, rule := range r.Rules {
+		sources = append(sources, rule.Source())
+	}
+	return sources
+}
+
+// RouterRules returns the rules served by the router of the Gateway
+func (r *Result) RouterRules() []Rule {
+	var rules []Rule
+	for _, rule := range r.Rules {
+		if rule.Router {
+			rules = append(rules, rule)
+		}
+	}
+	return append(rules, r.routed...)
+}
+
+// RenderedRules returns how many ingress rules of the route made it into the config
+func (r *Result) RenderedRules(route Route) int {
+	return r.rendered[route.Key()]
+}
+
+// Conflicts returns the rules of the route that were dropped in favour of another route
+func (r *Result) Conflicts(route Route) []Conflict {
+	return r.conflicts[route.Key()]
+}
+
+// Unsupported returns the fields of the route that cloudflared ignores or only approximates
+func (r *Result) Unsupported(route Route) []string {
+	return r.unsupported[route.Key()]
+}
+
+// UnresolvedRefs returns the backendRefs of the route that do not point at a backend the Gateway can reach
+func (r *Result) UnresolvedRefs(route Route) []UnresolvedRef {
+	return r.unresolved[route.Key()]
+}
+
+// ReadinessGate reports whether the Gateway holds back the rules of Services without ready endpoints
+func (r *Result) ReadinessGate() bool {
+	return r.readinessGate
+}
+
+// Waiting returns the backend Services of the route that the readiness gate holds its rules back for
+func (r *Result) Waiting(route Route) []string {
+	return r.waiting[route.Key()]
+}
+
+// Maintenance reports whether the rules of the route answer with the maintenance response, as the route or
+// the Gateway is in maintenance
+func (r *Result) Maintenance(route Route) bool {
+	return r.maintenance[route.Key()]
+}
+
+// PreviewHostname returns the hostname generated for the route, empty when the route has hostnames
+func (r *Result) PreviewHostname(route Route) string {
+	return r.previews[route.Key()]
+}
+
+// PreviewHostnames returns the hostnames generated for the routes of the Gateway without any, sorted
+func (r *Result) PreviewHostnames() []string {
+	seen := map[string]bool{}
+	hostnames := make([]string, 0, len(r.previews))
+	for _, hostname := range r.previews {
+		if !seen[hostname] {
+			seen[hostname] = true
+			hostnames = append(hostnames, hostname)
+		}
+	}
+	sort.Strings(hostnames)
+	return hostnames
+}
+
+// Splits returns how the requests of the rules of the route with several backendRefs are split between them
+func (r *Result) Splits(route Route) []BackendSplit {
+	return r.splits[route.Key()]
+}
+
+// Policy returns how a policy was applied, reporting false when it does not apply to any rule of the Gateway
+func (r *Result) Policy(policy PolicyKey) (PolicyStatus, bool) {
+	status, ok := r.policies[policy]
+	return status, ok
+}
+
+// Builder renders the cloudflared ingress rules of every route attached to a Gateway
+type Builder struct {
+	client  client.Reader
+	gateway *gatewayv1.Gateway
+
+	// the fields below are the state of a single Build
+	// domain is the domain of the GatewayClass, empty when its parameters can't be loaded
+	domain string
+	// routerService is the cloudflared service of the router of the Gateway, empty when it has none
+	routerService string
+	// readinessGate is the cloudflared service answering the rules held back by the readiness gate of the
+	// Gateway, empty when it has none
+	readinessGate string
+	// maintenanceResponse answers the requests of the rules of routes in maintenance
+	maintenanceResponse maintenanceResponse
+	// previewHostname is the template of the hostnames of routes without any, empty when the Gateway generates none
+	previewHostname string
+	// routerClaims are the hostnames and paths that the router serves
+	routerClaims          map[claim]bool
+	services              map[types.NamespacedName]*corev1.Service
+	endpointSlices        map[types.NamespacedName][]discoveryv1.EndpointSlice
+	serviceImports        map[types.NamespacedName]bool
+	referenceGrants       referenceGrantIndex
+	backendTLS            *backendTLSIndex
+	originRequestPolicies *originRequestPolicyIndex
+	policies              map[PolicyKey]PolicyStatus
+	caBundles             map[string]string
+	unsupported           map[RouteKey][]string
+	splits                map[RouteKey][]BackendSplit
+	unresolved            map[RouteKey][]UnresolvedRef
+	waiting               map[RouteKey][]string
+	maintenance           map[RouteKey]bool
+	previews              map[RouteKey]string
+}
+
+func NewBuilder(c client.Reader, gateway *gatewayv1.Gateway) *Builder {
+	return &Builder{
+		client:  c,
+		gateway: gateway,
+	}
+}
+
+func (b *Builder) Build(ctx context.Context) (*Result, error) {
+	services, err := loadServices(ctx, b.client)
+	if err != nil {
+		return nil, err
+	}
+	b.services = services
+	endpointSlices, err := loadEndpointSlices(ctx, b.client)
+	if err != nil {
+		return nil, err
+	}
+	b.endpointSlices = endpointSlices
+	serviceImports, err := loadServiceImports(ctx, b.client)
+	if err != nil {
+		return nil, err
+	}
+	b.serviceImports = serviceImports
+	referenceGrants, err := loadReferenceGrants(ctx, b.client)
+	if err != nil {
+		return nil, err
+	}
+	b.referenceGrants = referenceGrants
+	backendTLS, err := loadBackendTLS(ctx, b.client)
+	if err != nil {
+		return nil, err
+	}
+	b.backendTLS = backendTLS
+	originRequestPolicies, err := loadOriginRequestPolicies(ctx, b.client)
+	if err != nil {
+		return nil, err
+	}
+	b.originRequestPolicies = originRequestPolicies
+	b.policies = map[PolicyKey]PolicyStatus{}
+	b.caBundles = map[string]string{}
+	b.unsupported = map[RouteKey][]string{}
+	b.splits = map[RouteKey][]BackendSplit{}
+	b.unresolved = map[RouteKey][]UnresolvedRef{}
+	b.waiting = map[RouteKey][]string{}
+	b.maintenance = map[RouteKey]bool{}
+	b.previews = map[RouteKey]string{}
+	b.domain = ""
+	// the Gateway reconciler reports invalid GatewayClass parameters, until they are fixed hostnames are not checked
+	if config, err := k8s.LoadGatewayClassConfig(ctx, b.client, b.gateway); err == nil {
+		b.domain = config.Domain
+	}
+	b.routerService = ""
+	b.readinessGate = ""
+	b.maintenanceResponse = maintenance(cloudflarev1alpha1.CloudflaredConfigSpec{}, b.gateway.Namespace)
+	b.previewHostname = ""
+	// the Gateway reconciler reports invalid parameters, until they are fixed the Gateway has no router nor
+	// readiness gate, and answers with a 503 in maintenance
+	if cloudflared, err := LoadCloudflaredConfig(ctx, b.client, b.gateway); err == nil {
+		if cloudflared.Router != nil {
+			b.routerService = k8s.RouterServiceURL(b.gateway.Name, b.gateway.Namespace)
+		}
+		b.readinessGate = readinessGateService(cloudflared, b.gateway.Namespace)
+		b.maintenanceResponse = maintenance(cloudflared, b.gateway.Namespace)
+		b.previewHostname = ptr.Deref(cloudflared.PreviewHostname, "")
+	}
+
+	var candidates []Rule
+
+	httpRoutes, err := listHTTPRoutes(ctx, b.client)
+	if err != nil {
+		return nil, err
+	}
+	var attached []attachedHTTPRoute
+	for i := range httpRoutes {
+		httpRoute := &httpRoutes[i]
+		listeners, err := b.attachedListeners(ctx, FromHTTPRoute(httpRoute))
+		if err != nil {
+			return nil, err
+		}
+		for _, listener := range listeners {
+			attached = append(attached, attachedHTTPRoute{
+				route:     httpRoute,
+				listener:  listener.Name,
+				hostnames: b.routeHostnames(FromHTTPRoute(httpRoute), listener.Hostname, httpRoute.Spec.Hostnames),
+			})
+		}
+	}
+	b.routerClaims = b.claimRouter(attached)
+	for _, a := range attached {
+		candidates = append(candidates, b.httpRouteRules(a.route, a.listener, a.hostnames)...)
+	}
+
+	grpcRoutes, err := listGRPCRoutes(ctx, b.client)
+	if err != nil {
+		return nil, err
+	}
+	for i := range grpcRoutes {
+		grpcRoute := &grpcRoutes[i]
+		listeners, err := b.attachedListeners(ctx, FromGRPCRoute(grpcRoute))
+		if err != nil {
+			return nil, err
+		}
+		for _, listener := range listeners {
+			hostnames := b.routeHostnames(FromGRPCRoute(grpcRoute), listener.Hostname, grpcRoute.Spec.Hostnames)
+			candidates = append(candidates, b.grpcRouteRules(grpcRoute, listener.Name, hostnames)...)
+		}
+	}
+
+	tcpRoutes, err := listTCPRoutes(ctx, b.client)
+	if err != nil {
+		return nil, err
+	}
+	for i := range tcpRoutes {
+		tcpRoute := &tcpRoutes[i]
+		listeners, err := b.attachedListeners(ctx, FromTCPRoute(tcpRoute))
+		if err != nil {
+			return nil, err
+		}
+		for _, listener := range listeners {
+			candidates = append(candidates, b.tcpRouteRules(tcpRoute, listener)...)
+		}
+	}
+
+	tlsRoutes, err := listTLSRoutes(ctx, b.client)
+	if err != nil {
+		return nil, err
+	}
+	for i := range tlsRoutes {
+		tlsRoute := &tlsRoutes[i]
+		listeners, err := b.attachedListeners(ctx, FromTLSRoute(tlsRoute))
+		if err != nil {
+			return nil, err
+		}
+		for _, listener := range listeners {
+			hostnames := b.routeHostnames(FromTLSRoute(tlsRoute), listener.Hostname, tlsRoute.Spec.Hostnames)
+			candidates = append(candidates, b.tlsRouteRules(tlsRoute, listener.Name, hostnames)...)
+		}
+	}
+
+	var podRules []Rule
+	for _, candidate := range candidates {
+		podRules = append(podRules, b.podHostnameRules(candidate)...)
+	}
+	candidates = append(candidates, podRules...)
+
+	for i := range candidates {
+		candidates[i].Ingress.OriginRequest = b.applyOriginRequestPolicies(candidates[i])
+		candidates[i] = b.gateReadiness(candidates[i])
+		candidates[i] = b.applyMaintenance(candidates[i])
+	}
+
+	result := resolveConflicts(candidates)
+	result.CABundles = b.caBundles
+	result.policies = b.policies
+	sortRules(result.Rules)
+	b.reportCapturedRewrites(result.Rules)
+	result.EdgeRules = b.edgeRules(cf.EdgeRuleOwner(b.gateway.Namespace, b.gateway.Name), result.Rules)
+	if b.routerService != "" {
+		result.RouterTable = b.routerTable(result.RouterRules())
+	}
+	result.unsupported = b.unsupported
+	result.splits = b.splits
+	result.unresolved = b.unresolved
+	result.readinessGate = b.readinessGate != ""
+	result.waiting = b.waiting
+	result.maintenance = b.maintenance
+	result.previews = b.previews
+	return result, nil
+}
+
+// unsupportedField records a field of a route that cloudflared ignores or only approximates. Routes are
+// rendered once per listener they attach to, so the same field is only recorded once
+func (b *Builder) unsupportedField(route Route, message string) {
+	key := route.Key()
+	for _, existing := range b.unsupported[key] {
+		if existing == message {
+			return
+		}
+	}
+	b.unsupported[key] = append(b.unsupported[key], message)
+}
+
+// inDomain returns the hostnames of a route within the domain of the GatewayClass, recording the others
+// as unsupported. Matches on every hostname are kept
+func (b *Builder) inDomain(route Route, hostnames []string) []string {
+	if b.domain == "" {
+		return hostnames
+	}
+	var kept []string
+	for _, hostname := range hostnames {
+		if hostname != "" && !InDomain(hostname, b.domain) {
+			b.unsupportedField(route, fmt.Sprintf("hostname %s is outside of the domain %s of the GatewayClass and is not rendered", hostname, b.domain))
+			continue
+		}
+		kept = append(kept, hostname)
+	}
+	return kept
+}
+
+// recordSplit records the split of a rule of a route, once for all the listeners the route attaches to
+func (b *Builder) recordSplit(route Route, split BackendSplit) {
+	key := route.Key()
+	for _, existing := range b.splits[key] {
+		if existing.RuleIndex == split.RuleIndex {
+			return
+		}
+	}
+	b.splits[key] = append(b.splits[key], split)
+}
+
+// backend returns the cloudflared service and origin settings of a backendRef, NoBackendsService when it does not
+// resolve. Services targeted by a BackendTLSPolicy are reached over https, and not at all while that policy is invalid
+func (b *Builder) backend(route Route, scheme string, ref gatewayv1.BackendObjectReference) (string, *cf.OriginRequestConfig) {
+	var tls *backendTLS
+	if service := b.permittedServiceKey(route, ref); service != nil {
+		tls = b.backendTLS.byService[*service]
+		for policy, status := range b.backendTLS.conflicted[*service] {
+			b.policies[PolicyKey{Kind: KindBackendTLSPolicy, NamespacedName: policy}] = status
+		}
+	}
+	if tls == nil {
+		url, ok := b.backendURL(route, scheme, ref)
+		if !ok {
+			return NoBackendsService, nil
+		}
+		return url, nil
+	}
+
+	b.policies[PolicyKey{Kind: KindBackendTLSPolicy, NamespacedName: tls.policy}] = tls.status
+	if !tls.status.Accepted() {
+		return NoBackendsService, nil
+	}
+	url, ok := b.backendURL(route, "https", ref)
+	if !ok {
+		return NoBackendsService, nil
+	}
+	if tls.caBundle != "" {
+		b.caBundles[CABundleKey(tls.policy)] = tls.caBundle
+	}
+	return url, tls.originRequest()
+}
+
+// attachedListeners returns every listener of the Gateway that the route attaches to through any of its parentRefs
+func (b *Builder) attachedListeners(ctx context.Context, route Route) ([]gatewayv1.Listener, error) {
+	seen := map[gatewayv1.SectionName]bool{}
+	var listeners []gatewayv1.Listener
+	for _, ref := range route.ParentRefs {
+		if !TargetsGateway(ref, route.Namespace, b.gateway) {
+			continue
+		}
+		attachment, err := Attach(ctx, b.client, b.gateway, route, ref)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to attach %s", route)
+		}
+		for _, name := range attachment.Listeners {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			for _, listener := range b.gateway.Spec.Listeners {
+				if listener.Name == name {
+					listeners = append(listeners, listener)
+				}
+			}
+		}
+	}
+	return listeners, nil
+}
+
+// sortRules orders the rules the way cf.Sort orders ingress rules
+func sortRules(rules []Rule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		return cf.Less(rules[i].Ingress, rules[j].Ingress)
+	})
+}
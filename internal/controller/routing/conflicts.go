@@ -0,0 +1,115 @@
+package routing
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Conflict describes a rule that was dropped because an older route already claims its hostname and path
+type Conflict struct {
+	Hostname  string
+	Path      string
+	RuleIndex int
+	Winner    Route
+}
+
+func (c Conflict) String() string {
+	path := c.Path
+	if path == "" {
+		path = "/"
+	}
+	return fmt.Sprintf("hostname %s path %s of rule %d is already claimed by %s", c.Hostname, path, c.RuleIndex, c.Winner)
+}
+
+type claim struct {
+	hostname string
+	path     string
+}
+
+// resolveConflicts keeps a single rule for every hostname and path, following the Gateway API
+// conflict resolution rules: the oldest route wins, with namespace/name breaking ties. Rules served by
+// the router share their hostname and path, as the router tells them apart by the rest of their match
+func resolveConflicts(candidates []Rule) *Result {
+	result := &Result{
+		rendered:  map[RouteKey]int{},
+		conflicts: map[RouteKey][]Conflict{},
+	}
+
+	sorted := make([]Rule, len(candidates))
+	copy(sorted, candidates)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return routeLess(sorted[i].Route, sorted[j].Route)
+	})
+
+	winners := map[claim]Rule{}
+	for _, rule := range sorted {
+		key := claim{hostname: rule.Ingress.Hostname, path: rule.Ingress.Path}
+		winner, claimed := winners[key]
+		switch {
+		case !claimed:
+			winners[key] = rule
+			result.Rules = append(result.Rules, rule)
+			result.rendered[rule.Route.Key()]++
+		case winner.Router && rule.Router:
+			result.routed = append(result.routed, rule)
+			if winner.Route.Key() != rule.Route.Key() {
+				result.rendered[rule.Route.Key()]++
+			}
+		case winner.Route.Key() != rule.Route.Key():
+			result.conflicts[rule.Route.Key()] = append(result.conflicts[rule.Route.Key()], Conflict{
+				Hostname:  rule.Ingress.Hostname,
+				Path:      rule.Ingress.Path,
+				RuleIndex: rule.RuleIndex,
+				Winner:    winner.Route,
+			})
+		}
+		// a route repeating its own hostname and path is not a conflict, its first rule is kept
+	}
+	return result
+}
+
+// TakenConflicts returns the conflicts that route causes for other routes in after and that were not in before,
+// by the route that lost the hostname and path. Routes keep their age when updated, so an older route updated to
+// claim the hostname and path of another route takes it from that route
+func TakenConflicts(before *Result, after *Result, route Route) map[RouteKey][]Conflict {
+	taken := map[RouteKey][]Conflict{}
+	for loser, conflicts := range after.conflicts {
+		if loser == route.Key() {
+			continue
+		}
+		for _, conflict := range conflicts {
+			if conflict.Winner.Key() != route.Key() || hasConflict(before.conflicts[loser], conflict) {
+				continue
+			}
+			taken[loser] = append(taken[loser], conflict)
+		}
+	}
+	return taken
+}
+
+// hasConflict reports whether conflicts holds the same conflict as conflict
+func hasConflict(conflicts []Conflict, conflict Conflict) bool {
+	for _, existing := range conflicts {
+		if existing.Hostname == conflict.Hostname &&
+			existing.Path == conflict.Path &&
+			existing.RuleIndex == conflict.RuleIndex &&
+			existing.Winner.Key() == conflict.Winner.Key() {
+			return true
+		}
+	}
+	return false
+}
+
+// routeLess orders routes by age, oldest first, then by namespace and name
+func routeLess(a Route, b Route) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	return a.Kind < b.Kind
+}
@@ -0,0 +1,159 @@
+package routing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestResolveConflicts(t *testing.T) {
+	older := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	newer := metav1.NewTime(older.Add(time.Hour))
+	rule := func(namespace string, name string, created metav1.Time, hostname string, path string) Rule {
+		return Rule{
+			Ingress: cf.IngressConfig{Hostname: hostname, Path: path, Service: name},
+			Route:   Route{Kind: KindHTTPRoute, Namespace: namespace, Name: name, CreationTimestamp: created},
+		}
+	}
+
+	routerRule := func(rule Rule) Rule {
+		rule.Router = true
+		return rule
+	}
+
+	tests := []struct {
+		name          string
+		candidates    []Rule
+		wantServices  []string
+		wantConflicts map[string]int
+		wantRouted    int
+	}{
+		{
+			name: "Oldest route wins",
+			candidates: []Rule{
+				rule("default", "new", newer, "a.example.com", ""),
+				rule("default", "old", older, "a.example.com", ""),
+			},
+			wantServices:  []string{"old"},
+			wantConflicts: map[string]int{"new": 1},
+		},
+		{
+			name: "Namespace and name break ties",
+			candidates: []Rule{
+				rule("b", "first", older, "a.example.com", ""),
+				rule("a", "second", older, "a.example.com", ""),
+			},
+			wantServices:  []string{"second"},
+			wantConflicts: map[string]int{"first": 1},
+		},
+		{
+			name: "Different paths do not conflict",
+			candidates: []Rule{
+				rule("default", "new", newer, "a.example.com", "^/api(/.*)?$"),
+				rule("default", "old", older, "a.example.com", ""),
+			},
+			wantServices:  []string{"old", "new"},
+			wantConflicts: map[string]int{},
+		},
+		{
+			name: "A route repeating itself does not conflict",
+			candidates: []Rule{
+				rule("default", "old", older, "a.example.com", ""),
+				rule("default", "old", older, "a.example.com", ""),
+			},
+			wantServices:  []string{"old"},
+			wantConflicts: map[string]int{},
+		},
+		{
+			name: "Rules of the router share their hostname and path",
+			candidates: []Rule{
+				routerRule(rule("default", "new", newer, "a.example.com", "")),
+				routerRule(rule("default", "old", older, "a.example.com", "")),
+			},
+			wantServices:  []string{"old"},
+			wantConflicts: map[string]int{},
+			wantRouted:    2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := resolveConflicts(tt.candidates)
+			if len(result.Rules) != len(tt.wantServices) {
+				t.Fatalf("resolveConflicts() kept %d rules, want %d", len(result.Rules), len(tt.wantServices))
+			}
+			for i, want := range tt.wantServices {
+				if got := result.Rules[i].Ingress.Service; got != want {
+					t.Errorf("rule %d service = %s, want %s", i, got, want)
+				}
+			}
+			for _, candidate := range tt.candidates {
+				if got, want := len(result.Conflicts(candidate.Route)), tt.wantConflicts[candidate.Route.Name]; got != want {
+					t.Errorf("%s has %d conflicts, want %d", candidate.Route, got, want)
+				}
+			}
+			if got := len(result.RouterRules()); got != tt.wantRouted {
+				t.Errorf("the router serves %d rules, want %d", got, tt.wantRouted)
+			}
+		})
+	}
+}
+
+func TestTakenConflicts(t *testing.T) {
+	older := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	newer := metav1.NewTime(older.Add(time.Hour))
+	oldest := metav1.NewTime(older.Add(-time.Hour))
+	rule := func(name string, created metav1.Time, hostname string) Rule {
+		return Rule{
+			Ingress: cf.IngressConfig{Hostname: hostname, Service: name},
+			Route:   Route{Kind: KindHTTPRoute, Namespace: "default", Name: name, CreationTimestamp: created},
+		}
+	}
+	updated := Route{Kind: KindHTTPRoute, Namespace: "default", Name: "old", CreationTimestamp: older}
+
+	tests := []struct {
+		name   string
+		before []Rule
+		after  []Rule
+		want   map[string]int
+	}{
+		{
+			name:   "Older routes taking the hostname of a newer route",
+			before: []Rule{rule("old", older, "a.example.com"), rule("new", newer, "b.example.com")},
+			after:  []Rule{rule("old", older, "b.example.com"), rule("new", newer, "b.example.com")},
+			want:   map[string]int{"new": 1},
+		},
+		{
+			name:   "Routes already winning the hostname",
+			before: []Rule{rule("old", older, "a.example.com"), rule("new", newer, "a.example.com")},
+			after:  []Rule{rule("old", older, "a.example.com"), rule("new", newer, "a.example.com")},
+			want:   map[string]int{},
+		},
+		{
+			name:   "Routes losing a hostname themselves",
+			before: []Rule{rule("old", older, "a.example.com"), rule("oldest", oldest, "b.example.com")},
+			after:  []Rule{rule("old", older, "b.example.com"), rule("oldest", oldest, "b.example.com")},
+			want:   map[string]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taken := TakenConflicts(resolveConflicts(tt.before), resolveConflicts(tt.after), updated)
+			got := map[string]int{}
+			for loser, conflicts := range taken {
+				got[loser.Name] = len(conflicts)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("TakenConflicts() = %v, want %v", got, tt.want)
+			}
+			for name, count := range tt.want {
+				if got[name] != count {
+					t.Errorf("TakenConflicts() took %d hostnames and paths from %s, want %d", got[name], name, count)
+				}
+			}
+		})
+	}
+}
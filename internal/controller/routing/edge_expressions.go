@@ -0,0 +1,147 @@
+package routing
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// Rules of zone rulesets run at the Cloudflare edge before requests reach cloudflared, so the expressions
+// below have to mirror how cloudflared picks the ingress rule of a request for the edge rules to only apply
+// to the requests of their ingress rule
+// https://developers.cloudflare.com/ruleset-engine/rules-language/
+
+// edgeExpression matches the requests of the ingress rule at index of rules, which are sorted the way cloudflared
+// matches them. Requests that cloudflared hands to an earlier rule are excluded
+func edgeExpression(rules []Rule, index int) string {
+	rule := rules[index]
+	conditions := []string{}
+	if host := hostnameExpression(rule.Ingress.Hostname); host != "" {
+		conditions = append(conditions, host)
+	}
+	conditions = append(conditions, matchExpressions(rule)...)
+	for _, earlier := range rules[:index] {
+		if !hostnamesOverlap(earlier.Ingress.Hostname, rule.Ingress.Hostname) {
+			continue
+		}
+		conditions = append(conditions, "not ("+ingressExpression(earlier)+")")
+	}
+	return andExpression(conditions)
+}
+
+// ingressExpression matches the requests cloudflared hands to a rule, which only looks at their hostname and path
+func ingressExpression(rule Rule) string {
+	conditions := []string{}
+	if host := hostnameExpression(rule.Ingress.Hostname); host != "" {
+		conditions = append(conditions, host)
+	}
+	if path := rulePathExpression(rule); path != "" {
+		conditions = append(conditions, path)
+	}
+	return andExpression(conditions)
+}
+
+// matchExpressions are the conditions of the HTTPRoute match of a rule. Unlike cloudflared, the edge can
+// match headers, query parameters and methods
+func matchExpressions(rule Rule) []string {
+	conditions := []string{}
+	if path := rulePathExpression(rule); path != "" {
+		conditions = append(conditions, path)
+	}
+	if rule.Match == nil {
+		return conditions
+	}
+	if rule.Match.Method != nil {
+		conditions = append(conditions, "http.request.method eq "+cf.QuoteString(string(*rule.Match.Method)))
+	}
+	for _, header := range rule.Match.Headers {
+		field := fmt.Sprintf("http.request.headers[%s][*]", cf.QuoteString(strings.ToLower(string(header.Name))))
+		regex := header.Type != nil && *header.Type == gatewayv1.HeaderMatchRegularExpression
+		conditions = append(conditions, anyExpression(field, header.Value, regex))
+	}
+	for _, param := range rule.Match.QueryParams {
+		field := fmt.Sprintf("http.request.uri.args[%s][*]", cf.QuoteString(string(param.Name)))
+		regex := param.Type != nil && *param.Type == gatewayv1.QueryParamMatchRegularExpression
+		conditions = append(conditions, anyExpression(field, param.Value, regex))
+	}
+	return conditions
+}
+
+func anyExpression(field string, value string, regex bool) string {
+	operator := "eq"
+	if regex {
+		operator = "matches"
+	}
+	return fmt.Sprintf("any(%s %s %s)", field, operator, cf.QuoteString(value))
+}
+
+// hostnameExpression matches requests for hostname, a wildcard matches every subdomain and an empty hostname everything
+func hostnameExpression(hostname string) string {
+	switch {
+	case hostname == "":
+		return ""
+	case strings.HasPrefix(hostname, "*."):
+		return "ends_with(http.host, " + cf.QuoteString(hostname[1:]) + ")"
+	default:
+		return "http.host eq " + cf.QuoteString(hostname)
+	}
+}
+
+// rulePathExpression matches the request paths of a rule, preferring its HTTPRoute path match over
+// the regular expression cloudflared is given, as regular expressions need a Business plan
+func rulePathExpression(rule Rule) string {
+	if rule.Match != nil {
+		return pathExpression(rule.Match.Path)
+	}
+	if rule.Ingress.Path == "" {
+		return ""
+	}
+	return "http.request.uri.path matches " + cf.QuoteString(rule.Ingress.Path)
+}
+
+// pathExpression matches the request paths of a HTTPRoute path match, see PathRegex
+func pathExpression(match *gatewayv1.HTTPPathMatch) string {
+	if match == nil || match.Value == nil {
+		return ""
+	}
+	matchType := gatewayv1.PathMatchPathPrefix
+	if match.Type != nil {
+		matchType = *match.Type
+	}
+	switch matchType {
+	case gatewayv1.PathMatchExact:
+		return "http.request.uri.path eq " + cf.QuoteString(*match.Value)
+	case gatewayv1.PathMatchRegularExpression:
+		return "http.request.uri.path matches " + cf.QuoteString(*match.Value)
+	default:
+		prefix := strings.TrimSuffix(*match.Value, "/")
+		if prefix == "" {
+			return ""
+		}
+		return fmt.Sprintf("(http.request.uri.path eq %s or starts_with(http.request.uri.path, %s))",
+			cf.QuoteString(prefix), cf.QuoteString(prefix+"/"))
+	}
+}
+
+// hostnamesOverlap reports whether requests for hostname b can be handed to a rule for hostname a
+func hostnamesOverlap(a string, b string) bool {
+	switch {
+	case a == b || a == "" || b == "":
+		return true
+	case strings.HasPrefix(a, "*."):
+		return strings.HasSuffix(b, a[1:])
+	case strings.HasPrefix(b, "*."):
+		return strings.HasSuffix(a, b[1:])
+	default:
+		return false
+	}
+}
+
+func andExpression(conditions []string) string {
+	if len(conditions) == 0 {
+		return "true"
+	}
+	return strings.Join(conditions, " and ")
+}
@@ -0,0 +1,38 @@
+package routing
+
+import (
+	"github.com/cloudflare/cloudflare-go"
+)
+
+const (
+	// RedirectPhase is the phase of the zone rulesets that holds Single Redirect rules
+	RedirectPhase = cloudflare.RulesetPhaseHTTPRequestDynamicRedirect
+	// URLRewritePhase is the phase of the zone rulesets that holds URL rewrite transform rules
+	URLRewritePhase = cloudflare.RulesetPhaseHTTPRequestTransform
+	// RequestHeaderPhase is the phase of the zone rulesets that holds request header transform rules
+	RequestHeaderPhase = cloudflare.RulesetPhaseHTTPRequestLateTransform
+	// ResponseHeaderPhase is the phase of the zone rulesets that holds response header transform rules
+	ResponseHeaderPhase = cloudflare.RulesetPhaseHTTPResponseHeadersTransform
+)
+
+// EdgePhases are the phases of the zone rulesets the controller manages rules in
+var EdgePhases = []cloudflare.RulesetPhase{
+	RedirectPhase,
+	URLRewritePhase,
+	RequestHeaderPhase,
+	ResponseHeaderPhase,
+}
+
+// edgeRules renders the filters of the rules that cloudflared can't apply into rules of the zone rulesets, owned by owner.
+// The router applies the filters of the rules it serves itself
+func (b *Builder) edgeRules(owner string, rules []Rule) map[cloudflare.RulesetPhase][]cloudflare.RulesetRule {
+	edgeRules := make(map[cloudflare.RulesetPhase][]cloudflare.RulesetRule, len(EdgePhases))
+	for _, phase := range EdgePhases {
+		edgeRules[phase] = nil
+	}
+	edgeRules[RedirectPhase] = b.redirectRules(owner, rules)
+	edgeRules[URLRewritePhase] = rewriteRules(owner, rules)
+	edgeRules[RequestHeaderPhase] = headerRules(owner, rules, RequestHeaderPhase)
+	edgeRules[ResponseHeaderPhase] = headerRules(owner, rules, ResponseHeaderPhase)
+	return edgeRules
+}
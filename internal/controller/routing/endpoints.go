@@ -0,0 +1,86 @@
+package routing
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// loadEndpointSlices returns the EndpointSlices of the cluster by the Service they belong to
+func loadEndpointSlices(ctx context.Context, c client.Reader) (map[types.NamespacedName][]discoveryv1.EndpointSlice, error) {
+	slices := &discoveryv1.EndpointSliceList{}
+	if err := c.List(ctx, slices); err != nil {
+		return nil, errors.Wrap(err, "failed to list endpointSlices")
+	}
+	byService := map[types.NamespacedName][]discoveryv1.EndpointSlice{}
+	for _, slice := range slices.Items {
+		service, ok := sliceService(&slice)
+		if !ok {
+			continue
+		}
+		byService[service] = append(byService[service], slice)
+	}
+	return byService, nil
+}
+
+// sliceService returns the Service an EndpointSlice belongs to
+func sliceService(slice client.Object) (types.NamespacedName, bool) {
+	name, ok := slice.GetLabels()[discoveryv1.LabelServiceName]
+	if !ok || name == "" {
+		return types.NamespacedName{}, false
+	}
+	return types.NamespacedName{Namespace: slice.GetNamespace(), Name: name}, true
+}
+
+// readyPodHostnames returns the hostnames of the ready endpoints of a Service, sorted. Only pods with a hostname
+// behind a headless Service, as the pods of StatefulSets, have one
+func (b *Builder) readyPodHostnames(service types.NamespacedName) []string {
+	seen := map[string]bool{}
+	var hostnames []string
+	for _, slice := range b.endpointSlices[service] {
+		for _, endpoint := range slice.Endpoints {
+			// endpoints of unknown readiness are ready, as for kube-proxy
+			if endpoint.Hostname == nil || *endpoint.Hostname == "" || !ptr.Deref(endpoint.Conditions.Ready, true) {
+				continue
+			}
+			if !seen[*endpoint.Hostname] {
+				seen[*endpoint.Hostname] = true
+				hostnames = append(hostnames, *endpoint.Hostname)
+			}
+		}
+	}
+	sort.Strings(hostnames)
+	return hostnames
+}
+
+// EnqueueEndpointSliceGateways returns a handler that requeues the parent Gateways of the routes that publish
+// the pods of the Service of a changed EndpointSlice, or that are held back by a readiness gate until it has
+// ready endpoints
+func EnqueueEndpointSliceGateways(c client.Reader) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		service, ok := sliceService(obj)
+		if !ok {
+			return nil
+		}
+		routes, err := routesOfService(ctx, c, service)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "failed to find routes referencing service")
+			return nil
+		}
+		var publishing []Route
+		for _, route := range routes {
+			if podHostnames(route) {
+				publishing = append(publishing, route)
+			}
+		}
+		return parentGatewayRequests(append(publishing, gatedRoutes(ctx, c, routes)...))
+	})
+}
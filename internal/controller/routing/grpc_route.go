@@ -0,0 +1,95 @@
+package routing
+
+import (
+	"regexp"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	// grpcNameRegex matches any single gRPC service or method name in a request path
+	grpcNameRegex = "[^/]+"
+)
+
+// grpcRouteRules renders one ingress rule per hostname and method match of every rule of the route.
+// cloudflared can only speak HTTP/2 to origins over https, so gRPC backends are rendered that way
+func (b *Builder) grpcRouteRules(route *gatewayv1.GRPCRoute, listener gatewayv1.SectionName, hostnames []string) []Rule {
+	source := FromGRPCRoute(route)
+	var rules []Rule
+	for ruleIndex, rule := range route.Spec.Rules {
+		rulePath := field.NewPath("rules").Index(ruleIndex)
+		if errs := validateGRPCRouteRule(rulePath, rule); len(errs) > 0 {
+			for _, err := range errs {
+				b.unsupportedField(source, err.Error()+", the rule is not rendered")
+			}
+			continue
+		}
+		for _, err := range unsupportedGRPCRouteFilters(rulePath, rule) {
+			b.unsupportedField(source, err.Error())
+		}
+		service := NoBackendsService
+		var originRequest *cf.OriginRequestConfig
+		var backend *types.NamespacedName
+		if len(rule.BackendRefs) > 0 {
+			backend = b.permittedServiceKey(source, rule.BackendRefs[0].BackendObjectReference)
+			service, originRequest = b.backend(source, "https", rule.BackendRefs[0].BackendObjectReference)
+			if service != NoBackendsService {
+				if originRequest == nil {
+					originRequest = &cf.OriginRequestConfig{}
+				}
+				originRequest.HTTP2Origin = ptr.To(true)
+			}
+		}
+		matches := rule.Matches
+		if len(matches) == 0 {
+			matches = []gatewayv1.GRPCRouteMatch{{}}
+		}
+		for _, hostname := range hostnames {
+			for _, match := range matches {
+				rules = append(rules, Rule{
+					Ingress: cf.IngressConfig{
+						Hostname:      hostname,
+						Path:          MethodRegex(match.Method),
+						Service:       service,
+						OriginRequest: originRequest,
+					},
+					Route:     source,
+					RuleIndex: ruleIndex,
+					Listener:  listener,
+					Backend:   backend,
+				})
+			}
+		}
+	}
+	return rules
+}
+
+// MethodRegex converts a GRPCRoute method match into a regular expression on the request path,
+// which gRPC sets to /<service>/<method>. Matches on every method render as an empty string
+func MethodRegex(match *gatewayv1.GRPCMethodMatch) string {
+	if match == nil || (match.Service == nil && match.Method == nil) {
+		return ""
+	}
+	matchType := gatewayv1.GRPCMethodMatchExact
+	if match.Type != nil {
+		matchType = *match.Type
+	}
+	service, method := grpcNameRegex, grpcNameRegex
+	if match.Service != nil && *match.Service != "" {
+		service = *match.Service
+		if matchType == gatewayv1.GRPCMethodMatchExact {
+			service = regexp.QuoteMeta(service)
+		}
+	}
+	if match.Method != nil && *match.Method != "" {
+		method = *match.Method
+		if matchType == gatewayv1.GRPCMethodMatchExact {
+			method = regexp.QuoteMeta(method)
+		}
+	}
+	return "^/(" + service + ")/(" + method + ")$"
+}
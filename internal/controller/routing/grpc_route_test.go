@@ -0,0 +1,54 @@
+package routing
+
+import (
+	"testing"
+
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestMethodRegex(t *testing.T) {
+	tests := []struct {
+		name  string
+		match *gatewayv1.GRPCMethodMatch
+		want  string
+	}{
+		{
+			name:  "No match",
+			match: nil,
+			want:  "",
+		},
+		{
+			name:  "Exact service and method",
+			match: &gatewayv1.GRPCMethodMatch{Service: ptr.To("helloworld.Greeter"), Method: ptr.To("SayHello")},
+			want:  `^/(helloworld\.Greeter)/(SayHello)$`,
+		},
+		{
+			name:  "Exact service only",
+			match: &gatewayv1.GRPCMethodMatch{Service: ptr.To("helloworld.Greeter")},
+			want:  `^/(helloworld\.Greeter)/([^/]+)$`,
+		},
+		{
+			name:  "Exact method only",
+			match: &gatewayv1.GRPCMethodMatch{Method: ptr.To("SayHello")},
+			want:  `^/([^/]+)/(SayHello)$`,
+		},
+		{
+			name: "Regular expression",
+			match: &gatewayv1.GRPCMethodMatch{
+				Type:    ptr.To(gatewayv1.GRPCMethodMatchRegularExpression),
+				Service: ptr.To(`helloworld\..*`),
+				Method:  ptr.To("Say.*"),
+			},
+			want: `^/(helloworld\..*)/(Say.*)$`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MethodRegex(tt.match); got != tt.want {
+				t.Errorf("MethodRegex() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
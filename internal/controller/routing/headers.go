@@ -0,0 +1,78 @@
+package routing
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	headerOperationSet    = "set"
+	headerOperationAdd    = "add"
+	headerOperationRemove = "remove"
+)
+
+// headerModifier returns the header modifier filter of a rule that applies in phase, if it has one
+func headerModifier(rule Rule, phase cloudflare.RulesetPhase) *gatewayv1.HTTPHeaderFilter {
+	for _, filter := range rule.Filters {
+		switch {
+		case phase == RequestHeaderPhase && filter.Type == gatewayv1.HTTPRouteFilterRequestHeaderModifier:
+			return filter.RequestHeaderModifier
+		case phase == ResponseHeaderPhase && filter.Type == gatewayv1.HTTPRouteFilterResponseHeaderModifier:
+			return filter.ResponseHeaderModifier
+		}
+	}
+	return nil
+}
+
+// headerRules renders the request or response header modifier filters of the rules into the header transform
+// rules of phase, owned by owner. Transform rules don't stop at the first match, so each is scoped to exactly
+// the requests cloudflared hands to its ingress rule
+func headerRules(owner string, rules []Rule, phase cloudflare.RulesetPhase) []cloudflare.RulesetRule {
+	var transforms []cloudflare.RulesetRule
+	for i, rule := range rules {
+		modifier := headerModifier(rule, phase)
+		if modifier == nil || rule.Router {
+			continue
+		}
+		headers := headerOperations(modifier)
+		if len(headers) == 0 {
+			continue
+		}
+		kind := "request"
+		if phase == ResponseHeaderPhase {
+			kind = "response"
+		}
+		transforms = append(transforms, cloudflare.RulesetRule{
+			Ref:         cf.EdgeRuleRef(owner, fmt.Sprintf("%s/%d/%s/%s/%s-headers", rule.Route, rule.RuleIndex, rule.Ingress.Hostname, rule.Ingress.Path, kind)),
+			Description: fmt.Sprintf("%s rule %d: %s headers of %s", rule.Route, rule.RuleIndex, kind, rule.Ingress.Hostname),
+			Expression:  edgeExpression(rules, i),
+			Action:      string(cloudflare.RulesetRuleActionRewrite),
+			Enabled:     ptr.To(true),
+			ActionParameters: &cloudflare.RulesetRuleActionParameters{
+				Headers: headers,
+			},
+		})
+	}
+	return transforms
+}
+
+// headerOperations converts a header modifier into the header operations of a transform rule. Header names are
+// case-insensitive, and the Gateway API doesn't allow the same name in more than one list of a filter
+func headerOperations(modifier *gatewayv1.HTTPHeaderFilter) map[string]cloudflare.RulesetRuleActionParametersHTTPHeader {
+	headers := map[string]cloudflare.RulesetRuleActionParametersHTTPHeader{}
+	for _, header := range modifier.Set {
+		headers[strings.ToLower(string(header.Name))] = cloudflare.RulesetRuleActionParametersHTTPHeader{Operation: headerOperationSet, Value: header.Value}
+	}
+	for _, header := range modifier.Add {
+		headers[strings.ToLower(string(header.Name))] = cloudflare.RulesetRuleActionParametersHTTPHeader{Operation: headerOperationAdd, Value: header.Value}
+	}
+	for _, name := range modifier.Remove {
+		headers[strings.ToLower(name)] = cloudflare.RulesetRuleActionParametersHTTPHeader{Operation: headerOperationRemove}
+	}
+	return headers
+}
@@ -0,0 +1,86 @@
+package routing
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestHeaderRules(t *testing.T) {
+	route := Route{Kind: KindHTTPRoute, Namespace: "default", Name: "route"}
+	match := &gatewayv1.HTTPRouteMatch{
+		Path:    &gatewayv1.HTTPPathMatch{Type: ptr.To(gatewayv1.PathMatchExact), Value: ptr.To("/login")},
+		Headers: []gatewayv1.HTTPHeaderMatch{{Name: "X-Version", Value: "2"}},
+	}
+	rules := []Rule{
+		{
+			Ingress: cf.IngressConfig{Hostname: "a.example.com", Path: "^/login$", Service: "http://a.default.svc.cluster.local:80"},
+			Route:   route,
+			Match:   match,
+			Filters: []gatewayv1.HTTPRouteFilter{
+				{
+					Type: gatewayv1.HTTPRouteFilterRequestHeaderModifier,
+					RequestHeaderModifier: &gatewayv1.HTTPHeaderFilter{
+						Set:    []gatewayv1.HTTPHeader{{Name: "X-Forwarded-Prefix", Value: "/login"}},
+						Remove: []string{"X-Internal"},
+					},
+				},
+				{
+					Type: gatewayv1.HTTPRouteFilterResponseHeaderModifier,
+					ResponseHeaderModifier: &gatewayv1.HTTPHeaderFilter{
+						Add: []gatewayv1.HTTPHeader{{Name: "Strict-Transport-Security", Value: "max-age=31536000"}},
+					},
+				},
+			},
+		},
+		{
+			Ingress: cf.IngressConfig{Hostname: "a.example.com", Service: "http://a.default.svc.cluster.local:80"},
+			Route:   route,
+			Match:   &gatewayv1.HTTPRouteMatch{},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		phase          cloudflare.RulesetPhase
+		wantExpression string
+		wantHeaders    map[string]cloudflare.RulesetRuleActionParametersHTTPHeader
+	}{
+		{
+			name:           "Request headers",
+			phase:          RequestHeaderPhase,
+			wantExpression: `http.host eq "a.example.com" and http.request.uri.path eq "/login" and any(http.request.headers["x-version"][*] eq "2")`,
+			wantHeaders: map[string]cloudflare.RulesetRuleActionParametersHTTPHeader{
+				"x-forwarded-prefix": {Operation: "set", Value: "/login"},
+				"x-internal":         {Operation: "remove"},
+			},
+		},
+		{
+			name:           "Response headers",
+			phase:          ResponseHeaderPhase,
+			wantExpression: `http.host eq "a.example.com" and http.request.uri.path eq "/login" and any(http.request.headers["x-version"][*] eq "2")`,
+			wantHeaders: map[string]cloudflare.RulesetRuleActionParametersHTTPHeader{
+				"strict-transport-security": {Operation: "add", Value: "max-age=31536000"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := headerRules("owner-", rules, tt.phase)
+			if len(got) != 1 {
+				t.Fatalf("headerRules() returned %d rules, want 1", len(got))
+			}
+			if got[0].Expression != tt.wantExpression {
+				t.Errorf("expression = %s, want %s", got[0].Expression, tt.wantExpression)
+			}
+			if !reflect.DeepEqual(got[0].ActionParameters.Headers, tt.wantHeaders) {
+				t.Errorf("headers = %+v, want %+v", got[0].ActionParameters.Headers, tt.wantHeaders)
+			}
+		})
+	}
+}
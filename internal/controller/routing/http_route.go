@@ -0,0 +1,141 @@
+package routing
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	// NoBackendsService is served for rules without any backendRefs, the Gateway API asks for a 500 in that case
+	NoBackendsService = StaticResponsePrefix + "500"
+)
+
+// httpRouteRules renders one ingress rule per hostname and path match of every rule of the route. Rules whose
+// hostname and path are served by the router of the Gateway are handed to it as they are
+func (b *Builder) httpRouteRules(route *gatewayv1.HTTPRoute, listener gatewayv1.SectionName, hostnames []string) []Rule {
+	source := FromHTTPRoute(route)
+	var rules []Rule
+	for ruleIndex, rule := range route.Spec.Rules {
+		rulePath := field.NewPath("rules").Index(ruleIndex)
+		if errs := validateHTTPRouteRule(rulePath, rule); len(errs) > 0 {
+			for _, err := range errs {
+				b.unsupportedField(source, err.Error()+", the rule is not rendered")
+			}
+			continue
+		}
+		for _, err := range unsupportedHTTPRouteFilters(rulePath, rule) {
+			b.unsupportedField(source, err.Error())
+		}
+		if reasons := routerReasons(rule); len(reasons) > 0 && b.routerService == "" {
+			b.unsupportedField(source, fmt.Sprintf(
+				"rules[%d] needs the router of the Gateway, which is not enabled, for %s", ruleIndex, strings.Join(reasons, ", "),
+			))
+		}
+		if len(rule.BackendRefs) > 1 {
+			b.recordSplit(source, backendSplit(route.Namespace, ruleIndex, rule.BackendRefs, b.routerService != ""))
+		}
+		matches := rule.Matches
+		if len(matches) == 0 {
+			matches = []gatewayv1.HTTPRouteMatch{{}}
+		}
+		for _, hostname := range hostnames {
+			for i := range matches {
+				match := &matches[i]
+				routed := Rule{
+					Route:       source,
+					RuleIndex:   ruleIndex,
+					Listener:    listener,
+					Match:       match,
+					Filters:     rule.Filters,
+					BackendRefs: rule.BackendRefs,
+					Timeouts:    rule.Timeouts,
+				}
+				if b.routerClaims[claim{hostname: hostname, path: PathRegex(match.Path)}] {
+					routed.Router = true
+					routed.Ingress = cf.IngressConfig{Hostname: hostname, Path: PathRegex(match.Path), Service: b.routerService}
+				} else {
+					routed.Ingress, routed.Backend = b.httpIngress(source, ruleIndex, rule, match, hostname)
+				}
+				rules = append(rules, routed)
+			}
+		}
+	}
+	return rules
+}
+
+// httpIngress renders the ingress rule cloudflared sends the requests of a hostname and match of a rule with,
+// and returns the Service it sends them to
+func (b *Builder) httpIngress(
+	source Route,
+	ruleIndex int,
+	rule gatewayv1.HTTPRouteRule,
+	match *gatewayv1.HTTPRouteMatch,
+	hostname string,
+) (cf.IngressConfig, *types.NamespacedName) {
+	weighted := weightedBackendRefs(rule.BackendRefs)
+	service, originRequest := b.httpBackendService(source, weighted)
+	var backend *types.NamespacedName
+	if len(weighted) > 0 {
+		backend = b.permittedServiceKey(source, weighted[0].BackendObjectReference)
+	}
+	if !strings.HasPrefix(service, StaticResponsePrefix) {
+		originRequest = b.applyTimeouts(source, ruleIndex, rule.Timeouts, service, originRequest)
+	}
+	path := PathRegex(match.Path)
+	rewrite := urlRewrite(rule.Filters)
+	if rewrite != nil && rewrite.Hostname != nil {
+		originRequest = cf.MergeOriginRequest(originRequest, &cf.OriginRequestConfig{HTTPHostHeader: string(*rewrite.Hostname)})
+	}
+	if rewrite != nil && rewrite.Path != nil {
+		rewritten, ok := rewrittenPath(match, rewrite.Path)
+		if ok {
+			path = rewritten
+		} else {
+			b.unsupportedField(source, fmt.Sprintf("rules[%d].filters.urlRewrite.path.replacePrefixMatch needs a PathPrefix match", ruleIndex))
+		}
+	}
+	return cf.IngressConfig{
+		Hostname:      hostname,
+		Path:          path,
+		Service:       service,
+		OriginRequest: originRequest,
+	}, backend
+}
+
+// httpBackendService returns the cloudflared service for the first backend of a rule, rules without backends answer with a 500
+func (b *Builder) httpBackendService(source Route, backendRefs []gatewayv1.HTTPBackendRef) (string, *cf.OriginRequestConfig) {
+	if len(backendRefs) == 0 {
+		return NoBackendsService, nil
+	}
+	return b.backend(source, "http", backendRefs[0].BackendObjectReference)
+}
+
+// PathRegex converts a HTTPRoute path match into the regular expression cloudflared matches
+// request paths against. Matches on every path render as an empty string
+func PathRegex(match *gatewayv1.HTTPPathMatch) string {
+	if match == nil || match.Value == nil {
+		return ""
+	}
+	matchType := gatewayv1.PathMatchPathPrefix
+	if match.Type != nil {
+		matchType = *match.Type
+	}
+	switch matchType {
+	case gatewayv1.PathMatchExact:
+		return "^" + regexp.QuoteMeta(*match.Value) + "$"
+	case gatewayv1.PathMatchRegularExpression:
+		return *match.Value
+	default:
+		prefix := strings.TrimSuffix(*match.Value, "/")
+		if prefix == "" {
+			return ""
+		}
+		return "^" + regexp.QuoteMeta(prefix) + "(/.*)?$"
+	}
+}
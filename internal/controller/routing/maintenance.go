@@ -0,0 +1,109 @@
+package routing
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	cloudflarev1alpha1 "github.com/cyclingwithelephants/cloudflare-gateway-controller/api/v1alpha1"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/router"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	// AnnotationMaintenance puts the Gateway or route it is set on into maintenance while it is true: the requests
+	// of every rule are answered with the maintenance response of the CloudflaredConfig of the Gateway instead of
+	// their backends. Nothing else is changed, so removing the annotation renders the rules as they were
+	AnnotationMaintenance = "cloudflare.adamland.xyz/maintenance"
+)
+
+// helloWorldService is the cloudflared service of its test page
+const helloWorldService = "hello_world"
+
+// maintenanceResponse is what answers the requests of the rules in maintenance
+type maintenanceResponse struct {
+	// service is the cloudflared service of the response
+	service string
+	// host is the host of the page hosted outside the cluster the requests go to, empty for other responses
+	host string
+}
+
+// inMaintenance reports whether an object carries AnnotationMaintenance
+func inMaintenance(annotations map[string]string) bool {
+	enabled, _ := strconv.ParseBool(annotations[AnnotationMaintenance])
+	return enabled
+}
+
+// GatewayInMaintenance reports whether every route of a Gateway is in maintenance
+func GatewayInMaintenance(gateway *gatewayv1.Gateway) bool {
+	return inMaintenance(gateway.Annotations)
+}
+
+// MaintenanceService returns the cloudflared service answering the requests in maintenance of a Gateway
+func MaintenanceService(spec cloudflarev1alpha1.CloudflaredConfigSpec, namespace string) string {
+	return maintenance(spec, namespace).service
+}
+
+// maintenance returns what answers the requests in maintenance of a Gateway, a 503 unless its CloudflaredConfig
+// sets a maintenance response
+func maintenance(spec cloudflarev1alpha1.CloudflaredConfigSpec, namespace string) maintenanceResponse {
+	if spec.Maintenance != nil {
+		if spec.Maintenance.Page != nil {
+			if page, err := url.Parse(*spec.Maintenance.Page); err == nil && page.Host != "" {
+				return maintenanceResponse{service: "https://" + page.Host, host: page.Hostname()}
+			}
+		}
+		if service := BackendService(spec.Maintenance.Response, namespace); service != "" {
+			return maintenanceResponse{service: service}
+		}
+	}
+	return maintenanceResponse{service: StaticResponsePrefix + "503"}
+}
+
+// inMaintenance reports whether the rules of a route are in maintenance, as the route or its Gateway is
+func (b *Builder) inMaintenance(route Route) bool {
+	return inMaintenance(b.gateway.Annotations) || inMaintenance(route.Annotations)
+}
+
+// applyMaintenance swaps the service of a rule of a route in maintenance for the maintenance response. Rules
+// served by the router keep sending their requests to it, their backends are swapped in the router table
+func (b *Builder) applyMaintenance(rule Rule) Rule {
+	if !b.inMaintenance(rule.Route) {
+		return rule
+	}
+	b.maintenance[rule.Route.Key()] = true
+	if rule.Router {
+		return rule
+	}
+	rule.Ingress.Service = b.maintenanceResponse.service
+	rule.Ingress.OriginRequest = nil
+	if host := b.maintenanceResponse.host; host != "" {
+		rule.Ingress.OriginRequest = &cf.OriginRequestConfig{HTTPHostHeader: host, OriginServerName: host}
+	}
+	return rule
+}
+
+// maintenanceBackend returns the backend of the router table that answers the requests of a rule in maintenance,
+// and the filters of the rule that send them to a page hosted outside the cluster
+func (b *Builder) maintenanceBackend(route Route) (router.Backend, []gatewayv1.HTTPRouteFilter) {
+	response := b.maintenanceResponse
+	if code, ok := strings.CutPrefix(response.service, StaticResponsePrefix); ok {
+		statusCode, _ := strconv.Atoi(code)
+		return router.Backend{Weight: defaultBackendWeight, StatusCode: statusCode}, nil
+	}
+	if response.service == helloWorldService {
+		b.unsupportedField(route, "the router can't answer with the test page of cloudflared, its rules in maintenance answer with a 503")
+		return router.Backend{Weight: defaultBackendWeight, StatusCode: 503}, nil
+	}
+	backend := router.Backend{Weight: defaultBackendWeight, URL: response.service}
+	if response.host == "" {
+		return backend, nil
+	}
+	// the page is served for its own host
+	hostname := gatewayv1.PreciseHostname(response.host)
+	return backend, []gatewayv1.HTTPRouteFilter{{
+		Type:       gatewayv1.HTTPRouteFilterURLRewrite,
+		URLRewrite: &gatewayv1.HTTPURLRewriteFilter{Hostname: &hostname},
+	}}
+}
@@ -0,0 +1,201 @@
+package routing
+
+import (
+	"testing"
+
+	cloudflarev1alpha1 "github.com/cyclingwithelephants/cloudflare-gateway-controller/api/v1alpha1"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestMaintenance(t *testing.T) {
+	tests := []struct {
+		name        string
+		maintenance *cloudflarev1alpha1.Maintenance
+		want        maintenanceResponse
+	}{
+		{
+			name: "Unset",
+			want: maintenanceResponse{service: "http_status:503"},
+		},
+		{
+			name:        "Status code",
+			maintenance: &cloudflarev1alpha1.Maintenance{Response: &cloudflarev1alpha1.DefaultBackend{StatusCode: ptr.To(int32(502))}},
+			want:        maintenanceResponse{service: "http_status:502"},
+		},
+		{
+			name: "Maintenance Service",
+			maintenance: &cloudflarev1alpha1.Maintenance{Response: &cloudflarev1alpha1.DefaultBackend{
+				Service: &cloudflarev1alpha1.DefaultBackendService{Name: "maintenance", Port: 8080},
+			}},
+			want: maintenanceResponse{service: "http://maintenance.gateways.svc.cluster.local:8080"},
+		},
+		{
+			name:        "Page hosted outside the cluster",
+			maintenance: &cloudflarev1alpha1.Maintenance{Page: ptr.To("https://maintenance.pages.dev/")},
+			want:        maintenanceResponse{service: "https://maintenance.pages.dev", host: "maintenance.pages.dev"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := maintenance(cloudflarev1alpha1.CloudflaredConfigSpec{Maintenance: tt.maintenance}, "gateways")
+			if got != tt.want {
+				t.Errorf("maintenance() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyMaintenance(t *testing.T) {
+	route := Route{Kind: KindHTTPRoute, Namespace: "default", Name: "web"}
+	inMaintenance := route
+	inMaintenance.Annotations = map[string]string{AnnotationMaintenance: "true"}
+	rule := func(route Route, router bool) Rule {
+		return Rule{
+			Ingress: cf.IngressConfig{
+				Hostname:      "web.example.com",
+				Service:       "http://web.default.svc.cluster.local:80",
+				OriginRequest: &cf.OriginRequestConfig{HTTPHostHeader: "web.internal"},
+			},
+			Route:  route,
+			Router: router,
+		}
+	}
+	page := maintenanceResponse{service: "https://maintenance.pages.dev", host: "maintenance.pages.dev"}
+
+	tests := []struct {
+		name            string
+		gateway         map[string]string
+		response        maintenanceResponse
+		rule            Rule
+		wantService     string
+		wantOrigin      *cf.OriginRequestConfig
+		wantMaintenance bool
+	}{
+		{
+			name:        "Routes out of maintenance",
+			response:    maintenanceResponse{service: "http_status:503"},
+			rule:        rule(route, false),
+			wantService: "http://web.default.svc.cluster.local:80",
+			wantOrigin:  &cf.OriginRequestConfig{HTTPHostHeader: "web.internal"},
+		},
+		{
+			name:            "Route in maintenance",
+			response:        maintenanceResponse{service: "http_status:503"},
+			rule:            rule(inMaintenance, false),
+			wantService:     "http_status:503",
+			wantMaintenance: true,
+		},
+		{
+			name:            "Gateway in maintenance",
+			gateway:         map[string]string{AnnotationMaintenance: "true"},
+			response:        maintenanceResponse{service: "http_status:503"},
+			rule:            rule(route, false),
+			wantService:     "http_status:503",
+			wantMaintenance: true,
+		},
+		{
+			name:        "Annotations that are not true",
+			gateway:     map[string]string{AnnotationMaintenance: "false"},
+			response:    maintenanceResponse{service: "http_status:503"},
+			rule:        rule(route, false),
+			wantService: "http://web.default.svc.cluster.local:80",
+			wantOrigin:  &cf.OriginRequestConfig{HTTPHostHeader: "web.internal"},
+		},
+		{
+			name:            "Page hosted outside the cluster",
+			response:        page,
+			rule:            rule(inMaintenance, false),
+			wantService:     "https://maintenance.pages.dev",
+			wantOrigin:      &cf.OriginRequestConfig{HTTPHostHeader: "maintenance.pages.dev", OriginServerName: "maintenance.pages.dev"},
+			wantMaintenance: true,
+		},
+		{
+			name:            "Rules served by the router keep sending requests to it",
+			response:        maintenanceResponse{service: "http_status:503"},
+			rule:            rule(inMaintenance, true),
+			wantService:     "http://web.default.svc.cluster.local:80",
+			wantOrigin:      &cf.OriginRequestConfig{HTTPHostHeader: "web.internal"},
+			wantMaintenance: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Builder{
+				gateway:             &gatewayv1.Gateway{ObjectMeta: metav1.ObjectMeta{Annotations: tt.gateway}},
+				maintenanceResponse: tt.response,
+				maintenance:         map[RouteKey]bool{},
+			}
+			got := b.applyMaintenance(tt.rule)
+			if got.Ingress.Service != tt.wantService {
+				t.Errorf("applyMaintenance() service = %s, want %s", got.Ingress.Service, tt.wantService)
+			}
+			if (got.Ingress.OriginRequest == nil) != (tt.wantOrigin == nil) || (got.Ingress.OriginRequest != nil && *got.Ingress.OriginRequest != *tt.wantOrigin) {
+				t.Errorf("applyMaintenance() origin request = %+v, want %+v", got.Ingress.OriginRequest, tt.wantOrigin)
+			}
+			if b.maintenance[tt.rule.Route.Key()] != tt.wantMaintenance {
+				t.Errorf("maintenance = %v, want %v", b.maintenance[tt.rule.Route.Key()], tt.wantMaintenance)
+			}
+		})
+	}
+}
+
+func TestMaintenanceBackend(t *testing.T) {
+	route := Route{Kind: KindHTTPRoute, Namespace: "default", Name: "web"}
+
+	tests := []struct {
+		name            string
+		response        maintenanceResponse
+		wantStatusCode  int
+		wantURL         string
+		wantHostname    string
+		wantUnsupported bool
+	}{
+		{
+			name:           "Status code",
+			response:       maintenanceResponse{service: "http_status:503"},
+			wantStatusCode: 503,
+		},
+		{
+			name:     "Maintenance Service",
+			response: maintenanceResponse{service: "http://maintenance.gateways.svc.cluster.local:8080"},
+			wantURL:  "http://maintenance.gateways.svc.cluster.local:8080",
+		},
+		{
+			name:         "Page hosted outside the cluster",
+			response:     maintenanceResponse{service: "https://maintenance.pages.dev", host: "maintenance.pages.dev"},
+			wantURL:      "https://maintenance.pages.dev",
+			wantHostname: "maintenance.pages.dev",
+		},
+		{
+			name:            "Test page of cloudflared",
+			response:        maintenanceResponse{service: "hello_world"},
+			wantStatusCode:  503,
+			wantUnsupported: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Builder{maintenanceResponse: tt.response, unsupported: map[RouteKey][]string{}}
+			backend, filters := b.maintenanceBackend(route)
+			if backend.StatusCode != tt.wantStatusCode || backend.URL != tt.wantURL {
+				t.Errorf("maintenanceBackend() = %d %s, want %d %s", backend.StatusCode, backend.URL, tt.wantStatusCode, tt.wantURL)
+			}
+			hostname := ""
+			if len(filters) == 1 && filters[0].URLRewrite != nil && filters[0].URLRewrite.Hostname != nil {
+				hostname = string(*filters[0].URLRewrite.Hostname)
+			}
+			if hostname != tt.wantHostname {
+				t.Errorf("maintenanceBackend() rewrites the hostname to %q, want %q", hostname, tt.wantHostname)
+			}
+			if unsupported := b.unsupported[route.Key()]; (len(unsupported) > 0) != tt.wantUnsupported {
+				t.Errorf("unsupported = %v, want unsupported %v", unsupported, tt.wantUnsupported)
+			}
+		})
+	}
+}
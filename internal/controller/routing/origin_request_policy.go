@@ -0,0 +1,160 @@
+package routing
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	cloudflarev1alpha1 "github.com/cyclingwithelephants/cloudflare-gateway-controller/api/v1alpha1"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+)
+
+const (
+	KindService = "Service"
+)
+
+// policyTarget identifies an object a policy can target
+type policyTarget struct {
+	Kind string
+	types.NamespacedName
+}
+
+type originRequestPolicy struct {
+	key           PolicyKey
+	originRequest *cf.OriginRequestConfig
+}
+
+// originRequestPolicyIndex holds the OriginRequestPolicies of the cluster by their targets, oldest first
+type originRequestPolicyIndex struct {
+	byTarget map[policyTarget][]originRequestPolicy
+}
+
+func listOriginRequestPolicies(ctx context.Context, c client.Reader) ([]cloudflarev1alpha1.OriginRequestPolicy, error) {
+	policies := &cloudflarev1alpha1.OriginRequestPolicyList{}
+	if err := c.List(ctx, policies); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to list originRequestPolicies")
+	}
+	return policies.Items, nil
+}
+
+func loadOriginRequestPolicies(ctx context.Context, c client.Reader) (*originRequestPolicyIndex, error) {
+	policies, err := listOriginRequestPolicies(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(policies, func(i, j int) bool {
+		return objectLess(&policies[i], &policies[j])
+	})
+
+	index := &originRequestPolicyIndex{byTarget: map[policyTarget][]originRequestPolicy{}}
+	for _, policy := range policies {
+		compiled := originRequestPolicy{
+			key:           PolicyKey{Kind: KindOriginRequestPolicy, NamespacedName: types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name}},
+			originRequest: OriginRequestConfig(policy.Spec.OriginRequest),
+		}
+		for _, ref := range policy.Spec.TargetRefs {
+			if !supportedPolicyTarget(ref) {
+				continue
+			}
+			target := policyTarget{Kind: string(ref.Kind), NamespacedName: types.NamespacedName{Namespace: policy.Namespace, Name: string(ref.Name)}}
+			index.byTarget[target] = append(index.byTarget[target], compiled)
+		}
+	}
+	return index, nil
+}
+
+// supportedPolicyTarget reports whether a policy can target the object of ref, other targets are ignored
+func supportedPolicyTarget(ref gatewayv1alpha2.LocalPolicyTargetReference) bool {
+	switch {
+	case ref.Group == gatewayv1.GroupName && (ref.Kind == KindGateway || ref.Kind == KindHTTPRoute):
+		return true
+	case ref.Group == "" && ref.Kind == KindService:
+		return true
+	default:
+		return false
+	}
+}
+
+// originRequest merges the policies targeting target, the oldest policy winning every field it sets
+func (i *originRequestPolicyIndex) originRequest(target policyTarget) (*cf.OriginRequestConfig, []PolicyKey) {
+	policies := i.byTarget[target]
+	var merged *cf.OriginRequestConfig
+	keys := make([]PolicyKey, 0, len(policies))
+	for j := len(policies) - 1; j >= 0; j-- {
+		merged = cf.MergeOriginRequest(merged, policies[j].originRequest)
+		keys = append(keys, policies[j].key)
+	}
+	return merged, keys
+}
+
+// applyOriginRequestPolicies merges the policies that apply to a rule into its origin settings. Settings are
+// inherited from the Gateway, overridden by the HTTPRoute and then by the Service of the rule. Settings the
+// controller derives from Gateway API resources, such as BackendTLSPolicies and route timeouts, take precedence
+func (b *Builder) applyOriginRequestPolicies(rule Rule) *cf.OriginRequestConfig {
+	if strings.HasPrefix(rule.Ingress.Service, StaticResponsePrefix) {
+		return rule.Ingress.OriginRequest
+	}
+	targets := []policyTarget{{Kind: string(KindGateway), NamespacedName: types.NamespacedName{Namespace: b.gateway.Namespace, Name: b.gateway.Name}}}
+	if rule.Route.Kind == KindHTTPRoute {
+		targets = append(targets, policyTarget{Kind: string(KindHTTPRoute), NamespacedName: types.NamespacedName{Namespace: rule.Route.Namespace, Name: rule.Route.Name}})
+	}
+	if rule.Backend != nil {
+		targets = append(targets, policyTarget{Kind: KindService, NamespacedName: *rule.Backend})
+	}
+
+	var originRequest *cf.OriginRequestConfig
+	for _, target := range targets {
+		targetOriginRequest, policies := b.originRequestPolicies.originRequest(target)
+		originRequest = cf.MergeOriginRequest(originRequest, targetOriginRequest)
+		for _, policy := range policies {
+			b.policies[policy] = PolicyStatus{Reason: gatewayv1alpha2.PolicyReasonAccepted, Message: "policy is applied to the rules of this Gateway"}
+		}
+	}
+	return cf.MergeOriginRequest(originRequest, rule.Ingress.OriginRequest)
+}
+
+// OriginRequestConfig converts origin settings of the cloudflare API group into their cloudflared form
+func OriginRequestConfig(spec cloudflarev1alpha1.OriginRequest) *cf.OriginRequestConfig {
+	originRequest := &cf.OriginRequestConfig{
+		ConnectTimeout:         durationString(spec.ConnectTimeout),
+		TLSTimeout:             durationString(spec.TLSTimeout),
+		TCPKeepAlive:           durationString(spec.TCPKeepAlive),
+		NoHappyEyeballs:        spec.NoHappyEyeballs,
+		KeepAliveTimeout:       durationString(spec.KeepAliveTimeout),
+		HTTPHostHeader:         ptr.Deref(spec.HTTPHostHeader, ""),
+		OriginServerName:       ptr.Deref(spec.OriginServerName, ""),
+		MatchSNIToHost:         spec.MatchSNIToHost,
+		NoTLSVerify:            spec.NoTLSVerify,
+		DisableChunkedEncoding: spec.DisableChunkedEncoding,
+		HTTP2Origin:            spec.HTTP2Origin,
+		ProxyAddress:           ptr.Deref(spec.ProxyAddress, ""),
+	}
+	if spec.KeepAliveConnections != nil {
+		originRequest.KeepAliveConnections = ptr.To(int(*spec.KeepAliveConnections))
+	}
+	if spec.ProxyType != nil {
+		originRequest.ProxyType = ptr.To(string(*spec.ProxyType))
+	}
+	if spec.ProxyPort != nil {
+		originRequest.ProxyPort = ptr.To(int(*spec.ProxyPort))
+	}
+	return originRequest
+}
+
+func durationString(duration *metav1.Duration) string {
+	if duration == nil {
+		return ""
+	}
+	return duration.Duration.String()
+}
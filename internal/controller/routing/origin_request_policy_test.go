@@ -0,0 +1,93 @@
+package routing
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestApplyOriginRequestPolicies(t *testing.T) {
+	gateway := &gatewayv1.Gateway{}
+	gateway.Namespace, gateway.Name = "default", "gateway"
+	route := Route{Kind: KindHTTPRoute, Namespace: "default", Name: "route"}
+	backend := &types.NamespacedName{Namespace: "default", Name: "backend"}
+	policy := func(name string) PolicyKey {
+		return PolicyKey{Kind: KindOriginRequestPolicy, NamespacedName: types.NamespacedName{Namespace: "default", Name: name}}
+	}
+
+	index := &originRequestPolicyIndex{byTarget: map[policyTarget][]originRequestPolicy{
+		{Kind: string(KindGateway), NamespacedName: types.NamespacedName{Namespace: "default", Name: "gateway"}}: {
+			{key: policy("gateway"), originRequest: &cf.OriginRequestConfig{ConnectTimeout: "30s", KeepAliveTimeout: "1m", HTTPHostHeader: "gateway"}},
+		},
+		{Kind: string(KindHTTPRoute), NamespacedName: types.NamespacedName{Namespace: "default", Name: "route"}}: {
+			{key: policy("route-old"), originRequest: &cf.OriginRequestConfig{ConnectTimeout: "10s"}},
+			{key: policy("route-new"), originRequest: &cf.OriginRequestConfig{ConnectTimeout: "20s", KeepAliveTimeout: "2m"}},
+		},
+		{Kind: KindService, NamespacedName: *backend}: {
+			{key: policy("service"), originRequest: &cf.OriginRequestConfig{KeepAliveTimeout: "3m", NoTLSVerify: ptr.To(true)}},
+		},
+	}}
+
+	tests := []struct {
+		name         string
+		rule         Rule
+		want         *cf.OriginRequestConfig
+		wantPolicies int
+	}{
+		{
+			name: "Gateway policy only",
+			rule: Rule{
+				Ingress: cf.IngressConfig{Service: "tcp://backend.default.svc.cluster.local:22"},
+				Route:   Route{Kind: KindTCPRoute, Namespace: "default", Name: "route"},
+			},
+			want:         &cf.OriginRequestConfig{ConnectTimeout: "30s", KeepAliveTimeout: "1m", HTTPHostHeader: "gateway"},
+			wantPolicies: 1,
+		},
+		{
+			name: "Route and Service override the Gateway, the oldest policy wins",
+			rule: Rule{
+				Ingress: cf.IngressConfig{Service: "http://backend.default.svc.cluster.local:80"},
+				Route:   route,
+				Backend: backend,
+			},
+			want:         &cf.OriginRequestConfig{ConnectTimeout: "10s", KeepAliveTimeout: "3m", HTTPHostHeader: "gateway", NoTLSVerify: ptr.To(true)},
+			wantPolicies: 4,
+		},
+		{
+			name: "Generated settings win",
+			rule: Rule{
+				Ingress: cf.IngressConfig{
+					Service:       "https://backend.default.svc.cluster.local:443",
+					OriginRequest: &cf.OriginRequestConfig{ConnectTimeout: "5s", OriginServerName: "backend.example.com"},
+				},
+				Route: route,
+			},
+			want:         &cf.OriginRequestConfig{ConnectTimeout: "5s", KeepAliveTimeout: "2m", HTTPHostHeader: "gateway", OriginServerName: "backend.example.com"},
+			wantPolicies: 3,
+		},
+		{
+			name: "Static responses are left alone",
+			rule: Rule{
+				Ingress: cf.IngressConfig{Service: NoBackendsService},
+				Route:   route,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Builder{gateway: gateway, originRequestPolicies: index, policies: map[PolicyKey]PolicyStatus{}}
+			got := b.applyOriginRequestPolicies(tt.rule)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("applyOriginRequestPolicies() = %+v, want %+v", got, tt.want)
+			}
+			if len(b.policies) != tt.wantPolicies {
+				t.Errorf("applyOriginRequestPolicies() recorded %d policies, want %d", len(b.policies), tt.wantPolicies)
+			}
+		})
+	}
+}
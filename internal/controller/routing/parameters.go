@@ -0,0 +1,71 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+
+	cloudflarev1alpha1 "github.com/cyclingwithelephants/cloudflare-gateway-controller/api/v1alpha1"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/k8s"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	KindCloudflaredConfig = "CloudflaredConfig"
+)
+
+// LoadCloudflaredConfig returns the settings of the CloudflaredConfig referenced by spec.infrastructure.parametersRef
+// of the gateway, or empty settings when the gateway references none
+func LoadCloudflaredConfig(ctx context.Context, c client.Reader, gateway *gatewayv1.Gateway) (cloudflarev1alpha1.CloudflaredConfigSpec, error) {
+	if gateway.Spec.Infrastructure == nil || gateway.Spec.Infrastructure.ParametersRef == nil {
+		return cloudflarev1alpha1.CloudflaredConfigSpec{}, nil
+	}
+	ref := gateway.Spec.Infrastructure.ParametersRef
+	if string(ref.Group) != cloudflarev1alpha1.GroupVersion.Group || ref.Kind != KindCloudflaredConfig {
+		return cloudflarev1alpha1.CloudflaredConfigSpec{}, errors.Errorf(
+			"parametersRef must reference a %s of group %s, got %s of group %s",
+			KindCloudflaredConfig, cloudflarev1alpha1.GroupVersion.Group, ref.Kind, ref.Group,
+		)
+	}
+
+	config := &cloudflarev1alpha1.CloudflaredConfig{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: gateway.Namespace, Name: ref.Name}, config); err != nil {
+		if apierrors.IsNotFound(err) {
+			return cloudflarev1alpha1.CloudflaredConfigSpec{}, errors.Errorf("%s %s not found", KindCloudflaredConfig, ref.Name)
+		}
+		return cloudflarev1alpha1.CloudflaredConfigSpec{}, errors.Wrap(err, "failed to get cloudflaredConfig")
+	}
+	return config.Spec, nil
+}
+
+// BackendService returns the cloudflared service of a backend of a CloudflaredConfig, whose Service lives in the
+// namespace of the Gateway. It is empty when the backend is unset
+func BackendService(backend *cloudflarev1alpha1.DefaultBackend, namespace string) string {
+	switch {
+	case backend == nil:
+		return ""
+	case backend.StatusCode != nil:
+		return fmt.Sprintf("%s%d", StaticResponsePrefix, *backend.StatusCode)
+	case ptr.Deref(backend.HelloWorld, false):
+		return helloWorldService
+	case backend.Service != nil:
+		return fmt.Sprintf("http://%s:%d", k8s.ServiceHostname(backend.Service.Name, namespace), backend.Service.Port)
+	default:
+		return ""
+	}
+}
+
+// readinessGateService returns the cloudflared service answering the rules held back by the readiness gate of a
+// Gateway, empty when the Gateway has no readiness gate
+func readinessGateService(spec cloudflarev1alpha1.CloudflaredConfigSpec, namespace string) string {
+	if spec.ReadinessGate == nil {
+		return ""
+	}
+	if service := BackendService(spec.ReadinessGate.Response, namespace); service != "" {
+		return service
+	}
+	return StaticResponsePrefix + "503"
+}
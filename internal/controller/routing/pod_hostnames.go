@@ -0,0 +1,72 @@
+package routing
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/k8s"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// AnnotationPodHostnames publishes every ready pod behind the headless Service backends of the route it is set
+	// on at a hostname of its own, <pod hostname>.<route hostname>, next to the hostname of the route. Pods have a
+	// hostname behind a headless Service when they set spec.subdomain to its name, as the pods of StatefulSets do
+	AnnotationPodHostnames = "cloudflare.adamland.xyz/pod-hostnames"
+)
+
+// podHostnames reports whether a route asks for its pods to be published at hostnames of their own
+func podHostnames(route Route) bool {
+	enabled, _ := strconv.ParseBool(route.Annotations[AnnotationPodHostnames])
+	return enabled
+}
+
+// podHostnameRules returns a rule for each ready pod of the backend of a rule of a route with AnnotationPodHostnames,
+// which sends the requests for the hostname of the pod to the stable cluster DNS name of the pod
+func (b *Builder) podHostnameRules(rule Rule) []Rule {
+	if !podHostnames(rule.Route) || rule.Router || rule.Backend == nil {
+		return nil
+	}
+	if rule.Ingress.Hostname == "" || strings.HasPrefix(rule.Ingress.Hostname, "*.") {
+		b.unsupportedField(rule.Route, fmt.Sprintf("%s can't publish pods under the hostname %q, which is not a single hostname", AnnotationPodHostnames, rule.Ingress.Hostname))
+		return nil
+	}
+	service, ok := b.services[*rule.Backend]
+	if !ok {
+		// reported as an unresolved backendRef
+		return nil
+	}
+	if service.Spec.ClusterIP != corev1.ClusterIPNone {
+		b.unsupportedField(rule.Route, fmt.Sprintf("%s needs headless Services, service %s has a cluster IP", AnnotationPodHostnames, rule.Backend))
+		return nil
+	}
+	target, err := url.Parse(rule.Ingress.Service)
+	if err != nil || target.Host == "" {
+		// static responses have no pods
+		return nil
+	}
+
+	var rules []Rule
+	for _, pod := range b.readyPodHostnames(*rule.Backend) {
+		podRule := rule
+		podRule.Pod = pod
+		podRule.Ingress.Hostname = pod + "." + rule.Ingress.Hostname
+		podTarget := *target
+		podTarget.Host = k8s.PodHostname(pod, rule.Backend.Name, rule.Backend.Namespace)
+		if port := target.Port(); port != "" {
+			podTarget.Host = net.JoinHostPort(podTarget.Host, port)
+		}
+		podRule.Ingress.Service = podTarget.String()
+		if origin := rule.Ingress.OriginRequest; origin != nil && origin.OriginServerName == rule.Ingress.Hostname {
+			// the SNI of TLSRoutes follows the hostname of the pod
+			podOrigin := *origin
+			podOrigin.OriginServerName = podRule.Ingress.Hostname
+			podRule.Ingress.OriginRequest = &podOrigin
+		}
+		rules = append(rules, podRule)
+	}
+	return rules
+}
@@ -0,0 +1,125 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+)
+
+func TestPodHostnameRules(t *testing.T) {
+	annotated := Route{Kind: KindTCPRoute, Namespace: "default", Name: "kafka", Annotations: map[string]string{AnnotationPodHostnames: "true"}}
+	kafka := types.NamespacedName{Namespace: "default", Name: "kafka"}
+	web := types.NamespacedName{Namespace: "default", Name: "web"}
+	headless := testService("default", "kafka", corev1.ServicePort{Port: 9092})
+	headless.Spec.ClusterIP = corev1.ClusterIPNone
+	endpoint := func(hostname *string, ready *bool) discoveryv1.Endpoint {
+		return discoveryv1.Endpoint{Hostname: hostname, Conditions: discoveryv1.EndpointConditions{Ready: ready}}
+	}
+	b := &Builder{
+		services: testServices(headless, testService("default", "web", corev1.ServicePort{Port: 80})),
+		endpointSlices: map[types.NamespacedName][]discoveryv1.EndpointSlice{
+			kafka: {
+				{Endpoints: []discoveryv1.Endpoint{endpoint(ptr.To("kafka-1"), ptr.To(true)), endpoint(ptr.To("kafka-2"), ptr.To(false))}},
+				{Endpoints: []discoveryv1.Endpoint{endpoint(ptr.To("kafka-0"), nil), endpoint(nil, ptr.To(true))}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		rule            Rule
+		want            []cf.IngressConfig
+		wantUnsupported bool
+	}{
+		{
+			name: "Ready pods with a hostname",
+			rule: Rule{
+				Ingress: cf.IngressConfig{Hostname: "kafka.example.com", Service: "tcp://kafka.default.svc.cluster.local:9092"},
+				Route:   annotated,
+				Backend: &kafka,
+			},
+			want: []cf.IngressConfig{
+				{Hostname: "kafka-0.kafka.example.com", Service: "tcp://kafka-0.kafka.default.svc.cluster.local:9092"},
+				{Hostname: "kafka-1.kafka.example.com", Service: "tcp://kafka-1.kafka.default.svc.cluster.local:9092"},
+			},
+		},
+		{
+			name: "The SNI follows the hostname of the pod",
+			rule: Rule{
+				Ingress: cf.IngressConfig{
+					Hostname:      "kafka.example.com",
+					Service:       "https://kafka.default.svc.cluster.local:9092",
+					OriginRequest: &cf.OriginRequestConfig{OriginServerName: "kafka.example.com"},
+				},
+				Route:   annotated,
+				Backend: &kafka,
+			},
+			want: []cf.IngressConfig{
+				{
+					Hostname:      "kafka-0.kafka.example.com",
+					Service:       "https://kafka-0.kafka.default.svc.cluster.local:9092",
+					OriginRequest: &cf.OriginRequestConfig{OriginServerName: "kafka-0.kafka.example.com"},
+				},
+				{
+					Hostname:      "kafka-1.kafka.example.com",
+					Service:       "https://kafka-1.kafka.default.svc.cluster.local:9092",
+					OriginRequest: &cf.OriginRequestConfig{OriginServerName: "kafka-1.kafka.example.com"},
+				},
+			},
+		},
+		{
+			name: "Routes without the annotation",
+			rule: Rule{
+				Ingress: cf.IngressConfig{Hostname: "kafka.example.com", Service: "tcp://kafka.default.svc.cluster.local:9092"},
+				Route:   Route{Kind: KindTCPRoute, Namespace: "default", Name: "kafka"},
+				Backend: &kafka,
+			},
+		},
+		{
+			name: "Services with a cluster IP",
+			rule: Rule{
+				Ingress: cf.IngressConfig{Hostname: "web.example.com", Service: "http://web.default.svc.cluster.local:80"},
+				Route:   annotated,
+				Backend: &web,
+			},
+			wantUnsupported: true,
+		},
+		{
+			name: "Wildcard hostnames",
+			rule: Rule{
+				Ingress: cf.IngressConfig{Hostname: "*.example.com", Service: "tcp://kafka.default.svc.cluster.local:9092"},
+				Route:   annotated,
+				Backend: &kafka,
+			},
+			wantUnsupported: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b.unsupported = map[RouteKey][]string{}
+			got := b.podHostnameRules(tt.rule)
+			if len(got) != len(tt.want) {
+				t.Fatalf("podHostnameRules() returned %d rules, want %d", len(got), len(tt.want))
+			}
+			for i, want := range tt.want {
+				if got[i].Ingress.Hostname != want.Hostname || got[i].Ingress.Service != want.Service {
+					t.Errorf("rule %d = %s -> %s, want %s -> %s", i, got[i].Ingress.Hostname, got[i].Ingress.Service, want.Hostname, want.Service)
+				}
+				if want.OriginRequest != nil && (got[i].Ingress.OriginRequest == nil || got[i].Ingress.OriginRequest.OriginServerName != want.OriginRequest.OriginServerName) {
+					t.Errorf("rule %d origin request = %+v, want %+v", i, got[i].Ingress.OriginRequest, want.OriginRequest)
+				}
+			}
+			if unsupported := b.unsupported[tt.rule.Route.Key()]; (len(unsupported) > 0) != tt.wantUnsupported {
+				t.Errorf("unsupported = %v, want unsupported %v", unsupported, tt.wantUnsupported)
+			}
+		})
+	}
+	if tt := tests[1]; tt.rule.Ingress.OriginRequest.OriginServerName != "kafka.example.com" {
+		t.Errorf("the origin request of the rule was modified to %+v", tt.rule.Ingress.OriginRequest)
+	}
+}
@@ -0,0 +1,159 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+)
+
+const (
+	KindBackendTLSPolicy    = "BackendTLSPolicy"
+	KindOriginRequestPolicy = "OriginRequestPolicy"
+
+	// maxAncestors is the most ancestors the status of a policy can hold
+	maxAncestors = 16
+)
+
+// PolicyKey identifies a policy of any kind
+type PolicyKey struct {
+	Kind string
+	types.NamespacedName
+}
+
+// PolicyStatus is how a policy was applied to the rules of a Gateway
+type PolicyStatus struct {
+	Reason  gatewayv1alpha2.PolicyConditionReason
+	Message string
+}
+
+func (s PolicyStatus) Accepted() bool {
+	return s.Reason == gatewayv1alpha2.PolicyReasonAccepted
+}
+
+// objectLess orders objects by age, oldest first, then by namespace and name
+func objectLess(a client.Object, b client.Object) bool {
+	aCreated, bCreated := a.GetCreationTimestamp(), b.GetCreationTimestamp()
+	if !aCreated.Equal(&bCreated) {
+		return aCreated.Before(&bCreated)
+	}
+	if a.GetNamespace() != b.GetNamespace() {
+		return a.GetNamespace() < b.GetNamespace()
+	}
+	return a.GetName() < b.GetName()
+}
+
+func ancestorRef(gateway *gatewayv1.Gateway) gatewayv1.ParentReference {
+	group := gatewayv1.Group(gatewayv1.GroupName)
+	kind := KindGateway
+	namespace := gatewayv1.Namespace(gateway.Namespace)
+	return gatewayv1.ParentReference{
+		Group:     &group,
+		Kind:      &kind,
+		Namespace: &namespace,
+		Name:      gatewayv1.ObjectName(gateway.Name),
+	}
+}
+
+// SetPolicyAncestors replaces the ancestors this controller reports on a policy with the Gateways that apply it,
+// leaving the ancestors of other controllers alone
+func SetPolicyAncestors(
+	ctx context.Context,
+	c client.Reader,
+	policy PolicyKey,
+	status *gatewayv1alpha2.PolicyStatus,
+	generation int64,
+) error {
+	gateways, err := ManagedGateways(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	ancestors := []gatewayv1alpha2.PolicyAncestorStatus{}
+	var existing []gatewayv1alpha2.PolicyAncestorStatus
+	for _, ancestor := range status.Ancestors {
+		if ancestor.ControllerName != controller.Name {
+			ancestors = append(ancestors, ancestor)
+			continue
+		}
+		existing = append(existing, ancestor)
+	}
+
+	for i := range gateways {
+		gateway := &gateways[i]
+		result, err := NewBuilder(c, gateway).Build(ctx)
+		if err != nil {
+			return errors.Wrapf(err, "failed to build routing table of gateway %s", client.ObjectKeyFromObject(gateway))
+		}
+		applied, ok := result.Policy(policy)
+		if !ok {
+			continue
+		}
+		if len(ancestors) >= maxAncestors {
+			log.FromContext(ctx).Info(fmt.Sprintf("%s %s applies to more than %d ancestors, not reporting %s", policy.Kind, policy.NamespacedName, maxAncestors, client.ObjectKeyFromObject(gateway)))
+			continue
+		}
+
+		ref := ancestorRef(gateway)
+		ancestor := gatewayv1alpha2.PolicyAncestorStatus{
+			AncestorRef:    ref,
+			ControllerName: controller.Name,
+			Conditions:     []metav1.Condition{},
+		}
+		for _, previous := range existing {
+			if reflect.DeepEqual(previous.AncestorRef, ref) {
+				ancestor.Conditions = previous.Conditions
+			}
+		}
+		conditionStatus := metav1.ConditionTrue
+		if !applied.Accepted() {
+			conditionStatus = metav1.ConditionFalse
+		}
+		meta.SetStatusCondition(&ancestor.Conditions, metav1.Condition{
+			Type:               string(gatewayv1alpha2.PolicyConditionAccepted),
+			Status:             conditionStatus,
+			ObservedGeneration: generation,
+			Reason:             string(applied.Reason),
+			Message:            applied.Message,
+		})
+		ancestors = append(ancestors, ancestor)
+	}
+	status.Ancestors = ancestors
+	return nil
+}
+
+// EnqueuePolicies returns a handler that requeues every policy of the kind of list. Whether a policy applies
+// to a Gateway depends on every route of it, so any change to a Gateway or route requeues them all
+func EnqueuePolicies(c client.Reader, list client.ObjectList) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, _ client.Object) []reconcile.Request {
+		policies := list.DeepCopyObject().(client.ObjectList)
+		if err := c.List(ctx, policies); err != nil {
+			if !meta.IsNoMatchError(err) {
+				log.FromContext(ctx).Error(err, "failed to list policies")
+			}
+			return nil
+		}
+		var requests []reconcile.Request
+		if err := meta.EachListItem(policies, func(obj runtime.Object) error {
+			if policy, ok := obj.(client.Object); ok {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(policy)})
+			}
+			return nil
+		}); err != nil {
+			log.FromContext(ctx).Error(err, "failed to enqueue policies")
+		}
+		return requests
+	})
+}
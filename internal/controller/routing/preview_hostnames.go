@@ -0,0 +1,69 @@
+package routing
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// PreviewHostnameData is what the previewHostname template of a CloudflaredConfig is given
+type PreviewHostnameData struct {
+	Route     string
+	Namespace string
+	Gateway   string
+	Domain    string
+}
+
+// PreviewHostname generates a hostname from the previewHostname template of a CloudflaredConfig. The hostname
+// is lower cased and must be a valid hostname within the domain of the GatewayClass
+func PreviewHostname(text string, data PreviewHostnameData) (string, error) {
+	tmpl, err := template.New("previewHostname").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse previewHostname")
+	}
+	var generated strings.Builder
+	if err := tmpl.Execute(&generated, data); err != nil {
+		return "", errors.Wrap(err, "failed to generate preview hostname")
+	}
+	hostname := strings.ToLower(strings.TrimSpace(generated.String()))
+	if errs := validation.IsDNS1123Subdomain(hostname); len(errs) > 0 {
+		return "", errors.Errorf("preview hostname %q is invalid: %s", hostname, strings.Join(errs, ", "))
+	}
+	for _, label := range strings.Split(hostname, ".") {
+		if len(label) > validation.DNS1123LabelMaxLength {
+			return "", errors.Errorf("preview hostname %q has a label longer than %d characters", hostname, validation.DNS1123LabelMaxLength)
+		}
+	}
+	if !InDomain(hostname, data.Domain) {
+		return "", errors.Errorf("preview hostname %q is outside of the domain %s", hostname, data.Domain)
+	}
+	return hostname, nil
+}
+
+// routeHostnames returns the hostnames a route is served on through a listener. Routes without any get a preview
+// hostname when the Gateway generates them
+func (b *Builder) routeHostnames(route Route, listenerHostname *gatewayv1.Hostname, hostnames []gatewayv1.Hostname) []string {
+	if (listenerHostname != nil && *listenerHostname != "") || len(hostnames) > 0 || b.previewHostname == "" {
+		return b.inDomain(route, EffectiveHostnames(listenerHostname, hostnames))
+	}
+	if b.domain == "" {
+		// the Gateway reconciler reports invalid GatewayClass parameters
+		return nil
+	}
+	hostname, err := PreviewHostname(b.previewHostname, PreviewHostnameData{
+		Route:     route.Name,
+		Namespace: route.Namespace,
+		Gateway:   b.gateway.Name,
+		Domain:    b.domain,
+	})
+	if err != nil {
+		b.unsupportedField(route, fmt.Sprintf("no preview hostname could be generated: %s", err))
+		return nil
+	}
+	b.previews[route.Key()] = hostname
+	return []string{hostname}
+}
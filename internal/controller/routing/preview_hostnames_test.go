@@ -0,0 +1,113 @@
+package routing
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestPreviewHostname(t *testing.T) {
+	data := PreviewHostnameData{Route: "pr-42", Namespace: "Previews", Gateway: "public", Domain: "example.com"}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "Route and namespace",
+			template: "{{.Route}}-{{.Namespace}}.{{.Domain}}",
+			want:     "pr-42-previews.example.com",
+		},
+		{
+			name:     "Subdomain of the domain",
+			template: "{{.Route}}.{{.Gateway}}.{{.Domain}}",
+			want:     "pr-42.public.example.com",
+		},
+		{
+			name:     "Outside of the domain",
+			template: "{{.Route}}.example.org",
+			wantErr:  true,
+		},
+		{
+			name:     "Invalid hostname",
+			template: "{{.Route}}_{{.Namespace}}.{{.Domain}}",
+			wantErr:  true,
+		},
+		{
+			name:     "Unknown fields",
+			template: "{{.Branch}}.{{.Domain}}",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PreviewHostname(tt.template, data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PreviewHostname() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("PreviewHostname() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteHostnames(t *testing.T) {
+	route := Route{Kind: KindHTTPRoute, Namespace: "previews", Name: "pr-42"}
+	template := "{{.Route}}-{{.Namespace}}.{{.Domain}}"
+
+	tests := []struct {
+		name             string
+		template         string
+		listenerHostname *gatewayv1.Hostname
+		hostnames        []gatewayv1.Hostname
+		want             []string
+		wantPreview      string
+	}{
+		{
+			name:        "Routes without hostnames",
+			template:    template,
+			want:        []string{"pr-42-previews.example.com"},
+			wantPreview: "pr-42-previews.example.com",
+		},
+		{
+			name:      "Routes with hostnames",
+			template:  template,
+			hostnames: []gatewayv1.Hostname{"web.example.com"},
+			want:      []string{"web.example.com"},
+		},
+		{
+			name:             "Listeners with a hostname",
+			template:         template,
+			listenerHostname: ptr.To(gatewayv1.Hostname("web.example.com")),
+			want:             []string{"web.example.com"},
+		},
+		{
+			name: "Gateways without a template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Builder{
+				gateway:         &gatewayv1.Gateway{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "public"}},
+				domain:          "example.com",
+				previewHostname: tt.template,
+				previews:        map[RouteKey]string{},
+				unsupported:     map[RouteKey][]string{},
+			}
+			got := b.routeHostnames(route, tt.listenerHostname, tt.hostnames)
+			if len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+				t.Errorf("routeHostnames() = %v, want %v", got, tt.want)
+			}
+			if preview := b.previews[route.Key()]; preview != tt.wantPreview {
+				t.Errorf("preview hostname = %q, want %q", preview, tt.wantPreview)
+			}
+		})
+	}
+}
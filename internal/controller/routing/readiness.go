@@ -0,0 +1,110 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// hasReadyEndpoints reports whether a Service has at least one ready endpoint
+func (b *Builder) hasReadyEndpoints(service types.NamespacedName) bool {
+	for _, slice := range b.endpointSlices[service] {
+		for _, endpoint := range slice.Endpoints {
+			// endpoints of unknown readiness are ready, as for kube-proxy
+			if ptr.Deref(endpoint.Conditions.Ready, true) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// gateReadiness holds back a rule whose backend Service has no ready endpoints when the Gateway has a readiness
+// gate, answering its requests with the maintenance response of the gate instead
+func (b *Builder) gateReadiness(rule Rule) Rule {
+	if b.readinessGate == "" || rule.Router || rule.Backend == nil || strings.HasPrefix(rule.Ingress.Service, StaticResponsePrefix) {
+		return rule
+	}
+	service, ok := b.services[*rule.Backend]
+	if !ok || service.Spec.Type == corev1.ServiceTypeExternalName {
+		// missing Services are reported as unresolved backendRefs, ExternalName Services have no endpoints
+		return rule
+	}
+	if b.hasReadyEndpoints(*rule.Backend) {
+		return rule
+	}
+	b.waitingFor(rule.Route, fmt.Sprintf("waiting for service %s to have ready endpoints", rule.Backend))
+	rule.Ingress.Service = b.readinessGate
+	rule.Ingress.OriginRequest = nil
+	return rule
+}
+
+// waitingFor records a rule of a route held back by the readiness gate, once per Service
+func (b *Builder) waitingFor(route Route, message string) {
+	key := route.Key()
+	for _, existing := range b.waiting[key] {
+		if existing == message {
+			return
+		}
+	}
+	b.waiting[key] = append(b.waiting[key], message)
+}
+
+// hasReadinessGate reports whether a Gateway holds back the rules of Services without ready endpoints
+func hasReadinessGate(ctx context.Context, c client.Reader, key types.NamespacedName) bool {
+	gateway := &gatewayv1.Gateway{}
+	if err := c.Get(ctx, key, gateway); err != nil {
+		return false
+	}
+	spec, err := LoadCloudflaredConfig(ctx, c, gateway)
+	return err == nil && spec.ReadinessGate != nil
+}
+
+// gatedRoutes returns the routes with a parent Gateway that has a readiness gate
+func gatedRoutes(ctx context.Context, c client.Reader, routes []Route) []Route {
+	var gated []Route
+	for _, route := range routes {
+		for _, gateway := range ParentGateways(route) {
+			if hasReadinessGate(ctx, c, gateway) {
+				gated = append(gated, route)
+				break
+			}
+		}
+	}
+	return gated
+}
+
+// EnqueueEndpointSliceRoutes returns a handler that requeues the routes of a kind whose parent Gateways have a
+// readiness gate and that reference the Service of a changed EndpointSlice, so that their BackendsReady
+// condition follows the endpoints of the Service
+func EnqueueEndpointSliceRoutes(c client.Reader, kind gatewayv1.Kind) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		service, ok := sliceService(obj)
+		if !ok {
+			return nil
+		}
+		routes, err := routesOfService(ctx, c, service)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "failed to find routes referencing service")
+			return nil
+		}
+		var requests []reconcile.Request
+		for _, route := range gatedRoutes(ctx, c, routes) {
+			if route.Kind == kind {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Namespace: route.Namespace, Name: route.Name},
+				})
+			}
+		}
+		return requests
+	})
+}
@@ -0,0 +1,128 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+)
+
+func TestGateReadiness(t *testing.T) {
+	route := Route{Kind: KindHTTPRoute, Namespace: "default", Name: "web"}
+	ready := types.NamespacedName{Namespace: "default", Name: "ready"}
+	starting := types.NamespacedName{Namespace: "default", Name: "starting"}
+	unknown := types.NamespacedName{Namespace: "default", Name: "unknown"}
+	external := types.NamespacedName{Namespace: "default", Name: "external"}
+	missing := types.NamespacedName{Namespace: "default", Name: "missing"}
+	endpoint := func(ready *bool) discoveryv1.Endpoint {
+		return discoveryv1.Endpoint{Conditions: discoveryv1.EndpointConditions{Ready: ready}}
+	}
+	services := testServices(
+		testService("default", "ready", corev1.ServicePort{Port: 80}),
+		testService("default", "starting", corev1.ServicePort{Port: 80}),
+		testService("default", "unknown", corev1.ServicePort{Port: 80}),
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "external"},
+			Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeExternalName, ExternalName: "api.example.org"},
+		},
+	)
+	endpointSlices := map[types.NamespacedName][]discoveryv1.EndpointSlice{
+		ready:    {{Endpoints: []discoveryv1.Endpoint{endpoint(ptr.To(false))}}, {Endpoints: []discoveryv1.Endpoint{endpoint(ptr.To(true))}}},
+		starting: {{Endpoints: []discoveryv1.Endpoint{endpoint(ptr.To(false))}}},
+		unknown:  {{Endpoints: []discoveryv1.Endpoint{endpoint(nil)}}},
+	}
+	rule := func(backend *types.NamespacedName, service string) Rule {
+		return Rule{
+			Ingress: cf.IngressConfig{
+				Hostname:      "web.example.com",
+				Service:       service,
+				OriginRequest: &cf.OriginRequestConfig{HTTPHostHeader: "web.internal"},
+			},
+			Route:   route,
+			Backend: backend,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		gate        string
+		rule        Rule
+		wantService string
+		wantWaiting bool
+	}{
+		{
+			name:        "Service with ready endpoints",
+			gate:        "http_status:503",
+			rule:        rule(&ready, "http://ready.default.svc.cluster.local:80"),
+			wantService: "http://ready.default.svc.cluster.local:80",
+		},
+		{
+			name:        "Service without ready endpoints",
+			gate:        "http_status:503",
+			rule:        rule(&starting, "http://starting.default.svc.cluster.local:80"),
+			wantService: "http_status:503",
+			wantWaiting: true,
+		},
+		{
+			name:        "Endpoints of unknown readiness are ready",
+			gate:        "http_status:503",
+			rule:        rule(&unknown, "http://unknown.default.svc.cluster.local:80"),
+			wantService: "http://unknown.default.svc.cluster.local:80",
+		},
+		{
+			name:        "Maintenance Service",
+			gate:        "http://maintenance.default.svc.cluster.local:8080",
+			rule:        rule(&starting, "http://starting.default.svc.cluster.local:80"),
+			wantService: "http://maintenance.default.svc.cluster.local:8080",
+			wantWaiting: true,
+		},
+		{
+			name:        "Gateways without a readiness gate",
+			rule:        rule(&starting, "http://starting.default.svc.cluster.local:80"),
+			wantService: "http://starting.default.svc.cluster.local:80",
+		},
+		{
+			name:        "ExternalName Services have no endpoints",
+			gate:        "http_status:503",
+			rule:        rule(&external, "http://api.example.org:80"),
+			wantService: "http://api.example.org:80",
+		},
+		{
+			name:        "Missing Services",
+			gate:        "http_status:503",
+			rule:        rule(&missing, NoBackendsService),
+			wantService: NoBackendsService,
+		},
+		{
+			name:        "Rules without a backend",
+			gate:        "http_status:503",
+			rule:        rule(nil, "http_status:404"),
+			wantService: "http_status:404",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Builder{
+				readinessGate:  tt.gate,
+				services:       services,
+				endpointSlices: endpointSlices,
+				waiting:        map[RouteKey][]string{},
+			}
+			got := b.gateReadiness(tt.rule)
+			if got.Ingress.Service != tt.wantService {
+				t.Errorf("gateReadiness() service = %s, want %s", got.Ingress.Service, tt.wantService)
+			}
+			if tt.wantWaiting && got.Ingress.OriginRequest != nil {
+				t.Errorf("gateReadiness() origin request = %+v, want none", got.Ingress.OriginRequest)
+			}
+			if waiting := b.waiting[route.Key()]; (len(waiting) > 0) != tt.wantWaiting {
+				t.Errorf("waiting = %v, want waiting %v", waiting, tt.wantWaiting)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package routing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	defaultRedirectStatusCode = 302
+)
+
+// requestRedirect returns the RequestRedirect filter of a rule, if it has one
+func requestRedirect(rule Rule) *gatewayv1.HTTPRequestRedirectFilter {
+	for _, filter := range rule.Filters {
+		if filter.Type == gatewayv1.HTTPRouteFilterRequestRedirect && filter.RequestRedirect != nil {
+			return filter.RequestRedirect
+		}
+	}
+	return nil
+}
+
+// redirectRules renders the RequestRedirect filters of the rules into Single Redirect rules owned by owner.
+// cloudflared can't issue redirects, so they happen at the Cloudflare edge instead
+func (b *Builder) redirectRules(owner string, rules []Rule) []cloudflare.RulesetRule {
+	var redirects []cloudflare.RulesetRule
+	for i, rule := range rules {
+		redirect := requestRedirect(rule)
+		if redirect == nil || rule.Router {
+			continue
+		}
+		path, ok := redirectPath(rule, redirect.Path)
+		if !ok {
+			b.unsupportedField(rule.Route, fmt.Sprintf("rules[%d].filters.requestRedirect.path.replacePrefixMatch needs a PathPrefix match", rule.RuleIndex))
+			continue
+		}
+		expression := edgeExpression(rules, i)
+		key := fmt.Sprintf("%s/%d/%s/%s", rule.Route, rule.RuleIndex, rule.Ingress.Hostname, rule.Ingress.Path)
+		description := fmt.Sprintf("%s rule %d: redirect %s", rule.Route, rule.RuleIndex, rule.Ingress.Hostname)
+
+		if redirect.Scheme != nil {
+			// a redirect that only changes the scheme would loop for requests that already use it
+			if redirect.Hostname == nil && redirect.Path == nil && redirect.Port == nil {
+				expression = andExpression([]string{expression, schemeCondition(*redirect.Scheme, false)})
+			}
+			redirects = append(redirects, redirectRule(owner, key, description, expression, *redirect.Scheme, path, redirect))
+			continue
+		}
+		// the target URL keeps the scheme of the request, which the rules language can't insert into a URL
+		for _, scheme := range []string{"https", "http"} {
+			redirects = append(redirects, redirectRule(
+				owner,
+				key+"/"+scheme,
+				description,
+				andExpression([]string{expression, schemeCondition(scheme, true)}),
+				scheme,
+				path,
+				redirect,
+			))
+		}
+	}
+	return redirects
+}
+
+func redirectRule(
+	owner string,
+	key string,
+	description string,
+	expression string,
+	scheme string,
+	path string,
+	redirect *gatewayv1.HTTPRequestRedirectFilter,
+) cloudflare.RulesetRule {
+	host := "http.host"
+	if redirect.Hostname != nil {
+		host = cf.QuoteString(string(*redirect.Hostname))
+	}
+	parts := []string{cf.QuoteString(scheme + "://"), host}
+	if redirect.Port != nil && !isDefaultPort(scheme, int(*redirect.Port)) {
+		parts = append(parts, cf.QuoteString(":"+strconv.Itoa(int(*redirect.Port))))
+	}
+	parts = append(parts, path)
+
+	return cloudflare.RulesetRule{
+		Ref:         cf.EdgeRuleRef(owner, key),
+		Description: description,
+		Expression:  expression,
+		Action:      string(cloudflare.RulesetRuleActionRedirect),
+		Enabled:     ptr.To(true),
+		ActionParameters: &cloudflare.RulesetRuleActionParameters{
+			FromValue: &cloudflare.RulesetRuleActionParametersFromValue{
+				StatusCode: uint16(ptr.Deref(redirect.StatusCode, defaultRedirectStatusCode)),
+				TargetURL: cloudflare.RulesetRuleActionParametersTargetURL{
+					Expression: "concat(" + strings.Join(parts, ", ") + ")",
+				},
+				PreserveQueryString: ptr.To(true),
+			},
+		},
+	}
+}
+
+// redirectPath returns the expression of the path of the redirect target, reporting false when the
+// path modifier can't be applied to the match of the rule
+func redirectPath(rule Rule, modifier *gatewayv1.HTTPPathModifier) (string, bool) {
+	if modifier == nil {
+		return "http.request.uri.path", true
+	}
+	switch modifier.Type {
+	case gatewayv1.FullPathHTTPPathModifier:
+		return cf.QuoteString(ptr.Deref(modifier.ReplaceFullPath, "/")), true
+	case gatewayv1.PrefixMatchHTTPPathModifier:
+		prefix, ok := matchedPrefix(rule.Match)
+		if !ok {
+			return "", false
+		}
+		return prefixReplacement(prefix, ptr.Deref(modifier.ReplacePrefixMatch, "/")), true
+	default:
+		return "http.request.uri.path", true
+	}
+}
+
+// schemeCondition matches requests using scheme, or not using it when matching is false
+func schemeCondition(scheme string, matching bool) string {
+	if (scheme == "https") == matching {
+		return "ssl"
+	}
+	return "not ssl"
+}
+
+func isDefaultPort(scheme string, port int) bool {
+	return (scheme == "https" && port == 443) || (scheme == "http" && port == 80)
+}
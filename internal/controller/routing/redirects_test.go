@@ -0,0 +1,118 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestRedirectRules(t *testing.T) {
+	route := Route{Kind: KindHTTPRoute, Namespace: "default", Name: "route"}
+	prefix := func(value string) *gatewayv1.HTTPRouteMatch {
+		return &gatewayv1.HTTPRouteMatch{Path: &gatewayv1.HTTPPathMatch{Type: ptr.To(gatewayv1.PathMatchPathPrefix), Value: ptr.To(value)}}
+	}
+	redirect := func(filter gatewayv1.HTTPRequestRedirectFilter) []gatewayv1.HTTPRouteFilter {
+		return []gatewayv1.HTTPRouteFilter{{Type: gatewayv1.HTTPRouteFilterRequestRedirect, RequestRedirect: &filter}}
+	}
+
+	tests := []struct {
+		name            string
+		rules           []Rule
+		wantExpressions []string
+		wantTargets     []string
+		wantUnsupported int
+	}{
+		{
+			name: "No redirects",
+			rules: []Rule{
+				{Ingress: cf.IngressConfig{Hostname: "a.example.com", Service: "http://a.default.svc.cluster.local:80"}, Route: route, Match: prefix("/")},
+			},
+		},
+		{
+			name: "Scheme redirect only applies to the other scheme",
+			rules: []Rule{
+				{
+					Ingress: cf.IngressConfig{Hostname: "a.example.com", Service: NoBackendsService},
+					Route:   route,
+					Match:   prefix("/"),
+					Filters: redirect(gatewayv1.HTTPRequestRedirectFilter{Scheme: ptr.To("https"), StatusCode: ptr.To(301)}),
+				},
+			},
+			wantExpressions: []string{`http.host eq "a.example.com" and not ssl`},
+			wantTargets:     []string{`concat("https://", http.host, http.request.uri.path)`},
+		},
+		{
+			name: "Host redirect keeps the scheme and skips more specific rules",
+			rules: []Rule{
+				{Ingress: cf.IngressConfig{Hostname: "a.example.com", Path: "^/api(/.*)?$", Service: "http://api.default.svc.cluster.local:80"}, Route: route, Match: prefix("/api")},
+				{
+					Ingress: cf.IngressConfig{Hostname: "a.example.com", Service: NoBackendsService},
+					Route:   route,
+					Match:   prefix("/"),
+					Filters: redirect(gatewayv1.HTTPRequestRedirectFilter{Hostname: ptr.To(gatewayv1.PreciseHostname("b.example.com")), Port: ptr.To(gatewayv1.PortNumber(8443))}),
+				},
+			},
+			wantExpressions: []string{
+				`http.host eq "a.example.com" and not (http.host eq "a.example.com" and (http.request.uri.path eq "/api" or starts_with(http.request.uri.path, "/api/"))) and ssl`,
+				`http.host eq "a.example.com" and not (http.host eq "a.example.com" and (http.request.uri.path eq "/api" or starts_with(http.request.uri.path, "/api/"))) and not ssl`,
+			},
+			wantTargets: []string{
+				`concat("https://", "b.example.com", ":8443", http.request.uri.path)`,
+				`concat("http://", "b.example.com", ":8443", http.request.uri.path)`,
+			},
+		},
+		{
+			name: "Prefix replacement",
+			rules: []Rule{
+				{
+					Ingress: cf.IngressConfig{Hostname: "*.example.com", Path: "^/old(/.*)?$", Service: NoBackendsService},
+					Route:   route,
+					Match:   prefix("/old/"),
+					Filters: redirect(gatewayv1.HTTPRequestRedirectFilter{
+						Scheme: ptr.To("https"),
+						Path:   &gatewayv1.HTTPPathModifier{Type: gatewayv1.PrefixMatchHTTPPathModifier, ReplacePrefixMatch: ptr.To("/new")},
+					}),
+				},
+			},
+			wantExpressions: []string{`ends_with(http.host, ".example.com") and (http.request.uri.path eq "/old" or starts_with(http.request.uri.path, "/old/"))`},
+			wantTargets:     []string{`concat("https://", http.host, concat("/new", substring(http.request.uri.path, 4)))`},
+		},
+		{
+			name: "Prefix replacement of an exact match",
+			rules: []Rule{
+				{
+					Ingress: cf.IngressConfig{Hostname: "a.example.com", Path: "^/old$", Service: NoBackendsService},
+					Route:   route,
+					Match:   &gatewayv1.HTTPRouteMatch{Path: &gatewayv1.HTTPPathMatch{Type: ptr.To(gatewayv1.PathMatchExact), Value: ptr.To("/old")}},
+					Filters: redirect(gatewayv1.HTTPRequestRedirectFilter{
+						Path: &gatewayv1.HTTPPathModifier{Type: gatewayv1.PrefixMatchHTTPPathModifier, ReplacePrefixMatch: ptr.To("/new")},
+					}),
+				},
+			},
+			wantUnsupported: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Builder{unsupported: map[RouteKey][]string{}}
+			got := b.redirectRules("owner-", tt.rules)
+			if len(got) != len(tt.wantExpressions) {
+				t.Fatalf("redirectRules() returned %d rules, want %d", len(got), len(tt.wantExpressions))
+			}
+			for i, rule := range got {
+				if rule.Expression != tt.wantExpressions[i] {
+					t.Errorf("rule %d expression = %s, want %s", i, rule.Expression, tt.wantExpressions[i])
+				}
+				if target := rule.ActionParameters.FromValue.TargetURL.Expression; target != tt.wantTargets[i] {
+					t.Errorf("rule %d target = %s, want %s", i, target, tt.wantTargets[i])
+				}
+			}
+			if len(b.unsupported[route.Key()]) != tt.wantUnsupported {
+				t.Errorf("redirectRules() recorded %d unsupported fields, want %d", len(b.unsupported[route.Key()]), tt.wantUnsupported)
+			}
+		})
+	}
+}
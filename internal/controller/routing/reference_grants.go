@@ -0,0 +1,151 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+// referenceGrantIndex holds the ReferenceGrants of the cluster by the namespace they grant references into
+type referenceGrantIndex map[string][]gatewayv1beta1.ReferenceGrant
+
+// loadReferenceGrants returns every ReferenceGrant of the cluster, none when the CRD is not installed
+func loadReferenceGrants(ctx context.Context, c client.Reader) (referenceGrantIndex, error) {
+	grants := &gatewayv1beta1.ReferenceGrantList{}
+	if err := c.List(ctx, grants); err != nil {
+		if meta.IsNoMatchError(err) {
+			return referenceGrantIndex{}, nil
+		}
+		return nil, errors.Wrap(err, "failed to list referenceGrants")
+	}
+	index := referenceGrantIndex{}
+	for _, grant := range grants.Items {
+		index[grant.Namespace] = append(index[grant.Namespace], grant)
+	}
+	return index, nil
+}
+
+// allows reports whether a ReferenceGrant in the namespace of target lets routes of the kind and namespace of
+// route reference it. References within the namespace of the route need none
+func (i referenceGrantIndex) allows(route Route, group gatewayv1.Group, kind gatewayv1.Kind, target types.NamespacedName) bool {
+	if target.Namespace == route.Namespace {
+		return true
+	}
+	for _, grant := range i[target.Namespace] {
+		if grantsFrom(grant, route) && grantsTo(grant, group, kind, target.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func grantsFrom(grant gatewayv1beta1.ReferenceGrant, route Route) bool {
+	for _, from := range grant.Spec.From {
+		if from.Group == gatewayv1.GroupName && from.Kind == route.Kind && string(from.Namespace) == route.Namespace {
+			return true
+		}
+	}
+	return false
+}
+
+func grantsTo(grant gatewayv1beta1.ReferenceGrant, group gatewayv1.Group, kind gatewayv1.Kind, name string) bool {
+	for _, to := range grant.Spec.To {
+		if to.Group == group && to.Kind == kind && (ptr.Deref(to.Name, "") == "" || string(*to.Name) == name) {
+			return true
+		}
+	}
+	return false
+}
+
+// referencePermitted reports whether route may reference the backend of ref, recording on the route when it may not
+func (b *Builder) referencePermitted(route Route, ref gatewayv1.BackendObjectReference) bool {
+	target := types.NamespacedName{Namespace: route.Namespace, Name: string(ref.Name)}
+	if ref.Namespace != nil {
+		target.Namespace = string(*ref.Namespace)
+	}
+	group := ptr.Deref(ref.Group, "")
+	kind := ptr.Deref(ref.Kind, KindService)
+	if b.referenceGrants.allows(route, group, kind, target) {
+		return true
+	}
+	b.unresolvedRef(route, gatewayv1.RouteReasonRefNotPermitted, fmt.Sprintf(
+		"no ReferenceGrant in namespace %s allows %ss of namespace %s to reference %s %s", target.Namespace, route.Kind, route.Namespace, kindName(group, kind), target.Name,
+	))
+	return false
+}
+
+// permittedServiceKey returns the Service a backendRef points at, nil for backends of other kinds and for
+// Services the route may not reference
+func (b *Builder) permittedServiceKey(route Route, ref gatewayv1.BackendObjectReference) *types.NamespacedName {
+	service := serviceKey(route.Namespace, ref)
+	if service == nil || !b.referenceGrants.allows(route, "", KindService, *service) {
+		return nil
+	}
+	return service
+}
+
+// routesOfReferenceGrant returns the routes of the cluster with a backendRef into the namespace of a ReferenceGrant
+// from another namespace
+func routesOfReferenceGrant(ctx context.Context, c client.Reader, grant client.Object) ([]Route, error) {
+	routes, err := ListRoutes(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	var referencing []Route
+	for _, route := range routes {
+		if route.Namespace == grant.GetNamespace() {
+			continue
+		}
+		for _, backend := range route.Backends {
+			if backend.Namespace == grant.GetNamespace() {
+				referencing = append(referencing, route)
+				break
+			}
+		}
+	}
+	return referencing, nil
+}
+
+// EnqueueReferenceGrantRoutes returns a handler that requeues the routes of kind with a backendRef into the namespace
+// of a changed ReferenceGrant
+func EnqueueReferenceGrantRoutes(c client.Reader, kind gatewayv1.Kind) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		routes, err := routesOfReferenceGrant(ctx, c, obj)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "failed to find routes referencing the namespace of referenceGrant")
+			return nil
+		}
+		var requests []reconcile.Request
+		for _, route := range routes {
+			if route.Kind == kind {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Namespace: route.Namespace, Name: route.Name},
+				})
+			}
+		}
+		return requests
+	})
+}
+
+// EnqueueReferenceGrantGateways returns a handler that requeues the parent Gateways of the routes with a backendRef
+// into the namespace of a changed ReferenceGrant
+func EnqueueReferenceGrantGateways(c client.Reader) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		routes, err := routesOfReferenceGrant(ctx, c, obj)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "failed to find routes referencing the namespace of referenceGrant")
+			return nil
+		}
+		return parentGatewayRequests(routes)
+	})
+}
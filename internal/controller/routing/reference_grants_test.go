@@ -0,0 +1,139 @@
+package routing
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+// testReferenceGrant lets routes of a kind and namespace reference the Service name of namespace, every Service
+// of it when name is empty
+func testReferenceGrant(namespace string, fromKind gatewayv1.Kind, fromNamespace string, name string) gatewayv1beta1.ReferenceGrant {
+	to := gatewayv1beta1.ReferenceGrantTo{Kind: KindService}
+	if name != "" {
+		to.Name = ptr.To(gatewayv1.ObjectName(name))
+	}
+	return gatewayv1beta1.ReferenceGrant{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "grant"},
+		Spec: gatewayv1beta1.ReferenceGrantSpec{
+			From: []gatewayv1beta1.ReferenceGrantFrom{{Group: gatewayv1.GroupName, Kind: fromKind, Namespace: gatewayv1.Namespace(fromNamespace)}},
+			To:   []gatewayv1beta1.ReferenceGrantTo{to},
+		},
+	}
+}
+
+func testReferenceGrants(grants ...gatewayv1beta1.ReferenceGrant) referenceGrantIndex {
+	index := referenceGrantIndex{}
+	for _, grant := range grants {
+		index[grant.Namespace] = append(index[grant.Namespace], grant)
+	}
+	return index
+}
+
+func TestReferenceGrantIndexAllows(t *testing.T) {
+	route := Route{Kind: KindHTTPRoute, Namespace: "default", Name: "route"}
+	index := testReferenceGrants(
+		testReferenceGrant("shared", KindHTTPRoute, "default", ""),
+		testReferenceGrant("named", KindHTTPRoute, "default", "api"),
+		testReferenceGrant("grpc", KindGRPCRoute, "default", ""),
+		testReferenceGrant("elsewhere", KindHTTPRoute, "other", ""),
+	)
+
+	tests := []struct {
+		name   string
+		group  gatewayv1.Group
+		kind   gatewayv1.Kind
+		target types.NamespacedName
+		want   bool
+	}{
+		{
+			name:   "Same namespace",
+			kind:   KindService,
+			target: types.NamespacedName{Namespace: "default", Name: "web"},
+			want:   true,
+		},
+		{
+			name:   "Grant for every Service of the namespace",
+			kind:   KindService,
+			target: types.NamespacedName{Namespace: "shared", Name: "web"},
+			want:   true,
+		},
+		{
+			name:   "Grant for the named Service",
+			kind:   KindService,
+			target: types.NamespacedName{Namespace: "named", Name: "api"},
+			want:   true,
+		},
+		{
+			name:   "Grant for another Service",
+			kind:   KindService,
+			target: types.NamespacedName{Namespace: "named", Name: "web"},
+		},
+		{
+			name:   "Grant for another route kind",
+			kind:   KindService,
+			target: types.NamespacedName{Namespace: "grpc", Name: "web"},
+		},
+		{
+			name:   "Grant for another route namespace",
+			kind:   KindService,
+			target: types.NamespacedName{Namespace: "elsewhere", Name: "web"},
+		},
+		{
+			name:   "Grant for another backend kind",
+			group:  GroupMultiCluster,
+			kind:   KindServiceImport,
+			target: types.NamespacedName{Namespace: "shared", Name: "web"},
+		},
+		{
+			name:   "No grant",
+			kind:   KindService,
+			target: types.NamespacedName{Namespace: "private", Name: "web"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := index.allows(route, tt.group, tt.kind, tt.target); got != tt.want {
+				t.Errorf("allows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouterFiltersReferenceGrants(t *testing.T) {
+	route := Route{Kind: KindHTTPRoute, Namespace: "default", Name: "route"}
+	mirror := func(namespace string) gatewayv1.HTTPRouteFilter {
+		return gatewayv1.HTTPRouteFilter{
+			Type: gatewayv1.HTTPRouteFilterRequestMirror,
+			RequestMirror: &gatewayv1.HTTPRequestMirrorFilter{BackendRef: gatewayv1.BackendObjectReference{
+				Name:      "shadow",
+				Namespace: ptr.To(gatewayv1.Namespace(namespace)),
+				Port:      ptr.To(gatewayv1.PortNumber(8080)),
+			}},
+		}
+	}
+	b := &Builder{
+		services: testServices(
+			testService("granted", "shadow", corev1.ServicePort{Port: 8080}),
+			testService("private", "shadow", corev1.ServicePort{Port: 8080}),
+		),
+		referenceGrants: testReferenceGrants(testReferenceGrant("granted", KindHTTPRoute, "default", "shadow")),
+		unsupported:     map[RouteKey][]string{},
+		unresolved:      map[RouteKey][]UnresolvedRef{},
+	}
+
+	_, mirrors := b.routerFilters(route, []gatewayv1.HTTPRouteFilter{mirror("granted"), mirror("private")})
+	if len(mirrors) != 1 || mirrors[0].URL != "http://shadow.granted.svc.cluster.local:8080" {
+		t.Errorf("mirrors = %+v, want only the mirror to the granted namespace", mirrors)
+	}
+	unresolved := b.unresolved[route.Key()]
+	if len(unresolved) != 1 || unresolved[0].Reason != gatewayv1.RouteReasonRefNotPermitted {
+		t.Errorf("unresolved = %v, want the mirror to the private namespace not permitted", unresolved)
+	}
+}
@@ -0,0 +1,81 @@
+package routing
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// StatusSetter sets the parent statuses of routes of any kind. It caches the routing table of
+// every Gateway it builds, so it is meant to live for a single reconciliation loop
+type StatusSetter struct {
+	client  client.Reader
+	results map[types.NamespacedName]*Result
+}
+
+func NewStatusSetter(c client.Reader) *StatusSetter {
+	return &StatusSetter{
+		client:  c,
+		results: map[types.NamespacedName]*Result{},
+	}
+}
+
+func (s *StatusSetter) buildResult(ctx context.Context, gateway *gatewayv1.Gateway) (*Result, error) {
+	key := types.NamespacedName{Namespace: gateway.Namespace, Name: gateway.Name}
+	if result, ok := s.results[key]; ok {
+		return result, nil
+	}
+	result, err := NewBuilder(s.client, gateway).Build(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build routing table of gateway %s", key)
+	}
+	s.results[key] = result
+	return result, nil
+}
+
+// SetParentStatuses sets the status of every parentRef of route that points at a Gateway of this controller, and
+// drops the statuses of this controller for parents the route no longer references. It returns false when none
+// of the parents are managed by this controller and there was no status of it to drop
+func (s *StatusSetter) SetParentStatuses(
+	ctx context.Context,
+	route Route,
+	status *gatewayv1.RouteStatus,
+	generation int64,
+) (bool, error) {
+	isMine := false
+	var managed []gatewayv1.ParentReference
+	for _, ref := range route.ParentRefs {
+		gateway, err := ManagedGateway(ctx, s.client, ref, route.Namespace)
+		if err != nil {
+			return false, err
+		}
+		if gateway == nil {
+			continue
+		}
+		isMine = true
+		managed = append(managed, ref)
+
+		attachment, err := Attach(ctx, s.client, gateway, route, ref)
+		if err != nil {
+			return false, errors.Wrap(err, "failed to attach route")
+		}
+		result, err := s.buildResult(ctx, gateway)
+		if err != nil {
+			return false, err
+		}
+
+		parent := ParentStatus(&status.Parents, ref)
+		SetAccepted(parent, generation, attachment, result.RenderedRules(route), result.Conflicts(route))
+		SetUnsupported(parent, generation, result.Unsupported(route))
+		SetTrafficSplit(parent, generation, result.Splits(route))
+		SetResolvedRefs(parent, generation, result.UnresolvedRefs(route))
+		SetBackendsReady(parent, generation, result.ReadinessGate(), result.Waiting(route))
+		SetMaintenance(parent, generation, result.Maintenance(route))
+		SetPreviewHostname(parent, generation, result.PreviewHostname(route))
+	}
+	removed := removeStaleParents(&status.Parents, managed)
+	return isMine || removed, nil
+}
@@ -0,0 +1,246 @@
+package routing
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/router"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// cloudflared only routes requests by their hostname and path, and to a single backend. Gateways can run a
+// router between cloudflared and their Services which implements the rest of HTTPRoute. cloudflared hands it
+// every request of a hostname and path that a rule needing the router claims, so that the router sees all the
+// rules those requests could match
+
+const (
+	defaultBackendWeight = 1
+)
+
+// attachedHTTPRoute is a HTTPRoute attached to a listener of the Gateway
+type attachedHTTPRoute struct {
+	route     *gatewayv1.HTTPRoute
+	listener  gatewayv1.SectionName
+	hostnames []string
+}
+
+// routerReasons returns the parts of a HTTPRoute rule that only the router can implement
+func routerReasons(rule gatewayv1.HTTPRouteRule) []string {
+	var reasons []string
+	for _, match := range rule.Matches {
+		if len(match.Headers) > 0 {
+			reasons = append(reasons, "header matches")
+			break
+		}
+	}
+	for _, match := range rule.Matches {
+		if len(match.QueryParams) > 0 {
+			reasons = append(reasons, "query parameter matches")
+			break
+		}
+	}
+	for _, match := range rule.Matches {
+		if match.Method != nil {
+			reasons = append(reasons, "method matches")
+			break
+		}
+	}
+	if len(weightedBackendRefs(rule.BackendRefs)) > 1 {
+		reasons = append(reasons, "weighted backendRefs")
+	}
+	for _, filter := range rule.Filters {
+		if filter.Type == gatewayv1.HTTPRouteFilterRequestMirror {
+			reasons = append(reasons, "requestMirror filters")
+			break
+		}
+	}
+	for _, ref := range rule.BackendRefs {
+		if len(ref.Filters) > 0 {
+			reasons = append(reasons, "backendRef filters")
+			break
+		}
+	}
+	return reasons
+}
+
+// claimRouter returns the hostnames and paths of the rules that need the router, nil when the Gateway has none
+func (b *Builder) claimRouter(attached []attachedHTTPRoute) map[claim]bool {
+	if b.routerService == "" {
+		return nil
+	}
+	claims := map[claim]bool{}
+	for _, a := range attached {
+		for ruleIndex, rule := range a.route.Spec.Rules {
+			if len(routerReasons(rule)) == 0 || len(validateHTTPRouteRule(field.NewPath("rules").Index(ruleIndex), rule)) > 0 {
+				continue
+			}
+			matches := rule.Matches
+			if len(matches) == 0 {
+				matches = []gatewayv1.HTTPRouteMatch{{}}
+			}
+			for _, hostname := range a.hostnames {
+				for i := range matches {
+					claims[claim{hostname: hostname, path: PathRegex(matches[i].Path)}] = true
+				}
+			}
+		}
+	}
+	return claims
+}
+
+// routerTable compiles the rules served by the router into its routing table, in the order of precedence
+// the Gateway API defines for HTTPRoute matches
+func (b *Builder) routerTable(rules []Rule) *router.Table {
+	sorted := make([]Rule, len(rules))
+	copy(sorted, rules)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return matchPrecedes(sorted[i], sorted[j])
+	})
+
+	table := &router.Table{Rules: []router.Rule{}}
+	for _, rule := range sorted {
+		routed := router.Rule{
+			Name:     fmt.Sprintf("%s rule %d", rule.Route, rule.RuleIndex),
+			Hostname: rule.Ingress.Hostname,
+			Match:    *rule.Match,
+			Timeouts: rule.Timeouts,
+		}
+		if b.maintenance[rule.Route.Key()] {
+			// the filters of the rule are left out with its backends, they are restored with them
+			backend, filters := b.maintenanceBackend(rule.Route)
+			routed.Backends, routed.Filters = []router.Backend{backend}, filters
+			table.Rules = append(table.Rules, routed)
+			continue
+		}
+		routed.Filters, routed.Mirrors = b.routerFilters(rule.Route, rule.Filters)
+		if rewrite := urlRewrite(rule.Filters); rewrite != nil && rewrite.Path != nil && rewrite.Path.Type == gatewayv1.PrefixMatchHTTPPathModifier {
+			if _, ok := matchedPrefix(rule.Match); !ok {
+				b.unsupportedField(rule.Route, fmt.Sprintf("rules[%d].filters.urlRewrite.path.replacePrefixMatch needs a PathPrefix match", rule.RuleIndex))
+			}
+		}
+		for i, ref := range rule.BackendRefs {
+			if ptr.Deref(ref.Weight, defaultBackendWeight) <= 0 {
+				// backendRefs with a weight of zero never receive requests
+				continue
+			}
+			// backendRefs that don't resolve keep their share of requests, which the router answers with a 500
+			url, _ := b.backendURL(rule.Route, "http", ref.BackendObjectReference)
+			backend := router.Backend{Weight: ptr.Deref(ref.Weight, defaultBackendWeight)}
+			if code, ok := strings.CutPrefix(url, StaticResponsePrefix); ok {
+				backend.StatusCode, _ = strconv.Atoi(code)
+			} else {
+				backend.URL = url
+			}
+			if service := b.permittedServiceKey(rule.Route, ref.BackendObjectReference); service != nil {
+				if _, ok := b.backendTLS.byService[*service]; ok {
+					b.unsupportedField(rule.Route, fmt.Sprintf("rules[%d].backendRefs[%d] is targeted by a BackendTLSPolicy, which the router does not implement", rule.RuleIndex, i))
+				}
+			}
+			backend.Filters, backend.Mirrors = b.routerFilters(rule.Route, ref.Filters)
+			routed.Backends = append(routed.Backends, backend)
+		}
+		table.Rules = append(table.Rules, routed)
+	}
+	return table
+}
+
+// routerFilters resolves the backends of the RequestMirror filters of filters, returning the other filters as they are
+func (b *Builder) routerFilters(route Route, filters []gatewayv1.HTTPRouteFilter) ([]gatewayv1.HTTPRouteFilter, []router.Mirror) {
+	var kept []gatewayv1.HTTPRouteFilter
+	var mirrors []router.Mirror
+	for _, filter := range filters {
+		if filter.Type != gatewayv1.HTTPRouteFilterRequestMirror {
+			kept = append(kept, filter)
+			continue
+		}
+		if filter.RequestMirror == nil {
+			continue
+		}
+		url, ok := b.backendURL(route, "http", filter.RequestMirror.BackendRef)
+		if !ok {
+			continue
+		}
+		if strings.HasPrefix(url, StaticResponsePrefix) {
+			b.unsupportedField(route, "requestMirror filters can't mirror requests to a StaticResponse")
+			continue
+		}
+		mirrors = append(mirrors, router.Mirror{
+			URL:      url,
+			Fraction: mirrorFraction(filter.RequestMirror),
+		})
+	}
+	return kept, mirrors
+}
+
+// mirrorFraction returns the share of requests a RequestMirror filter mirrors
+func mirrorFraction(mirror *gatewayv1.HTTPRequestMirrorFilter) float64 {
+	switch {
+	case mirror.Percent != nil:
+		return float64(*mirror.Percent) / 100
+	case mirror.Fraction != nil:
+		denominator := ptr.Deref(mirror.Fraction.Denominator, 100)
+		if denominator == 0 {
+			return 0
+		}
+		return float64(mirror.Fraction.Numerator) / float64(denominator)
+	default:
+		return 1
+	}
+}
+
+// matchPrecedes orders rules by the precedence of their matches: the most specific hostname, exact paths,
+// then the longest path prefix, method matches, the most header and then query parameter matches, and finally
+// the oldest route and its first rule
+func matchPrecedes(a Rule, b Rule) bool {
+	if ha, hb := hostnameRank(a.Ingress.Hostname), hostnameRank(b.Ingress.Hostname); ha != hb {
+		return ha > hb
+	}
+	if pa, pb := pathRank(a.Match.Path), pathRank(b.Match.Path); pa != pb {
+		return pa > pb
+	}
+	if (a.Match.Method != nil) != (b.Match.Method != nil) {
+		return a.Match.Method != nil
+	}
+	if len(a.Match.Headers) != len(b.Match.Headers) {
+		return len(a.Match.Headers) > len(b.Match.Headers)
+	}
+	if len(a.Match.QueryParams) != len(b.Match.QueryParams) {
+		return len(a.Match.QueryParams) > len(b.Match.QueryParams)
+	}
+	if a.Route.Key() != b.Route.Key() {
+		return routeLess(a.Route, b.Route)
+	}
+	return a.RuleIndex < b.RuleIndex
+}
+
+// hostnameRank ranks exact hostnames above wildcards, and longer wildcards above shorter ones
+func hostnameRank(hostname string) int {
+	switch {
+	case hostname == "":
+		return 0
+	case strings.HasPrefix(hostname, "*."):
+		return len(hostname)
+	default:
+		// above every wildcard, which are at most 253 characters long
+		return 1000
+	}
+}
+
+// pathRank ranks exact paths above regular expressions, and those above prefixes, with longer prefixes first
+func pathRank(match *gatewayv1.HTTPPathMatch) int {
+	if match == nil || match.Value == nil {
+		return 0
+	}
+	switch ptr.Deref(match.Type, gatewayv1.PathMatchPathPrefix) {
+	case gatewayv1.PathMatchExact:
+		return 3000
+	case gatewayv1.PathMatchRegularExpression:
+		return 2000
+	default:
+		return len(strings.TrimSuffix(*match.Value, "/"))
+	}
+}
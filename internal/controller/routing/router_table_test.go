@@ -0,0 +1,167 @@
+package routing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestRouterTable(t *testing.T) {
+	older := Route{Kind: KindHTTPRoute, Namespace: "default", Name: "old", CreationTimestamp: metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))}
+	newer := Route{Kind: KindHTTPRoute, Namespace: "default", Name: "new", CreationTimestamp: metav1.NewTime(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))}
+	backend := func(name string, weight *int32) gatewayv1.HTTPBackendRef {
+		return gatewayv1.HTTPBackendRef{BackendRef: gatewayv1.BackendRef{
+			BackendObjectReference: gatewayv1.BackendObjectReference{Name: gatewayv1.ObjectName(name), Port: ptr.To(gatewayv1.PortNumber(80))},
+			Weight:                 weight,
+		}}
+	}
+	rule := func(route Route, hostname string, match gatewayv1.HTTPRouteMatch, backendRefs ...gatewayv1.HTTPBackendRef) Rule {
+		return Rule{
+			Ingress:     cf.IngressConfig{Hostname: hostname},
+			Route:       route,
+			Match:       &match,
+			BackendRefs: backendRefs,
+			Router:      true,
+		}
+	}
+	prefix := func(value string) *gatewayv1.HTTPPathMatch {
+		return &gatewayv1.HTTPPathMatch{Type: ptr.To(gatewayv1.PathMatchPathPrefix), Value: ptr.To(value)}
+	}
+	header := []gatewayv1.HTTPHeaderMatch{{Name: "x-canary", Value: "true"}}
+
+	tests := []struct {
+		name      string
+		rules     []Rule
+		wantNames []string
+	}{
+		{
+			name: "Header matches precede rules without them",
+			rules: []Rule{
+				rule(older, "a.example.com", gatewayv1.HTTPRouteMatch{Path: prefix("/")}, backend("stable", nil)),
+				rule(newer, "a.example.com", gatewayv1.HTTPRouteMatch{Path: prefix("/"), Headers: header}, backend("canary", nil)),
+			},
+			wantNames: []string{"HTTPRoute default/new rule 0", "HTTPRoute default/old rule 0"},
+		},
+		{
+			name: "Longer prefixes precede methods",
+			rules: []Rule{
+				rule(older, "a.example.com", gatewayv1.HTTPRouteMatch{Path: prefix("/"), Method: ptr.To(gatewayv1.HTTPMethodPost)}),
+				rule(newer, "a.example.com", gatewayv1.HTTPRouteMatch{Path: prefix("/api")}),
+			},
+			wantNames: []string{"HTTPRoute default/new rule 0", "HTTPRoute default/old rule 0"},
+		},
+		{
+			name: "Exact hostnames precede wildcards",
+			rules: []Rule{
+				rule(older, "*.example.com", gatewayv1.HTTPRouteMatch{Path: prefix("/api")}),
+				rule(newer, "a.example.com", gatewayv1.HTTPRouteMatch{Path: prefix("/")}),
+			},
+			wantNames: []string{"HTTPRoute default/new rule 0", "HTTPRoute default/old rule 0"},
+		},
+		{
+			name: "The oldest route breaks ties",
+			rules: []Rule{
+				rule(newer, "a.example.com", gatewayv1.HTTPRouteMatch{Path: prefix("/")}),
+				rule(older, "a.example.com", gatewayv1.HTTPRouteMatch{Path: prefix("/")}),
+			},
+			wantNames: []string{"HTTPRoute default/old rule 0", "HTTPRoute default/new rule 0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Builder{backendTLS: &backendTLSIndex{}, unsupported: map[RouteKey][]string{}, unresolved: map[RouteKey][]UnresolvedRef{}}
+			table := b.routerTable(tt.rules)
+			if len(table.Rules) != len(tt.wantNames) {
+				t.Fatalf("routerTable() returned %d rules, want %d", len(table.Rules), len(tt.wantNames))
+			}
+			for i, want := range tt.wantNames {
+				if got := table.Rules[i].Name; got != want {
+					t.Errorf("rule %d = %s, want %s", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRouterTableBackends(t *testing.T) {
+	route := Route{Kind: KindHTTPRoute, Namespace: "default", Name: "api"}
+	rule := Rule{
+		Ingress: cf.IngressConfig{Hostname: "a.example.com"},
+		Route:   route,
+		Match:   &gatewayv1.HTTPRouteMatch{},
+		Filters: []gatewayv1.HTTPRouteFilter{{
+			Type: gatewayv1.HTTPRouteFilterRequestMirror,
+			RequestMirror: &gatewayv1.HTTPRequestMirrorFilter{
+				BackendRef: gatewayv1.BackendObjectReference{Name: "shadow", Port: ptr.To(gatewayv1.PortNumber(8080))},
+				Percent:    ptr.To(int32(25)),
+			},
+		}},
+		BackendRefs: []gatewayv1.HTTPBackendRef{
+			{BackendRef: gatewayv1.BackendRef{
+				BackendObjectReference: gatewayv1.BackendObjectReference{Name: "v1", Port: ptr.To(gatewayv1.PortNumber(80))},
+				Weight:                 ptr.To(int32(90)),
+			}},
+			{
+				BackendRef: gatewayv1.BackendRef{
+					BackendObjectReference: gatewayv1.BackendObjectReference{Name: "v2", Namespace: ptr.To(gatewayv1.Namespace("canary")), Port: ptr.To(gatewayv1.PortNumber(80))},
+				},
+				Filters: []gatewayv1.HTTPRouteFilter{{
+					Type:                  gatewayv1.HTTPRouteFilterRequestHeaderModifier,
+					RequestHeaderModifier: &gatewayv1.HTTPHeaderFilter{Set: []gatewayv1.HTTPHeader{{Name: "x-version", Value: "v2"}}},
+				}},
+			},
+			{BackendRef: gatewayv1.BackendRef{
+				BackendObjectReference: gatewayv1.BackendObjectReference{Name: "v3", Port: ptr.To(gatewayv1.PortNumber(80))},
+			}},
+		},
+		Router: true,
+	}
+	b := &Builder{
+		services: testServices(
+			testService("default", "shadow", corev1.ServicePort{Port: 8080}),
+			testService("default", "v1", corev1.ServicePort{Port: 80}),
+			testService("canary", "v2", corev1.ServicePort{Port: 80}),
+		),
+		referenceGrants: testReferenceGrants(testReferenceGrant("canary", KindHTTPRoute, "default", "")),
+		backendTLS:      &backendTLSIndex{byService: map[types.NamespacedName]*backendTLS{{Namespace: "canary", Name: "v2"}: {}}},
+		unsupported:     map[RouteKey][]string{},
+		unresolved:      map[RouteKey][]UnresolvedRef{},
+	}
+
+	table := b.routerTable([]Rule{rule})
+	if len(table.Rules) != 1 {
+		t.Fatalf("routerTable() returned %d rules, want 1", len(table.Rules))
+	}
+	got := table.Rules[0]
+	if len(got.Filters) != 0 {
+		t.Errorf("filters = %v, want the mirror filter to be resolved", got.Filters)
+	}
+	if len(got.Mirrors) != 1 || got.Mirrors[0].URL != "http://shadow.default.svc.cluster.local:8080" || got.Mirrors[0].Fraction != 0.25 {
+		t.Errorf("mirrors = %+v, want a quarter of the requests mirrored to shadow", got.Mirrors)
+	}
+	if len(got.Backends) != 3 {
+		t.Fatalf("routerTable() returned %d backends, want 3", len(got.Backends))
+	}
+	if got.Backends[0].Weight != 90 || got.Backends[1].Weight != 1 {
+		t.Errorf("weights = %d, %d, want 90, 1", got.Backends[0].Weight, got.Backends[1].Weight)
+	}
+	if got.Backends[2].URL != "" || got.Backends[2].Weight != 1 {
+		t.Errorf("backend = %+v, want v3 to keep its share of requests without a URL", got.Backends[2])
+	}
+	if unresolved := b.unresolved[route.Key()]; len(unresolved) != 1 || unresolved[0].Reason != gatewayv1.RouteReasonBackendNotFound {
+		t.Errorf("unresolved = %v, want the missing service v3 reported", unresolved)
+	}
+	if got.Backends[1].URL != "http://v2.canary.svc.cluster.local:80" || len(got.Backends[1].Filters) != 1 {
+		t.Errorf("backend = %+v, want v2 in canary with its filter", got.Backends[1])
+	}
+	if len(b.unsupported[route.Key()]) != 1 {
+		t.Errorf("unsupported = %v, want the BackendTLSPolicy reported", b.unsupported[route.Key()])
+	}
+}
@@ -0,0 +1,224 @@
+package routing
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+)
+
+// routeObjects are the route kinds this controller serves
+func routeObjects() []client.Object {
+	return []client.Object{
+		&gatewayv1.HTTPRoute{},
+		&gatewayv1.GRPCRoute{},
+		&gatewayv1alpha2.TCPRoute{},
+		&gatewayv1alpha2.TLSRoute{},
+	}
+}
+
+// WatchRoutes adds a watch for every route kind this controller serves that is installed in the cluster
+func WatchRoutes(mgr ctrl.Manager, b *builder.Builder, eventHandler handler.EventHandler) *builder.Builder {
+	for _, obj := range routeObjects() {
+		if Installed(mgr, obj) {
+			b = b.Watches(obj, eventHandler)
+		}
+	}
+	return b
+}
+
+// Installed reports whether the API server serves the kind of obj, so that route kinds from
+// the experimental channel can be skipped on clusters that only have the standard channel installed
+func Installed(mgr ctrl.Manager, obj client.Object) bool {
+	gvk, err := apiutil.GVKForObject(obj, mgr.GetScheme())
+	if err != nil {
+		return false
+	}
+	_, err = mgr.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	return err == nil
+}
+
+func listHTTPRoutes(ctx context.Context, c client.Reader) ([]gatewayv1.HTTPRoute, error) {
+	httpRoutes := &gatewayv1.HTTPRouteList{}
+	if err := c.List(ctx, httpRoutes); err != nil {
+		return nil, errors.Wrap(err, "failed to list httpRoutes")
+	}
+	return httpRoutes.Items, nil
+}
+
+func listGRPCRoutes(ctx context.Context, c client.Reader) ([]gatewayv1.GRPCRoute, error) {
+	grpcRoutes := &gatewayv1.GRPCRouteList{}
+	if err := c.List(ctx, grpcRoutes); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to list grpcRoutes")
+	}
+	return grpcRoutes.Items, nil
+}
+
+func listTCPRoutes(ctx context.Context, c client.Reader) ([]gatewayv1alpha2.TCPRoute, error) {
+	tcpRoutes := &gatewayv1alpha2.TCPRouteList{}
+	if err := c.List(ctx, tcpRoutes); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to list tcpRoutes")
+	}
+	return tcpRoutes.Items, nil
+}
+
+func listTLSRoutes(ctx context.Context, c client.Reader) ([]gatewayv1alpha2.TLSRoute, error) {
+	tlsRoutes := &gatewayv1alpha2.TLSRouteList{}
+	if err := c.List(ctx, tlsRoutes); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to list tlsRoutes")
+	}
+	return tlsRoutes.Items, nil
+}
+
+// ListRoutes returns every route in the cluster, of every kind this controller serves
+func ListRoutes(ctx context.Context, c client.Reader) ([]Route, error) {
+	httpRoutes, err := listHTTPRoutes(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	grpcRoutes, err := listGRPCRoutes(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	tcpRoutes, err := listTCPRoutes(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	tlsRoutes, err := listTLSRoutes(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	routes := make([]Route, 0, len(httpRoutes)+len(grpcRoutes)+len(tcpRoutes)+len(tlsRoutes))
+	for i := range httpRoutes {
+		routes = append(routes, FromHTTPRoute(&httpRoutes[i]))
+	}
+	for i := range grpcRoutes {
+		routes = append(routes, FromGRPCRoute(&grpcRoutes[i]))
+	}
+	for i := range tcpRoutes {
+		routes = append(routes, FromTCPRoute(&tcpRoutes[i]))
+	}
+	for i := range tlsRoutes {
+		routes = append(routes, FromTLSRoute(&tlsRoutes[i]))
+	}
+	return routes, nil
+}
+
+// CountAttachedRoutes returns the number of routes attached to each listener of gateway
+func CountAttachedRoutes(ctx context.Context, c client.Reader, gateway *gatewayv1.Gateway) (map[gatewayv1.SectionName]int32, error) {
+	routes, err := ListRoutes(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[gatewayv1.SectionName]int32{}
+	for _, route := range routes {
+		listeners := map[gatewayv1.SectionName]bool{}
+		for _, ref := range route.ParentRefs {
+			if !TargetsGateway(ref, route.Namespace, gateway) {
+				continue
+			}
+			attachment, err := Attach(ctx, c, gateway, route, ref)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to attach %s", route)
+			}
+			for _, listener := range attachment.Listeners {
+				listeners[listener] = true
+			}
+		}
+		for listener := range listeners {
+			counts[listener]++
+		}
+	}
+	return counts, nil
+}
+
+// ParentGateways returns the Gateways referenced by the parentRefs of a route
+func ParentGateways(route Route) []types.NamespacedName {
+	var gateways []types.NamespacedName
+	for _, ref := range route.ParentRefs {
+		if groupOrDefault(ref.Group) != gatewayv1.GroupName {
+			continue
+		}
+		if ref.Kind != nil && *ref.Kind != KindGateway {
+			continue
+		}
+		namespace := route.Namespace
+		if ref.Namespace != nil {
+			namespace = string(*ref.Namespace)
+		}
+		gateways = append(gateways, types.NamespacedName{Namespace: namespace, Name: string(ref.Name)})
+	}
+	return gateways
+}
+
+// EnqueueGateways returns a handler that requeues the parent Gateways of a changed route of any kind
+func EnqueueGateways() handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(_ context.Context, obj client.Object) []reconcile.Request {
+		route, ok := FromObject(obj)
+		if !ok {
+			return nil
+		}
+		var requests []reconcile.Request
+		for _, gateway := range ParentGateways(route) {
+			requests = append(requests, reconcile.Request{NamespacedName: gateway})
+		}
+		return requests
+	})
+}
+
+// EnqueueRoutes returns a handler that requeues every route of kind that shares a parent Gateway
+// with the changed object, which is either a Gateway or a route of any kind. A route appearing
+// or going away can decide which of its siblings wins a conflicting hostname
+func EnqueueRoutes(c client.Reader, kind gatewayv1.Kind) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		parents := map[types.NamespacedName]bool{}
+		changed, isRoute := FromObject(obj)
+		if isRoute {
+			for _, gateway := range ParentGateways(changed) {
+				parents[gateway] = true
+			}
+		} else {
+			parents[client.ObjectKeyFromObject(obj)] = true
+		}
+
+		routes, err := ListRoutes(ctx, c)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "failed to list routes")
+			return nil
+		}
+		var requests []reconcile.Request
+		for _, route := range routes {
+			if route.Kind != kind || (isRoute && route.Key() == changed.Key()) {
+				continue
+			}
+			for _, gateway := range ParentGateways(route) {
+				if parents[gateway] {
+					requests = append(requests, reconcile.Request{
+						NamespacedName: types.NamespacedName{Namespace: route.Namespace, Name: route.Name},
+					})
+					break
+				}
+			}
+		}
+		return requests
+	})
+}
@@ -0,0 +1,310 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	// RouteReasonHostnameConflict is used when rules of a route were dropped because an older
+	// route already claims the same hostname and path
+	RouteReasonHostnameConflict gatewayv1.RouteConditionReason = "HostnameConflict"
+
+	// RouteConditionUnsupportedFields is set to True while a route uses fields that cloudflared
+	// ignores or only approximates, like PartiallyInvalid it is absent otherwise
+	RouteConditionUnsupportedFields gatewayv1.RouteConditionType = "UnsupportedFields"
+
+	// RouteConditionTrafficSplit reports how the requests of the rules with several backendRefs are split
+	// between them, for rollout tools to follow. It is absent when no rule has several backendRefs
+	RouteConditionTrafficSplit gatewayv1.RouteConditionType = "TrafficSplit"
+	// RouteReasonWeighted is used when the weights of every backendRef are honoured
+	RouteReasonWeighted gatewayv1.RouteConditionReason = "Weighted"
+	// RouteReasonFirstBackend is used when requests all go to the first weighted backendRef of a rule
+	RouteReasonFirstBackend gatewayv1.RouteConditionReason = "FirstBackend"
+
+	// RouteConditionBackendsReady reports whether the readiness gate of the Gateway holds back rules of the route
+	// until their backend Services have ready endpoints. It is absent when the Gateway has no readiness gate
+	RouteConditionBackendsReady gatewayv1.RouteConditionType = "BackendsReady"
+	// RouteReasonBackendsReady is used when every backend Service of the route has ready endpoints
+	RouteReasonBackendsReady gatewayv1.RouteConditionReason = "BackendsReady"
+	// RouteReasonWaitingForEndpoints is used while rules of the route answer with the maintenance response of
+	// the readiness gate
+	RouteReasonWaitingForEndpoints gatewayv1.RouteConditionReason = "WaitingForEndpoints"
+
+	// RouteConditionMaintenance is set to True while the rules of the route answer with the maintenance response
+	// of the Gateway, as the route or the Gateway carries AnnotationMaintenance. It is absent otherwise
+	RouteConditionMaintenance gatewayv1.RouteConditionType = "Maintenance"
+	// RouteReasonMaintenance is used while the route or its Gateway is in maintenance
+	RouteReasonMaintenance gatewayv1.RouteConditionReason = "Maintenance"
+
+	// RouteConditionPreviewHostname is set to True when the route has no hostnames and is published at the
+	// hostname generated by the previewHostname template of the Gateway. It is absent otherwise
+	RouteConditionPreviewHostname gatewayv1.RouteConditionType = "PreviewHostname"
+	// RouteReasonGenerated is used when the route is published at a generated hostname
+	RouteReasonGenerated gatewayv1.RouteConditionReason = "Generated"
+
+	// EventReasonMaintenanceStarted is the reason of the event recorded when a route or Gateway goes into maintenance
+	EventReasonMaintenanceStarted = "MaintenanceStarted"
+	// EventReasonMaintenanceEnded is the reason of the event recorded when a route or Gateway leaves maintenance
+	EventReasonMaintenanceEnded = "MaintenanceEnded"
+)
+
+// ManagedGateway returns the Gateway a parentRef points at when it belongs to a GatewayClass
+// of this controller, and nil otherwise
+func ManagedGateway(
+	ctx context.Context,
+	c client.Reader,
+	ref gatewayv1.ParentReference,
+	routeNamespace string,
+) (*gatewayv1.Gateway, error) {
+	if groupOrDefault(ref.Group) != gatewayv1.GroupName || (ref.Kind != nil && *ref.Kind != KindGateway) {
+		return nil, nil
+	}
+	namespace := routeNamespace
+	if ref.Namespace != nil {
+		namespace = string(*ref.Namespace)
+	}
+	gateway := &gatewayv1.Gateway{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: string(ref.Name)}, gateway); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to get gateway")
+	}
+	gatewayClass := &gatewayv1.GatewayClass{}
+	if err := c.Get(ctx, client.ObjectKey{Name: string(gateway.Spec.GatewayClassName)}, gatewayClass); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to get gatewayClass")
+	}
+	if gatewayClass.Spec.ControllerName != controller.Name {
+		return nil, nil
+	}
+	return gateway, nil
+}
+
+// ManagedGateways returns every Gateway that belongs to a GatewayClass of this controller
+func ManagedGateways(ctx context.Context, c client.Reader) ([]gatewayv1.Gateway, error) {
+	gateways := &gatewayv1.GatewayList{}
+	if err := c.List(ctx, gateways); err != nil {
+		return nil, errors.Wrap(err, "failed to list gateways")
+	}
+	var managed []gatewayv1.Gateway
+	for _, gateway := range gateways.Items {
+		gatewayClass := &gatewayv1.GatewayClass{}
+		if err := c.Get(ctx, client.ObjectKey{Name: string(gateway.Spec.GatewayClassName)}, gatewayClass); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, errors.Wrap(err, "failed to get gatewayClass")
+		}
+		if gatewayClass.Spec.ControllerName == controller.Name {
+			managed = append(managed, gateway)
+		}
+	}
+	return managed, nil
+}
+
+// ParentStatus returns the status this controller maintains for ref, adding one if the route does not have it yet
+func ParentStatus(parents *[]gatewayv1.RouteParentStatus, ref gatewayv1.ParentReference) *gatewayv1.RouteParentStatus {
+	for i := range *parents {
+		parent := &(*parents)[i]
+		if parent.ControllerName == controller.Name && reflect.DeepEqual(parent.ParentRef, ref) {
+			return parent
+		}
+	}
+	*parents = append(*parents, gatewayv1.RouteParentStatus{
+		ParentRef:      ref,
+		ControllerName: controller.Name,
+		Conditions:     []metav1.Condition{},
+	})
+	return &(*parents)[len(*parents)-1]
+}
+
+// removeStaleParents drops the statuses this controller maintains for parents that are not in refs, such as the
+// Gateways a route no longer references. It reports whether any status was dropped
+func removeStaleParents(parents *[]gatewayv1.RouteParentStatus, refs []gatewayv1.ParentReference) bool {
+	kept := (*parents)[:0]
+	removed := false
+	for _, parent := range *parents {
+		stale := parent.ControllerName == controller.Name
+		for _, ref := range refs {
+			if reflect.DeepEqual(parent.ParentRef, ref) {
+				stale = false
+				break
+			}
+		}
+		if stale {
+			removed = true
+			continue
+		}
+		kept = append(kept, parent)
+	}
+	*parents = kept
+	return removed
+}
+
+func SetCondition(
+	parent *gatewayv1.RouteParentStatus,
+	generation int64,
+	conditionType gatewayv1.RouteConditionType,
+	status metav1.ConditionStatus,
+	reason gatewayv1.RouteConditionReason,
+	message string,
+) {
+	meta.SetStatusCondition(&parent.Conditions, metav1.Condition{
+		Type:               string(conditionType),
+		Status:             status,
+		ObservedGeneration: generation,
+		Reason:             string(reason),
+		Message:            message,
+	})
+}
+
+// SetAccepted sets the Accepted and PartiallyInvalid conditions of a parent from how the
+// route attached to the Gateway and which of its rules were lost to conflicting routes
+func SetAccepted(
+	parent *gatewayv1.RouteParentStatus,
+	generation int64,
+	attachment Attachment,
+	rendered int,
+	conflicts []Conflict,
+) {
+	if !attachment.Accepted() {
+		SetCondition(parent, generation, gatewayv1.RouteConditionAccepted, metav1.ConditionFalse, attachment.Reason, attachment.Message)
+		meta.RemoveStatusCondition(&parent.Conditions, string(gatewayv1.RouteConditionPartiallyInvalid))
+		return
+	}
+	if len(conflicts) == 0 && rendered == 0 {
+		SetCondition(parent, generation, gatewayv1.RouteConditionAccepted, metav1.ConditionFalse, gatewayv1.RouteReasonUnsupportedValue, "no rules could be published, which needs a hostname on the route or on the listener it attaches to, or a previewHostname on the Gateway")
+		meta.RemoveStatusCondition(&parent.Conditions, string(gatewayv1.RouteConditionPartiallyInvalid))
+		return
+	}
+	if len(conflicts) == 0 {
+		SetCondition(parent, generation, gatewayv1.RouteConditionAccepted, metav1.ConditionTrue, gatewayv1.RouteReasonAccepted, "route is accepted")
+		meta.RemoveStatusCondition(&parent.Conditions, string(gatewayv1.RouteConditionPartiallyInvalid))
+		return
+	}
+
+	messages := make([]string, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		messages = append(messages, conflict.String())
+	}
+	message := strings.Join(messages, "; ")
+	if rendered == 0 {
+		SetCondition(parent, generation, gatewayv1.RouteConditionAccepted, metav1.ConditionFalse, RouteReasonHostnameConflict, message)
+		meta.RemoveStatusCondition(&parent.Conditions, string(gatewayv1.RouteConditionPartiallyInvalid))
+		return
+	}
+	SetCondition(parent, generation, gatewayv1.RouteConditionAccepted, metav1.ConditionTrue, gatewayv1.RouteReasonAccepted, "route is accepted")
+	SetCondition(parent, generation, gatewayv1.RouteConditionPartiallyInvalid, metav1.ConditionTrue, RouteReasonHostnameConflict, message)
+}
+
+// SetUnsupported sets the UnsupportedFields condition of a parent from the fields of the route
+// that cloudflared ignores or only approximates
+func SetUnsupported(parent *gatewayv1.RouteParentStatus, generation int64, unsupported []string) {
+	if len(unsupported) == 0 {
+		meta.RemoveStatusCondition(&parent.Conditions, string(RouteConditionUnsupportedFields))
+		return
+	}
+	SetCondition(parent, generation, RouteConditionUnsupportedFields, metav1.ConditionTrue, gatewayv1.RouteReasonUnsupportedValue, strings.Join(unsupported, "; "))
+}
+
+// SetTrafficSplit sets the TrafficSplit condition of a parent from the effective splits of the rules of the route
+func SetTrafficSplit(parent *gatewayv1.RouteParentStatus, generation int64, splits []BackendSplit) {
+	if len(splits) == 0 {
+		meta.RemoveStatusCondition(&parent.Conditions, string(RouteConditionTrafficSplit))
+		return
+	}
+	status, reason := metav1.ConditionTrue, RouteReasonWeighted
+	messages := make([]string, 0, len(splits))
+	for _, split := range splits {
+		if !split.Applied {
+			status, reason = metav1.ConditionFalse, RouteReasonFirstBackend
+		}
+		messages = append(messages, split.String())
+	}
+	SetCondition(parent, generation, RouteConditionTrafficSplit, status, reason, strings.Join(messages, "; "))
+}
+
+// SetResolvedRefs sets the ResolvedRefs condition of a parent from the backendRefs of the route that don't resolve
+func SetResolvedRefs(parent *gatewayv1.RouteParentStatus, generation int64, unresolved []UnresolvedRef) {
+	if len(unresolved) == 0 {
+		SetCondition(parent, generation, gatewayv1.RouteConditionResolvedRefs, metav1.ConditionTrue, gatewayv1.RouteReasonResolvedRefs, "all references are resolved")
+		return
+	}
+	messages := make([]string, 0, len(unresolved))
+	for _, ref := range unresolved {
+		messages = append(messages, ref.Message)
+	}
+	SetCondition(parent, generation, gatewayv1.RouteConditionResolvedRefs, metav1.ConditionFalse, unresolved[0].Reason, strings.Join(messages, "; "))
+}
+
+// SetBackendsReady sets the BackendsReady condition of a parent from the backend Services the readiness gate of the
+// Gateway holds the rules of the route back for
+func SetBackendsReady(parent *gatewayv1.RouteParentStatus, generation int64, gated bool, waiting []string) {
+	if !gated {
+		meta.RemoveStatusCondition(&parent.Conditions, string(RouteConditionBackendsReady))
+		return
+	}
+	if len(waiting) == 0 {
+		SetCondition(parent, generation, RouteConditionBackendsReady, metav1.ConditionTrue, RouteReasonBackendsReady, "all backend services have ready endpoints")
+		return
+	}
+	SetCondition(parent, generation, RouteConditionBackendsReady, metav1.ConditionFalse, RouteReasonWaitingForEndpoints, strings.Join(waiting, "; "))
+}
+
+// SetMaintenance sets the Maintenance condition of a parent from whether the rules of the route answer with the
+// maintenance response of the Gateway
+func SetMaintenance(parent *gatewayv1.RouteParentStatus, generation int64, maintenance bool) {
+	if !maintenance {
+		meta.RemoveStatusCondition(&parent.Conditions, string(RouteConditionMaintenance))
+		return
+	}
+	SetCondition(parent, generation, RouteConditionMaintenance, metav1.ConditionTrue, RouteReasonMaintenance, "requests are answered with the maintenance response of the Gateway")
+}
+
+// SetPreviewHostname sets the PreviewHostname condition of a parent from the hostname generated for the route
+func SetPreviewHostname(parent *gatewayv1.RouteParentStatus, generation int64, hostname string) {
+	if hostname == "" {
+		meta.RemoveStatusCondition(&parent.Conditions, string(RouteConditionPreviewHostname))
+		return
+	}
+	SetCondition(parent, generation, RouteConditionPreviewHostname, metav1.ConditionTrue, RouteReasonGenerated, fmt.Sprintf("route is published at %s", hostname))
+}
+
+// RecordMaintenance records an event on a route when it goes into or leaves maintenance on any of its parents,
+// from its status before and after the reconciliation
+func RecordMaintenance(recorder record.EventRecorder, route runtime.Object, before *gatewayv1.RouteStatus, after *gatewayv1.RouteStatus) {
+	was, is := routeInMaintenance(before), routeInMaintenance(after)
+	switch {
+	case !was && is:
+		recorder.Event(route, corev1.EventTypeNormal, EventReasonMaintenanceStarted, "requests are answered with the maintenance response of the Gateway")
+	case was && !is:
+		recorder.Event(route, corev1.EventTypeNormal, EventReasonMaintenanceEnded, "requests are sent to the backends of the route again")
+	}
+}
+
+// routeInMaintenance reports whether a parent of this controller has the Maintenance condition
+func routeInMaintenance(status *gatewayv1.RouteStatus) bool {
+	for _, parent := range status.Parents {
+		if parent.ControllerName == controller.Name && meta.IsStatusConditionTrue(parent.Conditions, string(RouteConditionMaintenance)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestRemoveStaleParents(t *testing.T) {
+	public := gatewayv1.ParentReference{Name: "public"}
+	internal := gatewayv1.ParentReference{Name: "internal"}
+	section := gatewayv1.SectionName("https")
+	publicHTTPS := gatewayv1.ParentReference{Name: "public", SectionName: &section}
+	other := gatewayv1.RouteParentStatus{ParentRef: public, ControllerName: "example.com/other-controller"}
+	mine := func(ref gatewayv1.ParentReference) gatewayv1.RouteParentStatus {
+		return gatewayv1.RouteParentStatus{ParentRef: ref, ControllerName: controller.Name}
+	}
+
+	tests := []struct {
+		name        string
+		parents     []gatewayv1.RouteParentStatus
+		refs        []gatewayv1.ParentReference
+		want        []gatewayv1.RouteParentStatus
+		wantRemoved bool
+	}{
+		{
+			name:    "Parents still referenced",
+			parents: []gatewayv1.RouteParentStatus{mine(public), mine(internal)},
+			refs:    []gatewayv1.ParentReference{public, internal},
+			want:    []gatewayv1.RouteParentStatus{mine(public), mine(internal)},
+		},
+		{
+			name:        "Gateways no longer referenced",
+			parents:     []gatewayv1.RouteParentStatus{mine(public), mine(internal)},
+			refs:        []gatewayv1.ParentReference{public},
+			want:        []gatewayv1.RouteParentStatus{mine(public)},
+			wantRemoved: true,
+		},
+		{
+			name:        "Listeners no longer referenced",
+			parents:     []gatewayv1.RouteParentStatus{mine(publicHTTPS)},
+			refs:        []gatewayv1.ParentReference{public},
+			want:        []gatewayv1.RouteParentStatus{},
+			wantRemoved: true,
+		},
+		{
+			name:    "Parents of other controllers",
+			parents: []gatewayv1.RouteParentStatus{other},
+			want:    []gatewayv1.RouteParentStatus{other},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parents := tt.parents
+			removed := removeStaleParents(&parents, tt.refs)
+			if removed != tt.wantRemoved {
+				t.Errorf("removeStaleParents() = %v, want %v", removed, tt.wantRemoved)
+			}
+			if len(parents) != len(tt.want) {
+				t.Fatalf("parents = %v, want %v", parents, tt.want)
+			}
+			for i := range parents {
+				if parents[i].ParentRef.Name != tt.want[i].ParentRef.Name ||
+					parents[i].ParentRef.SectionName != tt.want[i].ParentRef.SectionName ||
+					parents[i].ControllerName != tt.want[i].ControllerName {
+					t.Errorf("parents[%d] = %v, want %v", i, parents[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
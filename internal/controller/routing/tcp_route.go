@@ -0,0 +1,38 @@
+package routing
+
+import (
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+)
+
+// tcpRouteRules renders the route on the hostname of the listener, which is what clients pass
+// to `cloudflared access tcp --hostname`. Listeners without a hostname have nothing to publish
+func (b *Builder) tcpRouteRules(route *gatewayv1alpha2.TCPRoute, listener gatewayv1.Listener) []Rule {
+	if listener.Hostname == nil || *listener.Hostname == "" {
+		return nil
+	}
+	source := FromTCPRoute(route)
+	var rules []Rule
+	for ruleIndex, rule := range route.Spec.Rules {
+		if len(rule.BackendRefs) == 0 {
+			continue
+		}
+		service, ok := b.backendURL(source, "tcp", rule.BackendRefs[0].BackendObjectReference)
+		if !ok {
+			// the hostname stays claimed by the route, cloudflared refuses its streams until the backend resolves
+			service = NoBackendsService
+		}
+		rules = append(rules, Rule{
+			Ingress: cf.IngressConfig{
+				Hostname: string(*listener.Hostname),
+				Service:  service,
+			},
+			Route:     source,
+			RuleIndex: ruleIndex,
+			Listener:  listener.Name,
+			Backend:   b.permittedServiceKey(source, rule.BackendRefs[0].BackendObjectReference),
+		})
+	}
+	return rules
+}
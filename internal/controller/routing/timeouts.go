@@ -0,0 +1,67 @@
+package routing
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// applyTimeouts maps the timeouts of a HTTPRoute rule onto the origin settings of cloudflared.
+// cloudflared can only bound setting up the connection to the origin, not waiting for its response,
+// so the timeouts are applied to connecting and the TLS handshake and the rest is reported on the route
+func (b *Builder) applyTimeouts(
+	route Route,
+	ruleIndex int,
+	timeouts *gatewayv1.HTTPRouteTimeouts,
+	service string,
+	originRequest *cf.OriginRequestConfig,
+) *cf.OriginRequestConfig {
+	if timeouts == nil {
+		return originRequest
+	}
+	request, requestSet := parseTimeout(timeouts.Request)
+	backendRequest, backendRequestSet := parseTimeout(timeouts.BackendRequest)
+
+	if requestSet {
+		b.unsupportedField(route, fmt.Sprintf("rules[%d].timeouts.request is only applied to connecting to the backend, Cloudflare waits up to 100s for the backend to respond", ruleIndex))
+	}
+	if backendRequestSet {
+		b.unsupportedField(route, fmt.Sprintf("rules[%d].timeouts.backendRequest is only applied to connecting to the backend, cloudflared does not time out waiting for the backend to respond", ruleIndex))
+	}
+
+	// connecting to the backend is part of both timeouts, so it is bound by the shorter one
+	timeout := backendRequest
+	if !backendRequestSet || (requestSet && request < backendRequest) {
+		timeout = request
+	}
+	if !requestSet && !backendRequestSet {
+		return originRequest
+	}
+
+	withTimeouts := &cf.OriginRequestConfig{}
+	if originRequest != nil {
+		copied := *originRequest
+		withTimeouts = &copied
+	}
+	withTimeouts.ConnectTimeout = timeout.String()
+	if strings.HasPrefix(service, "https://") {
+		withTimeouts.TLSTimeout = timeout.String()
+	}
+	return withTimeouts
+}
+
+// parseTimeout parses a Gateway API duration, reporting false when it is unset or disables the timeout with 0s
+func parseTimeout(duration *gatewayv1.Duration) (time.Duration, bool) {
+	if duration == nil {
+		return 0, false
+	}
+	// the Gateway API duration format is a subset of the Go one, and validated by the API server
+	timeout, err := time.ParseDuration(string(*duration))
+	if err != nil || timeout <= 0 {
+		return 0, false
+	}
+	return timeout, true
+}
@@ -0,0 +1,77 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestApplyTimeouts(t *testing.T) {
+	route := Route{Kind: KindHTTPRoute, Namespace: "default", Name: "route"}
+	duration := func(d string) *gatewayv1.Duration {
+		return ptr.To(gatewayv1.Duration(d))
+	}
+
+	tests := []struct {
+		name            string
+		timeouts        *gatewayv1.HTTPRouteTimeouts
+		service         string
+		originRequest   *cf.OriginRequestConfig
+		wantConnect     string
+		wantTLS         string
+		wantUnsupported int
+	}{
+		{
+			name:    "No timeouts",
+			service: "http://backend.default.svc.cluster.local:80",
+		},
+		{
+			name:            "Request timeout",
+			timeouts:        &gatewayv1.HTTPRouteTimeouts{Request: duration("10s")},
+			service:         "http://backend.default.svc.cluster.local:80",
+			wantConnect:     "10s",
+			wantUnsupported: 1,
+		},
+		{
+			name:            "Shorter backend request timeout over https",
+			timeouts:        &gatewayv1.HTTPRouteTimeouts{Request: duration("1m"), BackendRequest: duration("5s")},
+			service:         "https://backend.default.svc.cluster.local:443",
+			originRequest:   &cf.OriginRequestConfig{OriginServerName: "backend.example.com"},
+			wantConnect:     "5s",
+			wantTLS:         "5s",
+			wantUnsupported: 2,
+		},
+		{
+			name:     "Disabled timeout",
+			timeouts: &gatewayv1.HTTPRouteTimeouts{Request: duration("0s")},
+			service:  "http://backend.default.svc.cluster.local:80",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Builder{unsupported: map[RouteKey][]string{}}
+			var original cf.OriginRequestConfig
+			if tt.originRequest != nil {
+				original = *tt.originRequest
+			}
+			got := b.applyTimeouts(route, 0, tt.timeouts, tt.service, tt.originRequest)
+
+			var connect, tls string
+			if got != nil {
+				connect, tls = got.ConnectTimeout, got.TLSTimeout
+			}
+			if connect != tt.wantConnect || tls != tt.wantTLS {
+				t.Errorf("applyTimeouts() connectTimeout = %q, tlsTimeout = %q, want %q, %q", connect, tls, tt.wantConnect, tt.wantTLS)
+			}
+			if tt.originRequest != nil && *tt.originRequest != original {
+				t.Errorf("applyTimeouts() modified the origin settings it was given")
+			}
+			if got := len(b.unsupported[route.Key()]); got != tt.wantUnsupported {
+				t.Errorf("applyTimeouts() reported %d unsupported fields, want %d", got, tt.wantUnsupported)
+			}
+		})
+	}
+}
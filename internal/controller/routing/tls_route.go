@@ -0,0 +1,64 @@
+package routing
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+)
+
+const (
+	// AnnotationNoTLSVerify disables verification of origin certificates for the routes it is set on
+	AnnotationNoTLSVerify = "cloudflare.adamland.xyz/no-tls-verify"
+)
+
+// tlsRouteRules renders one ingress rule per hostname of every rule of the route.
+// Cloudflare always terminates the client connection at its edge, so passthrough here means
+// that cloudflared opens a new TLS connection to the backend using the hostname of the route as SNI
+func (b *Builder) tlsRouteRules(route *gatewayv1alpha2.TLSRoute, listener gatewayv1.SectionName, hostnames []string) []Rule {
+	source := FromTLSRoute(route)
+	noTLSVerify, _ := strconv.ParseBool(route.Annotations[AnnotationNoTLSVerify])
+	var rules []Rule
+	for ruleIndex, rule := range route.Spec.Rules {
+		if len(rule.BackendRefs) == 0 {
+			continue
+		}
+		service, ok := b.backendURL(source, "https", rule.BackendRefs[0].BackendObjectReference)
+		if !ok {
+			service = NoBackendsService
+		}
+		backend := b.permittedServiceKey(source, rule.BackendRefs[0].BackendObjectReference)
+		for _, hostname := range hostnames {
+			rules = append(rules, Rule{
+				Ingress: cf.IngressConfig{
+					Hostname:      hostname,
+					Service:       service,
+					OriginRequest: tlsOriginRequest(hostname, noTLSVerify),
+				},
+				Route:     source,
+				RuleIndex: ruleIndex,
+				Listener:  listener,
+				Backend:   backend,
+			})
+		}
+	}
+	return rules
+}
+
+// tlsOriginRequest sets the SNI of the origin connection. A wildcard can't be sent as SNI,
+// so wildcard hostnames pass on the hostname of each request instead
+func tlsOriginRequest(hostname string, noTLSVerify bool) *cf.OriginRequestConfig {
+	originRequest := &cf.OriginRequestConfig{}
+	if strings.HasPrefix(hostname, "*.") {
+		originRequest.MatchSNIToHost = ptr.To(true)
+	} else {
+		originRequest.OriginServerName = hostname
+	}
+	if noTLSVerify {
+		originRequest.NoTLSVerify = ptr.To(true)
+	}
+	return originRequest
+}
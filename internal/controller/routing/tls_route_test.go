@@ -0,0 +1,65 @@
+package routing
+
+import (
+	"testing"
+
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestSupportedKinds(t *testing.T) {
+	tests := []struct {
+		name     string
+		listener gatewayv1.Listener
+		want     []gatewayv1.Kind
+	}{
+		{
+			name:     "HTTP",
+			listener: gatewayv1.Listener{Protocol: gatewayv1.HTTPProtocolType},
+			want:     []gatewayv1.Kind{KindHTTPRoute, KindGRPCRoute},
+		},
+		{
+			name: "TLS passthrough",
+			listener: gatewayv1.Listener{
+				Protocol: gatewayv1.TLSProtocolType,
+				TLS:      &gatewayv1.GatewayTLSConfig{Mode: ptr.To(gatewayv1.TLSModePassthrough)},
+			},
+			want: []gatewayv1.Kind{KindTLSRoute},
+		},
+		{
+			name:     "TLS defaults to terminate",
+			listener: gatewayv1.Listener{Protocol: gatewayv1.TLSProtocolType},
+			want:     nil,
+		},
+		{
+			name:     "UDP",
+			listener: gatewayv1.Listener{Protocol: gatewayv1.UDPProtocolType},
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SupportedKinds(tt.listener)
+			if len(got) != len(tt.want) {
+				t.Fatalf("SupportedKinds() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].Kind != tt.want[i] {
+					t.Errorf("SupportedKinds()[%d] = %s, want %s", i, got[i].Kind, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTLSOriginRequest(t *testing.T) {
+	exact := tlsOriginRequest("secure.example.com", false)
+	if exact.OriginServerName != "secure.example.com" || exact.MatchSNIToHost != nil || exact.NoTLSVerify != nil {
+		t.Errorf("tlsOriginRequest() for an exact hostname = %+v", exact)
+	}
+	wildcard := tlsOriginRequest("*.example.com", true)
+	if wildcard.OriginServerName != "" || !ptr.Deref(wildcard.MatchSNIToHost, false) || !ptr.Deref(wildcard.NoTLSVerify, false) {
+		t.Errorf("tlsOriginRequest() for a wildcard hostname = %+v", wildcard)
+	}
+}
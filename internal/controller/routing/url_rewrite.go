@@ -0,0 +1,152 @@
+package routing
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cloudflare/cloudflare-go"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// URLRewrite filters are split between cloudflared and the Cloudflare edge: hostname rewrites become the
+// httpHostHeader of the origin, and path rewrites become URL rewrite transform rules. cloudflared only ever
+// sees the rewritten path, so the ingress rule of a rewritten rule matches the rewritten paths instead of
+// the paths of the route
+
+// urlRewrite returns the URLRewrite filter of a rule, if it has one
+func urlRewrite(filters []gatewayv1.HTTPRouteFilter) *gatewayv1.HTTPURLRewriteFilter {
+	for _, filter := range filters {
+		if filter.Type == gatewayv1.HTTPRouteFilterURLRewrite && filter.URLRewrite != nil {
+			return filter.URLRewrite
+		}
+	}
+	return nil
+}
+
+// rewrittenPath returns the regular expression cloudflared matches the rewritten paths of a rule against,
+// reporting false when the path modifier can't be applied to the match
+func rewrittenPath(match *gatewayv1.HTTPRouteMatch, modifier *gatewayv1.HTTPPathModifier) (string, bool) {
+	switch modifier.Type {
+	case gatewayv1.FullPathHTTPPathModifier:
+		return PathRegex(&gatewayv1.HTTPPathMatch{
+			Type:  ptr.To(gatewayv1.PathMatchExact),
+			Value: ptr.To(ptr.Deref(modifier.ReplaceFullPath, "/")),
+		}), true
+	case gatewayv1.PrefixMatchHTTPPathModifier:
+		if _, ok := matchedPrefix(match); !ok {
+			return "", false
+		}
+		return PathRegex(&gatewayv1.HTTPPathMatch{
+			Type:  ptr.To(gatewayv1.PathMatchPathPrefix),
+			Value: ptr.To(ptr.Deref(modifier.ReplacePrefixMatch, "/")),
+		}), true
+	default:
+		return "", false
+	}
+}
+
+// matchedPrefix returns the prefix of a PathPrefix match without its trailing slash, reporting false for other matches
+func matchedPrefix(match *gatewayv1.HTTPRouteMatch) (string, bool) {
+	if match == nil || match.Path == nil || match.Path.Value == nil ||
+		ptr.Deref(match.Path.Type, gatewayv1.PathMatchPathPrefix) != gatewayv1.PathMatchPathPrefix {
+		return "", false
+	}
+	return strings.TrimSuffix(*match.Path.Value, "/"), true
+}
+
+// prefixReplacement returns an expression of the request path with prefix, which the path is known to start
+// with, replaced by replacement
+func prefixReplacement(prefix string, replacement string) string {
+	replacement = strings.TrimSuffix(replacement, "/")
+	if prefix == "" {
+		return "concat(" + cf.QuoteString(replacement) + ", http.request.uri.path)"
+	}
+	if replacement == "" {
+		// the rest of the path after the prefix and its slash, which is empty when the path is the prefix itself
+		return fmt.Sprintf(`concat("/", substring(http.request.uri.path, %d))`, len(prefix)+1)
+	}
+	return fmt.Sprintf("concat(%s, substring(http.request.uri.path, %d))", cf.QuoteString(replacement), len(prefix))
+}
+
+// rewriteRules renders the path rewrites of the URLRewrite filters of the rules into URL rewrite transform rules owned by owner
+func rewriteRules(owner string, rules []Rule) []cloudflare.RulesetRule {
+	var rewrites []cloudflare.RulesetRule
+	for i, rule := range rules {
+		rewrite := urlRewrite(rule.Filters)
+		if rewrite == nil || rewrite.Path == nil || rule.Router {
+			continue
+		}
+		path := &cloudflare.RulesetRuleActionParametersURIPath{}
+		switch rewrite.Path.Type {
+		case gatewayv1.FullPathHTTPPathModifier:
+			path.Value = ptr.Deref(rewrite.Path.ReplaceFullPath, "/")
+		case gatewayv1.PrefixMatchHTTPPathModifier:
+			prefix, ok := matchedPrefix(rule.Match)
+			if !ok {
+				continue
+			}
+			path.Expression = prefixReplacement(prefix, ptr.Deref(rewrite.Path.ReplacePrefixMatch, "/"))
+		default:
+			continue
+		}
+		rewrites = append(rewrites, cloudflare.RulesetRule{
+			Ref:         cf.EdgeRuleRef(owner, fmt.Sprintf("%s/%d/%s/%s/rewrite", rule.Route, rule.RuleIndex, rule.Ingress.Hostname, rule.Ingress.Path)),
+			Description: fmt.Sprintf("%s rule %d: rewrite %s", rule.Route, rule.RuleIndex, rule.Ingress.Hostname),
+			Expression:  edgeExpression(rules, i),
+			Action:      string(cloudflare.RulesetRuleActionRewrite),
+			Enabled:     ptr.To(true),
+			ActionParameters: &cloudflare.RulesetRuleActionParameters{
+				URI: &cloudflare.RulesetRuleActionParametersURI{Path: path},
+			},
+		})
+	}
+	return rewrites
+}
+
+// reportCapturedRewrites records the path rewrites whose rewritten requests cloudflared can hand to a more
+// specific rule of another backend, as cloudflared routes requests by their rewritten path
+func (b *Builder) reportCapturedRewrites(rules []Rule) {
+	for i, rule := range rules {
+		rewrite := urlRewrite(rule.Filters)
+		if rewrite == nil || rewrite.Path == nil || rule.Router {
+			continue
+		}
+		for _, earlier := range rules[:i] {
+			if earlier.Ingress.Service == rule.Ingress.Service || !hostnamesOverlap(earlier.Ingress.Hostname, rule.Ingress.Hostname) {
+				continue
+			}
+			if !pathsOverlap(earlier, rule.Ingress.Path) {
+				continue
+			}
+			earlierPath := earlier.Ingress.Path
+			if earlierPath == "" {
+				earlierPath = "/"
+			}
+			b.unsupportedField(rule.Route, fmt.Sprintf(
+				"rules[%d].filters.urlRewrite.path can't be represented, cloudflared routes requests by their rewritten path and hands some of them to path %s of %s",
+				rule.RuleIndex, earlierPath, earlier.Route,
+			))
+		}
+	}
+}
+
+// pathsOverlap reports whether a request with a path matching the regular expression path, as rendered
+// by PathRegex, can be handed to rule by cloudflared
+func pathsOverlap(rule Rule, path string) bool {
+	if rule.Ingress.Path == "" || path == "" {
+		return true
+	}
+	if prefix, ok := matchedPrefix(rule.Match); ok {
+		// the paths of a PathPrefix match render as ^<prefix>(/.*)?$, so it's enough to test its prefix
+		if matched, err := regexp.MatchString(path, prefix); err == nil && matched {
+			return true
+		}
+	}
+	representative := strings.TrimSuffix(strings.TrimPrefix(path, "^"), "(/.*)?$")
+	representative = strings.TrimSuffix(representative, "$")
+	matched, err := regexp.MatchString(rule.Ingress.Path, strings.ReplaceAll(representative, `\`, ""))
+	return err != nil || matched
+}
@@ -0,0 +1,139 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/cf"
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestRewriteRules(t *testing.T) {
+	route := Route{Kind: KindHTTPRoute, Namespace: "default", Name: "api"}
+	other := Route{Kind: KindHTTPRoute, Namespace: "default", Name: "admin"}
+	prefix := func(value string) *gatewayv1.HTTPRouteMatch {
+		return &gatewayv1.HTTPRouteMatch{Path: &gatewayv1.HTTPPathMatch{Type: ptr.To(gatewayv1.PathMatchPathPrefix), Value: ptr.To(value)}}
+	}
+	rewrite := func(modifier gatewayv1.HTTPPathModifier) []gatewayv1.HTTPRouteFilter {
+		return []gatewayv1.HTTPRouteFilter{{
+			Type:       gatewayv1.HTTPRouteFilterURLRewrite,
+			URLRewrite: &gatewayv1.HTTPURLRewriteFilter{Path: &modifier},
+		}}
+	}
+
+	tests := []struct {
+		name            string
+		rules           []Rule
+		wantPath        string
+		wantExpression  string
+		wantUnsupported int
+	}{
+		{
+			name: "Strip a prefix",
+			rules: []Rule{{
+				Ingress: cf.IngressConfig{Hostname: "a.example.com", Service: "http://api.default.svc.cluster.local:80"},
+				Route:   route,
+				Match:   prefix("/api"),
+				Filters: rewrite(gatewayv1.HTTPPathModifier{Type: gatewayv1.PrefixMatchHTTPPathModifier, ReplacePrefixMatch: ptr.To("/")}),
+			}},
+			wantExpression: `concat("/", substring(http.request.uri.path, 5))`,
+		},
+		{
+			name: "Replace a prefix",
+			rules: []Rule{{
+				Ingress: cf.IngressConfig{Hostname: "a.example.com", Path: "^/v2(/.*)?$", Service: "http://api.default.svc.cluster.local:80"},
+				Route:   route,
+				Match:   prefix("/api/"),
+				Filters: rewrite(gatewayv1.HTTPPathModifier{Type: gatewayv1.PrefixMatchHTTPPathModifier, ReplacePrefixMatch: ptr.To("/v2")}),
+			}},
+			wantExpression: `concat("/v2", substring(http.request.uri.path, 4))`,
+		},
+		{
+			name: "Replace the full path",
+			rules: []Rule{{
+				Ingress: cf.IngressConfig{Hostname: "a.example.com", Path: "^/health$", Service: "http://api.default.svc.cluster.local:80"},
+				Route:   route,
+				Match:   prefix("/status"),
+				Filters: rewrite(gatewayv1.HTTPPathModifier{Type: gatewayv1.FullPathHTTPPathModifier, ReplaceFullPath: ptr.To("/health")}),
+			}},
+			wantPath: "/health",
+		},
+		{
+			name: "Rewritten requests reach a more specific rule of another backend",
+			rules: []Rule{
+				{
+					Ingress: cf.IngressConfig{Hostname: "a.example.com", Path: "^/admin(/.*)?$", Service: "http://admin.default.svc.cluster.local:80"},
+					Route:   other,
+					Match:   prefix("/admin"),
+				},
+				{
+					Ingress: cf.IngressConfig{Hostname: "a.example.com", Service: "http://api.default.svc.cluster.local:80"},
+					Route:   route,
+					Match:   prefix("/api"),
+					Filters: rewrite(gatewayv1.HTTPPathModifier{Type: gatewayv1.PrefixMatchHTTPPathModifier, ReplacePrefixMatch: ptr.To("/")}),
+				},
+			},
+			wantExpression:  `concat("/", substring(http.request.uri.path, 5))`,
+			wantUnsupported: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rewriteRules("owner-", tt.rules)
+			if len(got) != 1 {
+				t.Fatalf("rewriteRules() returned %d rules, want 1", len(got))
+			}
+			path := got[0].ActionParameters.URI.Path
+			if path.Value != tt.wantPath || path.Expression != tt.wantExpression {
+				t.Errorf("rewriteRules() path = %+v, want value %q expression %q", path, tt.wantPath, tt.wantExpression)
+			}
+
+			b := &Builder{unsupported: map[RouteKey][]string{}}
+			b.reportCapturedRewrites(tt.rules)
+			if len(b.unsupported[route.Key()]) != tt.wantUnsupported {
+				t.Errorf("reportCapturedRewrites() recorded %v, want %d messages", b.unsupported[route.Key()], tt.wantUnsupported)
+			}
+		})
+	}
+}
+
+func TestRewrittenPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		match    *gatewayv1.HTTPRouteMatch
+		modifier gatewayv1.HTTPPathModifier
+		want     string
+		wantOK   bool
+	}{
+		{
+			name:     "Prefix to root",
+			match:    &gatewayv1.HTTPRouteMatch{Path: &gatewayv1.HTTPPathMatch{Value: ptr.To("/api")}},
+			modifier: gatewayv1.HTTPPathModifier{Type: gatewayv1.PrefixMatchHTTPPathModifier, ReplacePrefixMatch: ptr.To("/")},
+			want:     "",
+			wantOK:   true,
+		},
+		{
+			name:     "Full path",
+			match:    &gatewayv1.HTTPRouteMatch{},
+			modifier: gatewayv1.HTTPPathModifier{Type: gatewayv1.FullPathHTTPPathModifier, ReplaceFullPath: ptr.To("/index.html")},
+			want:     `^/index\.html$`,
+			wantOK:   true,
+		},
+		{
+			name:     "Prefix replacement of an exact match",
+			match:    &gatewayv1.HTTPRouteMatch{Path: &gatewayv1.HTTPPathMatch{Type: ptr.To(gatewayv1.PathMatchExact), Value: ptr.To("/api")}},
+			modifier: gatewayv1.HTTPPathModifier{Type: gatewayv1.PrefixMatchHTTPPathModifier, ReplacePrefixMatch: ptr.To("/")},
+			wantOK:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := rewrittenPath(tt.match, &tt.modifier)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("rewrittenPath() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
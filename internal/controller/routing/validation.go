@@ -0,0 +1,170 @@
+package routing
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+)
+
+// The validation below is shared by the admission webhooks, which reject invalid routes and Gateways when they
+// are applied, and the Builder, which skips what slipped through before the webhooks were installed
+
+// InDomain reports whether hostname, which may be a wildcard, is the domain of the GatewayClass or one of its subdomains
+func InDomain(hostname string, domain string) bool {
+	hostname = strings.ToLower(strings.TrimPrefix(hostname, "*."))
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	return hostname == domain || strings.HasSuffix(hostname, "."+domain)
+}
+
+// ValidateHostnames returns an error for every hostname outside of the domain of the GatewayClass, an empty
+// domain accepts every hostname
+func ValidateHostnames(path *field.Path, hostnames []gatewayv1.Hostname, domain string) field.ErrorList {
+	if domain == "" {
+		return nil
+	}
+	var errs field.ErrorList
+	for i, hostname := range hostnames {
+		if !InDomain(string(hostname), domain) {
+			errs = append(errs, field.Invalid(path.Index(i), hostname, fmt.Sprintf("must be %s or one of its subdomains", domain)))
+		}
+	}
+	return errs
+}
+
+// ValidateGateway returns the errors of the listeners of a Gateway, an empty domain accepts every hostname
+func ValidateGateway(gateway *gatewayv1.Gateway, domain string) field.ErrorList {
+	if domain == "" {
+		return nil
+	}
+	var errs field.ErrorList
+	listeners := field.NewPath("spec", "listeners")
+	for i, listener := range gateway.Spec.Listeners {
+		if listener.Hostname != nil && !InDomain(string(*listener.Hostname), domain) {
+			errs = append(errs, field.Invalid(listeners.Index(i).Child("hostname"), *listener.Hostname, fmt.Sprintf("must be %s or one of its subdomains", domain)))
+		}
+	}
+	return errs
+}
+
+// ValidateHTTPRoute returns the errors of a HTTPRoute, and the fields cloudflared does not support
+func ValidateHTTPRoute(route *gatewayv1.HTTPRoute, domain string) field.ErrorList {
+	spec := field.NewPath("spec")
+	errs := ValidateHostnames(spec.Child("hostnames"), route.Spec.Hostnames, domain)
+	for i, rule := range route.Spec.Rules {
+		errs = append(errs, validateHTTPRouteRule(spec.Child("rules").Index(i), rule)...)
+		errs = append(errs, unsupportedHTTPRouteFilters(spec.Child("rules").Index(i), rule)...)
+	}
+	return errs
+}
+
+// ValidateGRPCRoute returns the errors of a GRPCRoute, and the fields cloudflared does not support
+func ValidateGRPCRoute(route *gatewayv1.GRPCRoute, domain string) field.ErrorList {
+	spec := field.NewPath("spec")
+	errs := ValidateHostnames(spec.Child("hostnames"), route.Spec.Hostnames, domain)
+	for i, rule := range route.Spec.Rules {
+		errs = append(errs, validateGRPCRouteRule(spec.Child("rules").Index(i), rule)...)
+		errs = append(errs, unsupportedGRPCRouteFilters(spec.Child("rules").Index(i), rule)...)
+	}
+	return errs
+}
+
+// ValidateTLSRoute returns the errors of a TLSRoute
+func ValidateTLSRoute(route *gatewayv1alpha2.TLSRoute, domain string) field.ErrorList {
+	return ValidateHostnames(field.NewPath("spec", "hostnames"), route.Spec.Hostnames, domain)
+}
+
+// validateHTTPRouteRule returns the errors that keep a HTTPRoute rule from being rendered: regular expressions
+// that neither cloudflared nor the router, which both use the regexp package of Go, can compile
+func validateHTTPRouteRule(path *field.Path, rule gatewayv1.HTTPRouteRule) field.ErrorList {
+	var errs field.ErrorList
+	for i, match := range rule.Matches {
+		matchPath := path.Child("matches").Index(i)
+		if match.Path != nil && match.Path.Type != nil && *match.Path.Type == gatewayv1.PathMatchRegularExpression && match.Path.Value != nil {
+			errs = append(errs, validateRegex(matchPath.Child("path", "value"), *match.Path.Value)...)
+		}
+		for j, header := range match.Headers {
+			if header.Type != nil && *header.Type == gatewayv1.HeaderMatchRegularExpression {
+				errs = append(errs, validateRegex(matchPath.Child("headers").Index(j).Child("value"), header.Value)...)
+			}
+		}
+		for j, query := range match.QueryParams {
+			if query.Type != nil && *query.Type == gatewayv1.QueryParamMatchRegularExpression {
+				errs = append(errs, validateRegex(matchPath.Child("queryParams").Index(j).Child("value"), query.Value)...)
+			}
+		}
+	}
+	return errs
+}
+
+// validateGRPCRouteRule returns the errors that keep a GRPCRoute rule from being rendered
+func validateGRPCRouteRule(path *field.Path, rule gatewayv1.GRPCRouteRule) field.ErrorList {
+	var errs field.ErrorList
+	for i, match := range rule.Matches {
+		matchPath := path.Child("matches").Index(i)
+		if match.Method != nil && match.Method.Type != nil && *match.Method.Type == gatewayv1.GRPCMethodMatchRegularExpression {
+			if match.Method.Service != nil {
+				errs = append(errs, validateRegex(matchPath.Child("method", "service"), *match.Method.Service)...)
+			}
+			if match.Method.Method != nil {
+				errs = append(errs, validateRegex(matchPath.Child("method", "method"), *match.Method.Method)...)
+			}
+		}
+		for j, header := range match.Headers {
+			if header.Type != nil && *header.Type == gatewayv1.GRPCHeaderMatchRegularExpression {
+				errs = append(errs, validateRegex(matchPath.Child("headers").Index(j).Child("value"), header.Value)...)
+			}
+		}
+	}
+	return errs
+}
+
+// unsupportedHTTPRouteFilters returns the filters of a HTTPRoute rule that are not implemented, which are ignored
+func unsupportedHTTPRouteFilters(path *field.Path, rule gatewayv1.HTTPRouteRule) field.ErrorList {
+	var errs field.ErrorList
+	for i, filter := range rule.Filters {
+		if filter.Type == gatewayv1.HTTPRouteFilterExtensionRef {
+			errs = append(errs, field.NotSupported(path.Child("filters").Index(i).Child("type"), filter.Type, supportedHTTPRouteFilters))
+		}
+	}
+	for i, ref := range rule.BackendRefs {
+		for j, filter := range ref.Filters {
+			if filter.Type == gatewayv1.HTTPRouteFilterExtensionRef {
+				errs = append(errs, field.NotSupported(path.Child("backendRefs").Index(i).Child("filters").Index(j).Child("type"), filter.Type, supportedHTTPRouteFilters))
+			}
+		}
+	}
+	return errs
+}
+
+// unsupportedGRPCRouteFilters returns the filters of a GRPCRoute rule, none of which cloudflared implements
+func unsupportedGRPCRouteFilters(path *field.Path, rule gatewayv1.GRPCRouteRule) field.ErrorList {
+	var errs field.ErrorList
+	for i, filter := range rule.Filters {
+		errs = append(errs, field.NotSupported(path.Child("filters").Index(i).Child("type"), filter.Type, []gatewayv1.GRPCRouteFilterType{}))
+	}
+	for i, ref := range rule.BackendRefs {
+		for j, filter := range ref.Filters {
+			errs = append(errs, field.NotSupported(path.Child("backendRefs").Index(i).Child("filters").Index(j).Child("type"), filter.Type, []gatewayv1.GRPCRouteFilterType{}))
+		}
+	}
+	return errs
+}
+
+var supportedHTTPRouteFilters = []gatewayv1.HTTPRouteFilterType{
+	gatewayv1.HTTPRouteFilterRequestHeaderModifier,
+	gatewayv1.HTTPRouteFilterResponseHeaderModifier,
+	gatewayv1.HTTPRouteFilterRequestRedirect,
+	gatewayv1.HTTPRouteFilterURLRewrite,
+	gatewayv1.HTTPRouteFilterRequestMirror,
+}
+
+func validateRegex(path *field.Path, value string) field.ErrorList {
+	if _, err := regexp.Compile(value); err != nil {
+		return field.ErrorList{field.Invalid(path, value, err.Error())}
+	}
+	return nil
+}
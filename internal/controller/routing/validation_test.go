@@ -0,0 +1,133 @@
+package routing
+
+import (
+	"testing"
+
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestInDomain(t *testing.T) {
+	tests := []struct {
+		hostname string
+		want     bool
+	}{
+		{hostname: "example.com", want: true},
+		{hostname: "a.example.com", want: true},
+		{hostname: "A.Example.com", want: true},
+		{hostname: "*.example.com", want: true},
+		{hostname: "*.a.example.com", want: true},
+		{hostname: "badexample.com", want: false},
+		{hostname: "example.com.evil.org", want: false},
+		{hostname: "*.org", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hostname, func(t *testing.T) {
+			if got := InDomain(tt.hostname, "example.com"); got != tt.want {
+				t.Errorf("InDomain() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateHTTPRoute(t *testing.T) {
+	regex := func(value string) gatewayv1.HTTPRouteMatch {
+		return gatewayv1.HTTPRouteMatch{Path: &gatewayv1.HTTPPathMatch{Type: ptr.To(gatewayv1.PathMatchRegularExpression), Value: ptr.To(value)}}
+	}
+	extensionRef := gatewayv1.HTTPRouteFilter{
+		Type:         gatewayv1.HTTPRouteFilterExtensionRef,
+		ExtensionRef: &gatewayv1.LocalObjectReference{Group: "example.com", Kind: "Filter", Name: "filter"},
+	}
+
+	tests := []struct {
+		name       string
+		hostnames  []gatewayv1.Hostname
+		domain     string
+		rules      []gatewayv1.HTTPRouteRule
+		wantFields []string
+	}{
+		{
+			name:      "Valid route",
+			hostnames: []gatewayv1.Hostname{"a.example.com", "*.example.com"},
+			domain:    "example.com",
+			rules:     []gatewayv1.HTTPRouteRule{{Matches: []gatewayv1.HTTPRouteMatch{regex("^/api/v[0-9]+$")}}},
+		},
+		{
+			name:       "Hostnames outside of the domain",
+			hostnames:  []gatewayv1.Hostname{"a.example.com", "a.example.org"},
+			domain:     "example.com",
+			wantFields: []string{"spec.hostnames[1]"},
+		},
+		{
+			name:      "Hostnames are not checked without a domain",
+			hostnames: []gatewayv1.Hostname{"a.example.org"},
+		},
+		{
+			name: "Regular expressions that don't compile",
+			rules: []gatewayv1.HTTPRouteRule{
+				{Matches: []gatewayv1.HTTPRouteMatch{regex("^/(api$")}},
+				{Matches: []gatewayv1.HTTPRouteMatch{{
+					Headers:     []gatewayv1.HTTPHeaderMatch{{Type: ptr.To(gatewayv1.HeaderMatchRegularExpression), Name: "x-version", Value: "v[2"}},
+					QueryParams: []gatewayv1.HTTPQueryParamMatch{{Type: ptr.To(gatewayv1.QueryParamMatchRegularExpression), Name: "q", Value: "(?<"}},
+				}}},
+			},
+			wantFields: []string{
+				"spec.rules[0].matches[0].path.value",
+				"spec.rules[1].matches[0].headers[0].value",
+				"spec.rules[1].matches[0].queryParams[0].value",
+			},
+		},
+		{
+			name: "Exact matches are not regular expressions",
+			rules: []gatewayv1.HTTPRouteRule{{Matches: []gatewayv1.HTTPRouteMatch{{
+				Headers: []gatewayv1.HTTPHeaderMatch{{Name: "x-version", Value: "v[2"}},
+			}}}},
+		},
+		{
+			name: "ExtensionRef filters",
+			rules: []gatewayv1.HTTPRouteRule{{
+				Filters:     []gatewayv1.HTTPRouteFilter{extensionRef},
+				BackendRefs: []gatewayv1.HTTPBackendRef{{Filters: []gatewayv1.HTTPRouteFilter{extensionRef}}},
+			}},
+			wantFields: []string{"spec.rules[0].filters[0].type", "spec.rules[0].backendRefs[0].filters[0].type"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := &gatewayv1.HTTPRoute{Spec: gatewayv1.HTTPRouteSpec{Hostnames: tt.hostnames, Rules: tt.rules}}
+			errs := ValidateHTTPRoute(route, tt.domain)
+			if len(errs) != len(tt.wantFields) {
+				t.Fatalf("ValidateHTTPRoute() = %v, want errors for %v", errs, tt.wantFields)
+			}
+			for i, err := range errs {
+				if err.Field != tt.wantFields[i] {
+					t.Errorf("error %d is for %s, want %s", i, err.Field, tt.wantFields[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateGRPCRoute(t *testing.T) {
+	route := &gatewayv1.GRPCRoute{Spec: gatewayv1.GRPCRouteSpec{Rules: []gatewayv1.GRPCRouteRule{
+		{Matches: []gatewayv1.GRPCRouteMatch{{Method: &gatewayv1.GRPCMethodMatch{
+			Type:    ptr.To(gatewayv1.GRPCMethodMatchRegularExpression),
+			Service: ptr.To("foo\\.v[1"),
+			Method:  ptr.To("Get.*"),
+		}}}},
+		{Filters: []gatewayv1.GRPCRouteFilter{{Type: gatewayv1.GRPCRouteFilterRequestHeaderModifier}}},
+	}}}
+	wantFields := []string{"spec.rules[0].matches[0].method.service", "spec.rules[1].filters[0].type"}
+
+	errs := ValidateGRPCRoute(route, "")
+	if len(errs) != len(wantFields) {
+		t.Fatalf("ValidateGRPCRoute() = %v, want errors for %v", errs, wantFields)
+	}
+	for i, err := range errs {
+		if err.Field != wantFields[i] {
+			t.Errorf("error %d is for %s, want %s", i, err.Field, wantFields[i])
+		}
+	}
+}
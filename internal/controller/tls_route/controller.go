@@ -0,0 +1,99 @@
+package tls_route
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller/routing"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ReconciliationLoop represents the data used for a single reconciliation loop
+type ReconciliationLoop struct {
+	logger logr.Logger
+	status *routing.StatusSetter
+}
+
+// Reconciler reconciles a TLSRoute object
+type Reconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Loop   *ReconciliationLoop
+	// Recorder records the events of the TLSRoutes, such as them going into maintenance
+	Recorder record.EventRecorder
+}
+
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=tlsroutes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=tlsroutes/status,verbs=get;update;patch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+// TLSRoutes attach to Passthrough TLS listeners and are rendered into the cloudflared config by the
+// Gateway reconciler as https:// services, with the hostname of the route as the origin server name.
+// This reconciler reports on the route how that rendering went.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.19.0/pkg/reconcile
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	defaultResult := ctrl.Result{Requeue: true, RequeueAfter: 60 * time.Second}
+	r.Loop = &ReconciliationLoop{
+		logger: log.FromContext(ctx),
+		status: routing.NewStatusSetter(r.Client),
+	}
+	r.Loop.logger.Info(fmt.Sprintf("Reconciling TLSRoute: %s", req.NamespacedName))
+
+	tlsRoute := &gatewayv1alpha2.TLSRoute{}
+	if err := r.Get(ctx, req.NamespacedName, tlsRoute); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	observedStatus := tlsRoute.Status.DeepCopy()
+
+	isMine, err := r.Loop.status.SetParentStatuses(ctx, routing.FromTLSRoute(tlsRoute), &tlsRoute.Status.RouteStatus, tlsRoute.Generation)
+	if err != nil {
+		r.Loop.logger.Error(err, "failed to determine tlsRoute status")
+		return defaultResult, err
+	}
+	if !isMine {
+		// Don't requeue this for processing if we don't own this resource
+		r.Loop.logger.Info(fmt.Sprintf("tlsRoute %s is not mine", req.NamespacedName))
+		return ctrl.Result{}, nil
+	}
+
+	if equality.Semantic.DeepEqual(observedStatus, &tlsRoute.Status) {
+		return ctrl.Result{}, nil
+	}
+	if err := r.Status().Update(ctx, tlsRoute); err != nil {
+		r.Loop.logger.Error(err, "failed to update tlsRoute status")
+		return defaultResult, err
+	}
+	routing.RecordMaintenance(r.Recorder, tlsRoute, &observedStatus.RouteStatus, &tlsRoute.Status.RouteStatus)
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	enqueueRoutes := routing.EnqueueRoutes(mgr.GetClient(), routing.KindTLSRoute)
+	builder := ctrl.NewControllerManagedBy(mgr).
+		For(&gatewayv1alpha2.TLSRoute{}).
+		Watches(&gatewayv1.Gateway{}, enqueueRoutes).
+		Watches(&corev1.Service{}, routing.EnqueueServiceRoutes(mgr.GetClient(), routing.KindTLSRoute)).
+		Watches(&discoveryv1.EndpointSlice{}, routing.EnqueueEndpointSliceRoutes(mgr.GetClient(), routing.KindTLSRoute))
+	if routing.Installed(mgr, &gatewayv1beta1.ReferenceGrant{}) {
+		builder = builder.Watches(&gatewayv1beta1.ReferenceGrant{}, routing.EnqueueReferenceGrantRoutes(mgr.GetClient(), routing.KindTLSRoute))
+	}
+	return routing.WatchRoutes(mgr, builder, enqueueRoutes).Complete(r)
+}
@@ -0,0 +1,131 @@
+package router
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	defaultRedirectStatusCode = http.StatusFound
+)
+
+// modifyHeaders applies a header modifier to the headers of a request or response
+func modifyHeaders(header http.Header, modifier *gatewayv1.HTTPHeaderFilter) {
+	if modifier == nil {
+		return
+	}
+	for _, h := range modifier.Set {
+		header.Set(string(h.Name), h.Value)
+	}
+	for _, h := range modifier.Add {
+		header.Add(string(h.Name), h.Value)
+	}
+	for _, name := range modifier.Remove {
+		header.Del(name)
+	}
+}
+
+// headerModifiers returns the header modifiers of the filters of filterType
+func headerModifiers(filters []gatewayv1.HTTPRouteFilter, filterType gatewayv1.HTTPRouteFilterType) []*gatewayv1.HTTPHeaderFilter {
+	var modifiers []*gatewayv1.HTTPHeaderFilter
+	for _, filter := range filters {
+		switch {
+		case filter.Type != filterType:
+		case filterType == gatewayv1.HTTPRouteFilterRequestHeaderModifier && filter.RequestHeaderModifier != nil:
+			modifiers = append(modifiers, filter.RequestHeaderModifier)
+		case filterType == gatewayv1.HTTPRouteFilterResponseHeaderModifier && filter.ResponseHeaderModifier != nil:
+			modifiers = append(modifiers, filter.ResponseHeaderModifier)
+		}
+	}
+	return modifiers
+}
+
+func requestRedirect(filters []gatewayv1.HTTPRouteFilter) *gatewayv1.HTTPRequestRedirectFilter {
+	for _, filter := range filters {
+		if filter.Type == gatewayv1.HTTPRouteFilterRequestRedirect && filter.RequestRedirect != nil {
+			return filter.RequestRedirect
+		}
+	}
+	return nil
+}
+
+func urlRewrite(filters []gatewayv1.HTTPRouteFilter) *gatewayv1.HTTPURLRewriteFilter {
+	for _, filter := range filters {
+		if filter.Type == gatewayv1.HTTPRouteFilterURLRewrite && filter.URLRewrite != nil {
+			return filter.URLRewrite
+		}
+	}
+	return nil
+}
+
+// redirectLocation returns where a RequestRedirect filter sends a request matched by match. The scheme of the
+// request is taken from X-Forwarded-Proto, which cloudflared sets to the scheme clients used
+func redirectLocation(request *http.Request, match gatewayv1.HTTPRouteMatch, redirect *gatewayv1.HTTPRequestRedirectFilter) string {
+	scheme := request.Header.Get("X-Forwarded-Proto")
+	if scheme == "" {
+		scheme = "http"
+	}
+	hostname, port := request.Host, ""
+	if h, p, err := net.SplitHostPort(request.Host); err == nil {
+		hostname, port = h, p
+	}
+	if redirect.Scheme != nil && *redirect.Scheme != scheme {
+		// a changed scheme drops the port of the request, which belongs to the previous scheme
+		scheme, port = *redirect.Scheme, ""
+	}
+	if redirect.Hostname != nil {
+		hostname = string(*redirect.Hostname)
+	}
+	if redirect.Port != nil {
+		port = strconv.Itoa(int(*redirect.Port))
+	}
+	if (scheme == "https" && port == "443") || (scheme == "http" && port == "80") {
+		port = ""
+	}
+	host := hostname
+	if port != "" {
+		host = net.JoinHostPort(hostname, port)
+	}
+	location := url.URL{
+		Scheme:   scheme,
+		Host:     host,
+		Path:     modifiedPath(request.URL.Path, match, redirect.Path),
+		RawQuery: request.URL.RawQuery,
+	}
+	return location.String()
+}
+
+func redirectStatusCode(redirect *gatewayv1.HTTPRequestRedirectFilter) int {
+	return ptr.Deref(redirect.StatusCode, defaultRedirectStatusCode)
+}
+
+// modifiedPath applies a path modifier to the path of a request matched by match. Prefix replacements
+// need a PathPrefix match, which the controller ensures
+func modifiedPath(path string, match gatewayv1.HTTPRouteMatch, modifier *gatewayv1.HTTPPathModifier) string {
+	if modifier == nil {
+		return path
+	}
+	switch modifier.Type {
+	case gatewayv1.FullPathHTTPPathModifier:
+		return ptr.Deref(modifier.ReplaceFullPath, "/")
+	case gatewayv1.PrefixMatchHTTPPathModifier:
+		if match.Path == nil || match.Path.Value == nil {
+			return path
+		}
+		prefix := strings.TrimSuffix(*match.Path.Value, "/")
+		replacement := strings.TrimSuffix(ptr.Deref(modifier.ReplacePrefixMatch, "/"), "/")
+		rest := strings.TrimPrefix(path, prefix)
+		if replacement == "" && !strings.HasPrefix(rest, "/") {
+			return "/" + rest
+		}
+		return replacement + rest
+	default:
+		return path
+	}
+}
@@ -0,0 +1,152 @@
+package router
+
+import (
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// compiledRule is a rule of the table with its regular expressions compiled
+type compiledRule struct {
+	Rule
+	path        *regexp.Regexp
+	headers     []valueMatcher
+	queryParams []valueMatcher
+	weight      int64
+}
+
+// valueMatcher matches the values of a header or query parameter
+type valueMatcher struct {
+	name  string
+	value string
+	regex *regexp.Regexp
+}
+
+func (m valueMatcher) matches(value string) bool {
+	if m.regex != nil {
+		return m.regex.MatchString(value)
+	}
+	return value == m.value
+}
+
+func compileRule(rule Rule) (*compiledRule, error) {
+	compiled := &compiledRule{Rule: rule}
+	if rule.Match.Path != nil && ptr.Deref(rule.Match.Path.Type, "") == gatewayv1.PathMatchRegularExpression {
+		regex, err := regexp.Compile(anchored(ptr.Deref(rule.Match.Path.Value, "")))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid path of %s", rule.Name)
+		}
+		compiled.path = regex
+	}
+	for _, header := range rule.Match.Headers {
+		matcher := valueMatcher{name: http.CanonicalHeaderKey(string(header.Name)), value: header.Value}
+		if ptr.Deref(header.Type, gatewayv1.HeaderMatchExact) == gatewayv1.HeaderMatchRegularExpression {
+			regex, err := regexp.Compile(header.Value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid header match %s of %s", header.Name, rule.Name)
+			}
+			matcher.regex = regex
+		}
+		compiled.headers = append(compiled.headers, matcher)
+	}
+	for _, param := range rule.Match.QueryParams {
+		matcher := valueMatcher{name: string(param.Name), value: param.Value}
+		if ptr.Deref(param.Type, gatewayv1.QueryParamMatchExact) == gatewayv1.QueryParamMatchRegularExpression {
+			regex, err := regexp.Compile(param.Value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid query parameter match %s of %s", param.Name, rule.Name)
+			}
+			matcher.regex = regex
+		}
+		compiled.queryParams = append(compiled.queryParams, matcher)
+	}
+	for _, backend := range rule.Backends {
+		if backend.Weight > 0 {
+			compiled.weight += int64(backend.Weight)
+		}
+	}
+	return compiled, nil
+}
+
+// anchored makes a regular expression match whole paths, the way HTTPRoute path matches are meant
+func anchored(expression string) string {
+	return "^(?:" + strings.TrimSuffix(strings.TrimPrefix(expression, "^"), "$") + ")$"
+}
+
+// matches reports whether the rule handles a request
+func (r *compiledRule) matches(request *http.Request) bool {
+	return hostnameMatches(r.Hostname, request.Host) &&
+		r.pathMatches(request.URL.Path) &&
+		(r.Match.Method == nil || string(*r.Match.Method) == request.Method) &&
+		r.headersMatch(request.Header) &&
+		r.queryParamsMatch(request)
+}
+
+func (r *compiledRule) pathMatches(path string) bool {
+	if r.path != nil {
+		return r.path.MatchString(path)
+	}
+	if r.Match.Path == nil || r.Match.Path.Value == nil {
+		return true
+	}
+	value := *r.Match.Path.Value
+	if ptr.Deref(r.Match.Path.Type, gatewayv1.PathMatchPathPrefix) == gatewayv1.PathMatchExact {
+		return path == value
+	}
+	return prefixMatches(value, path)
+}
+
+// prefixMatches reports whether path starts with the path elements of prefix
+func prefixMatches(prefix string, path string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+func (r *compiledRule) headersMatch(header http.Header) bool {
+	for _, matcher := range r.headers {
+		values, ok := header[matcher.name]
+		if !ok {
+			return false
+		}
+		// repeated headers are matched as their values joined by commas, RFC 9110 section 5.3
+		if !matcher.matches(strings.Join(values, ",")) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *compiledRule) queryParamsMatch(request *http.Request) bool {
+	if len(r.queryParams) == 0 {
+		return true
+	}
+	query := request.URL.Query()
+	for _, matcher := range r.queryParams {
+		values, ok := query[matcher.name]
+		// only the first value of a repeated parameter is matched, as the Gateway API recommends
+		if !ok || !matcher.matches(values[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+// hostnameMatches reports whether the Host of a request is hostname, or a subdomain of a wildcard hostname
+func hostnameMatches(hostname string, host string) bool {
+	if hostname == "" {
+		return true
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	if strings.HasPrefix(hostname, "*.") {
+		return strings.HasSuffix(host, hostname[1:])
+	}
+	return host == hostname
+}
@@ -0,0 +1,222 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	// maxMirroredBody is the largest request body that is copied to mirrors, larger bodies are not mirrored
+	maxMirroredBody = 1 << 20
+	mirrorTimeout   = 30 * time.Second
+)
+
+// Router is a HTTP handler that routes requests through the rules of a Table. It sits between cloudflared and
+// the Services of a Gateway and implements the HTTPRoute features cloudflared can't express
+type Router struct {
+	logger    logr.Logger
+	transport http.RoundTripper
+	rules     atomic.Pointer[[]*compiledRule]
+}
+
+func New(logger logr.Logger, transport http.RoundTripper) *Router {
+	router := &Router{logger: logger, transport: transport}
+	router.rules.Store(&[]*compiledRule{})
+	return router
+}
+
+// Load replaces the rules of the router with those of table. The previous rules stay in place when the table is invalid
+func (r *Router) Load(table *Table) error {
+	rules := make([]*compiledRule, 0, len(table.Rules))
+	for _, rule := range table.Rules {
+		compiled, err := compileRule(rule)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, compiled)
+	}
+	r.rules.Store(&rules)
+	return nil
+}
+
+func (r *Router) match(request *http.Request) *compiledRule {
+	for _, rule := range *r.rules.Load() {
+		if rule.matches(request) {
+			return rule
+		}
+	}
+	return nil
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, request *http.Request) {
+	rule := r.match(request)
+	if rule == nil {
+		http.NotFound(w, request)
+		return
+	}
+	if redirect := requestRedirect(rule.Filters); redirect != nil {
+		http.Redirect(w, request, redirectLocation(request, rule.Match, redirect), redirectStatusCode(redirect))
+		return
+	}
+	backend := rule.pick()
+	if backend != nil && backend.StatusCode != 0 {
+		http.Error(w, http.StatusText(backend.StatusCode), backend.StatusCode)
+		return
+	}
+	if backend == nil || backend.URL == "" {
+		// the Gateway API asks for a 500 when there is no backend to send a request to
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	target, err := url.Parse(backend.URL)
+	if err != nil {
+		r.logger.Error(err, "invalid backend", "rule", rule.Name, "url", backend.URL)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if timeout := rule.timeout(); timeout > 0 {
+		ctx, cancel := context.WithTimeout(request.Context(), timeout)
+		defer cancel()
+		request = request.WithContext(ctx)
+	}
+	mirrors := append(append([]Mirror{}, rule.Mirrors...), backend.Mirrors...)
+	if len(mirrors) > 0 {
+		r.mirror(request, mirrors)
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Transport: r.transport,
+		Rewrite: func(proxied *httputil.ProxyRequest) {
+			proxied.SetURL(target)
+			// the backend sees the hostname the client asked for, unless a URLRewrite filter replaces it
+			proxied.Out.Host = proxied.In.Host
+			proxied.SetXForwarded()
+			if forwardedProto := proxied.In.Header.Get("X-Forwarded-Proto"); forwardedProto != "" {
+				proxied.Out.Header.Set("X-Forwarded-Proto", forwardedProto)
+			}
+			if rewrite := urlRewrite(rule.Filters); rewrite != nil {
+				if rewrite.Hostname != nil {
+					proxied.Out.Host = string(*rewrite.Hostname)
+				}
+				proxied.Out.URL.Path = modifiedPath(proxied.In.URL.Path, rule.Match, rewrite.Path)
+				proxied.Out.URL.RawPath = ""
+			}
+			for _, modifier := range headerModifiers(rule.Filters, gatewayv1.HTTPRouteFilterRequestHeaderModifier) {
+				modifyHeaders(proxied.Out.Header, modifier)
+			}
+			for _, modifier := range headerModifiers(backend.Filters, gatewayv1.HTTPRouteFilterRequestHeaderModifier) {
+				modifyHeaders(proxied.Out.Header, modifier)
+			}
+		},
+		ModifyResponse: func(response *http.Response) error {
+			for _, modifier := range headerModifiers(rule.Filters, gatewayv1.HTTPRouteFilterResponseHeaderModifier) {
+				modifyHeaders(response.Header, modifier)
+			}
+			for _, modifier := range headerModifiers(backend.Filters, gatewayv1.HTTPRouteFilterResponseHeaderModifier) {
+				modifyHeaders(response.Header, modifier)
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, request *http.Request, err error) {
+			r.logger.Info("backend request failed", "rule", rule.Name, "url", backend.URL, "error", err.Error())
+			status := http.StatusBadGateway
+			if errors.Is(err, context.DeadlineExceeded) {
+				status = http.StatusGatewayTimeout
+			}
+			http.Error(w, http.StatusText(status), status)
+		},
+	}
+	proxy.ServeHTTP(w, request)
+}
+
+// pick chooses a backend at random in proportion to the weights, nil when no backend has a weight
+func (r *compiledRule) pick() *Backend {
+	if r.weight == 0 {
+		return nil
+	}
+	n := rand.Int64N(r.weight)
+	for i := range r.Backends {
+		if r.Backends[i].Weight <= 0 {
+			continue
+		}
+		n -= int64(r.Backends[i].Weight)
+		if n < 0 {
+			return &r.Backends[i]
+		}
+	}
+	return nil
+}
+
+// timeout returns how long the router waits for a response, zero to wait as long as the client does
+func (r *compiledRule) timeout() time.Duration {
+	if r.Timeouts == nil {
+		return 0
+	}
+	for _, timeout := range []*gatewayv1.Duration{r.Timeouts.BackendRequest, r.Timeouts.Request} {
+		if timeout == nil {
+			continue
+		}
+		// the duration format of the Gateway API is a subset of the one of Go
+		duration, err := time.ParseDuration(string(*timeout))
+		if err == nil && duration > 0 {
+			return duration
+		}
+	}
+	return 0
+}
+
+// mirror sends copies of a request to the mirrors, without waiting for their responses. The body of the
+// request is buffered so that it can be read again by the backend
+func (r *Router) mirror(request *http.Request, mirrors []Mirror) {
+	var body []byte
+	if request.Body != nil && request.Body != http.NoBody {
+		buffered, err := io.ReadAll(io.LimitReader(request.Body, maxMirroredBody+1))
+		request.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(buffered), request.Body), request.Body}
+		if err != nil || len(buffered) > maxMirroredBody {
+			r.logger.V(1).Info("not mirroring request with a large body", "path", request.URL.Path)
+			return
+		}
+		body = buffered
+	}
+	for _, mirror := range mirrors {
+		if mirror.Fraction < 1 && rand.Float64() >= mirror.Fraction {
+			continue
+		}
+		target, err := url.Parse(mirror.URL)
+		if err != nil {
+			r.logger.Error(err, "invalid mirror", "url", mirror.URL)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), mirrorTimeout)
+		copied := request.Clone(ctx)
+		copied.RequestURI = ""
+		copied.URL.Scheme = target.Scheme
+		copied.URL.Host = target.Host
+		copied.Body = io.NopCloser(bytes.NewReader(body))
+		go func() {
+			defer cancel()
+			response, err := r.transport.RoundTrip(copied)
+			if err != nil {
+				r.logger.V(1).Info("mirrored request failed", "url", mirror.URL, "error", err.Error())
+				return
+			}
+			_, _ = io.Copy(io.Discard, response.Body)
+			_ = response.Body.Close()
+		}()
+	}
+}
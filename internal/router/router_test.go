@@ -0,0 +1,226 @@
+package router
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// echo answers with the name of the backend, the path and the x-version header it received
+func echo(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Backend", name)
+		_, _ = io.WriteString(w, name+" "+r.URL.Path+" "+r.Header.Get("X-Version"))
+	}))
+}
+
+func TestRouter(t *testing.T) {
+	stable := echo("stable")
+	defer stable.Close()
+	canary := echo("canary")
+	defer canary.Close()
+
+	prefix := func(value string) *gatewayv1.HTTPPathMatch {
+		return &gatewayv1.HTTPPathMatch{Type: ptr.To(gatewayv1.PathMatchPathPrefix), Value: ptr.To(value)}
+	}
+	table := &Table{Rules: []Rule{
+		{
+			Name:     "canary by header",
+			Hostname: "a.example.com",
+			Match: gatewayv1.HTTPRouteMatch{
+				Path:    prefix("/"),
+				Headers: []gatewayv1.HTTPHeaderMatch{{Name: "x-canary", Value: "true"}},
+			},
+			Backends: []Backend{{
+				URL:    canary.URL,
+				Weight: 1,
+				Filters: []gatewayv1.HTTPRouteFilter{{
+					Type:                  gatewayv1.HTTPRouteFilterRequestHeaderModifier,
+					RequestHeaderModifier: &gatewayv1.HTTPHeaderFilter{Set: []gatewayv1.HTTPHeader{{Name: "x-version", Value: "v2"}}},
+				}},
+			}},
+		},
+		{
+			Name:     "canary by query parameter",
+			Hostname: "a.example.com",
+			Match: gatewayv1.HTTPRouteMatch{
+				Path:        prefix("/"),
+				QueryParams: []gatewayv1.HTTPQueryParamMatch{{Name: "canary", Value: "1"}},
+			},
+			Backends: []Backend{{URL: stable.URL}, {URL: canary.URL, Weight: 1}},
+		},
+		{
+			Name:     "posts",
+			Hostname: "a.example.com",
+			Match:    gatewayv1.HTTPRouteMatch{Path: prefix("/"), Method: ptr.To(gatewayv1.HTTPMethodPost)},
+			Filters: []gatewayv1.HTTPRouteFilter{{
+				Type:            gatewayv1.HTTPRouteFilterRequestRedirect,
+				RequestRedirect: &gatewayv1.HTTPRequestRedirectFilter{Hostname: ptr.To(gatewayv1.PreciseHostname("b.example.com")), StatusCode: ptr.To(301)},
+			}},
+		},
+		{
+			Name:     "api",
+			Hostname: "*.example.com",
+			Match:    gatewayv1.HTTPRouteMatch{Path: prefix("/api")},
+			Filters: []gatewayv1.HTTPRouteFilter{{
+				Type: gatewayv1.HTTPRouteFilterURLRewrite,
+				URLRewrite: &gatewayv1.HTTPURLRewriteFilter{Path: &gatewayv1.HTTPPathModifier{
+					Type:               gatewayv1.PrefixMatchHTTPPathModifier,
+					ReplacePrefixMatch: ptr.To("/"),
+				}},
+			}, {
+				Type:                   gatewayv1.HTTPRouteFilterResponseHeaderModifier,
+				ResponseHeaderModifier: &gatewayv1.HTTPHeaderFilter{Remove: []string{"X-Backend"}},
+			}},
+			Backends: []Backend{{URL: stable.URL, Weight: 1}},
+		},
+		{
+			Name:     "static response",
+			Hostname: "m.example.com",
+			Match:    gatewayv1.HTTPRouteMatch{Path: prefix("/")},
+			Backends: []Backend{{StatusCode: http.StatusServiceUnavailable, Weight: 1}},
+		},
+		{
+			Name:     "no backends",
+			Hostname: "a.example.com",
+			Match:    gatewayv1.HTTPRouteMatch{Path: prefix("/")},
+			Backends: []Backend{{URL: stable.URL, Weight: 0}},
+		},
+	}}
+	r := New(logr.Discard(), http.DefaultTransport)
+	if err := r.Load(table); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		url          string
+		header       http.Header
+		wantStatus   int
+		wantBody     string
+		wantLocation string
+		wantBackend  string
+	}{
+		{
+			name:        "Header match with a backend filter",
+			url:         "http://a.example.com/",
+			header:      http.Header{"X-Canary": {"true"}},
+			wantStatus:  http.StatusOK,
+			wantBody:    "canary / v2",
+			wantBackend: "canary",
+		},
+		{
+			name:        "Query parameter match skips backends without weight",
+			url:         "http://a.example.com/?canary=1",
+			wantStatus:  http.StatusOK,
+			wantBody:    "canary / ",
+			wantBackend: "canary",
+		},
+		{
+			name:         "Method match redirecting",
+			method:       http.MethodPost,
+			url:          "http://a.example.com/form?x=1",
+			header:       http.Header{"X-Forwarded-Proto": {"https"}},
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "https://b.example.com/form?x=1",
+		},
+		{
+			name:       "Wildcard hostname with a prefix rewrite and response header removal",
+			url:        "http://b.example.com/api/users",
+			wantStatus: http.StatusOK,
+			wantBody:   "stable /users ",
+		},
+		{
+			name:       "Rules without weighted backends answer with a 500",
+			url:        "http://a.example.com/",
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "StaticResponse backends answer with their status",
+			url:        "http://m.example.com/",
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "Requests without a rule are not found",
+			url:        "http://c.other.com/",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			request := httptest.NewRequest(method, tt.url, nil)
+			for name, values := range tt.header {
+				request.Header[name] = values
+			}
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && recorder.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", recorder.Body.String(), tt.wantBody)
+			}
+			if got := recorder.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("location = %q, want %q", got, tt.wantLocation)
+			}
+			if got := recorder.Header().Get("X-Backend"); got != tt.wantBackend {
+				t.Errorf("backend header = %q, want %q", got, tt.wantBackend)
+			}
+		})
+	}
+}
+
+func TestModifiedPath(t *testing.T) {
+	match := gatewayv1.HTTPRouteMatch{Path: &gatewayv1.HTTPPathMatch{Value: ptr.To("/api/")}}
+	tests := []struct {
+		name     string
+		path     string
+		modifier gatewayv1.HTTPPathModifier
+		want     string
+	}{
+		{
+			name:     "Strip the prefix",
+			path:     "/api/users",
+			modifier: gatewayv1.HTTPPathModifier{Type: gatewayv1.PrefixMatchHTTPPathModifier, ReplacePrefixMatch: ptr.To("/")},
+			want:     "/users",
+		},
+		{
+			name:     "Strip the prefix of the prefix itself",
+			path:     "/api",
+			modifier: gatewayv1.HTTPPathModifier{Type: gatewayv1.PrefixMatchHTTPPathModifier, ReplacePrefixMatch: ptr.To("/")},
+			want:     "/",
+		},
+		{
+			name:     "Replace the prefix",
+			path:     "/api/users",
+			modifier: gatewayv1.HTTPPathModifier{Type: gatewayv1.PrefixMatchHTTPPathModifier, ReplacePrefixMatch: ptr.To("/v2/")},
+			want:     "/v2/users",
+		},
+		{
+			name:     "Replace the full path",
+			path:     "/api/users",
+			modifier: gatewayv1.HTTPPathModifier{Type: gatewayv1.FullPathHTTPPathModifier, ReplaceFullPath: ptr.To("/index.html")},
+			want:     "/index.html",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := modifiedPath(tt.path, match, &tt.modifier); got != tt.want {
+				t.Errorf("modifiedPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package router
+
+import (
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// Table is the routing table the controller compiles for the router of a Gateway from the HTTPRoute rules
+// cloudflared hands to the router
+type Table struct {
+	// Rules are matched in order, the first rule matching a request handles it
+	Rules []Rule `json:"rules"`
+}
+
+// Rule routes the requests matching a hostname and a HTTPRoute match to weighted backends
+type Rule struct {
+	// Name identifies the HTTPRoute rule the rule was compiled from
+	Name string `json:"name"`
+	// Hostname is the hostname of the requests, a leading wildcard matches every subdomain and an empty
+	// hostname every request
+	Hostname string                   `json:"hostname,omitempty"`
+	Match    gatewayv1.HTTPRouteMatch `json:"match"`
+	// Filters are the filters of the HTTPRoute rule, RequestMirror filters are resolved into Mirrors
+	Filters  []gatewayv1.HTTPRouteFilter  `json:"filters,omitempty"`
+	Mirrors  []Mirror                     `json:"mirrors,omitempty"`
+	Timeouts *gatewayv1.HTTPRouteTimeouts `json:"timeouts,omitempty"`
+	// Backends are picked at random in proportion to their weight. A rule without backends, or whose
+	// backends all have a weight of zero, answers with a 500
+	Backends []Backend `json:"backends,omitempty"`
+}
+
+// Backend is a weighted backendRef of a rule
+type Backend struct {
+	// URL is the scheme, host and port requests are sent to. It is empty for backendRefs that don't resolve,
+	// whose share of requests is answered with a 500
+	URL    string `json:"url"`
+	Weight int32  `json:"weight"`
+	// StatusCode is the status the requests of StaticResponse backendRefs are answered with, which have no URL
+	StatusCode int `json:"statusCode,omitempty"`
+	// Filters are the filters of the backendRef, applied after those of the rule
+	Filters []gatewayv1.HTTPRouteFilter `json:"filters,omitempty"`
+	Mirrors []Mirror                    `json:"mirrors,omitempty"`
+}
+
+// Mirror is the backend of a RequestMirror filter, which receives a copy of the requests whose responses are discarded
+type Mirror struct {
+	URL string `json:"url"`
+	// Fraction is the share of requests that are mirrored, between 0 and 1
+	Fraction float64 `json:"fraction"`
+}
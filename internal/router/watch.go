@@ -0,0 +1,52 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Watch loads the table at path into the router, and reloads it whenever the file changes until ctx is done.
+// Kubernetes swaps the files of mounted ConfigMaps in place, so the file is polled rather than watched
+func (r *Router) Watch(ctx context.Context, path string, interval time.Duration) error {
+	var loaded []byte
+	reload := func() error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return errors.Wrap(err, "failed to read routing table")
+		}
+		if bytes.Equal(data, loaded) {
+			return nil
+		}
+		table := &Table{}
+		if err := json.Unmarshal(data, table); err != nil {
+			return errors.Wrap(err, "failed to parse routing table")
+		}
+		if err := r.Load(table); err != nil {
+			return err
+		}
+		loaded = data
+		r.logger.Info("loaded routing table", "rules", len(table.Rules))
+		return nil
+	}
+	if err := reload(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := reload(); err != nil {
+				r.logger.Error(err, "keeping the previous routing table")
+			}
+		}
+	}
+}
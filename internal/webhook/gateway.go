@@ -0,0 +1,73 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/k8s"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller/routing"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// +kubebuilder:webhook:path=/validate-gateway-networking-k8s-io-v1-gateway,mutating=false,failurePolicy=ignore,sideEffects=None,groups=gateway.networking.k8s.io,resources=gateways,verbs=create;update,versions=v1,name=vgateway-v1.cloudflare.adamland.xyz,admissionReviewVersions=v1
+
+// GatewayValidator rejects Gateways of a GatewayClass of this controller with listener hostnames outside of
+// the domain of the GatewayClass
+type GatewayValidator struct {
+	Client client.Client
+}
+
+var _ admission.CustomValidator = &GatewayValidator{}
+
+// SetupWithManager registers the validating webhook of Gateways with the Manager
+func (v *GatewayValidator) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&gatewayv1.Gateway{}).
+		WithValidator(v).
+		Complete()
+}
+
+func (v *GatewayValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return v.validate(ctx, obj)
+}
+
+func (v *GatewayValidator) ValidateUpdate(ctx context.Context, _ runtime.Object, newObj runtime.Object) (admission.Warnings, error) {
+	return v.validate(ctx, newObj)
+}
+
+func (v *GatewayValidator) ValidateDelete(context.Context, runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *GatewayValidator) validate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	gateway, ok := obj.(*gatewayv1.Gateway)
+	if !ok {
+		return nil, errors.Errorf("unexpected object %T", obj)
+	}
+	gatewayClass := &gatewayv1.GatewayClass{}
+	if err := v.Client.Get(ctx, client.ObjectKey{Name: string(gateway.Spec.GatewayClassName)}, gatewayClass); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to get gatewayClass")
+	}
+	if gatewayClass.Spec.ControllerName != controller.Name {
+		return nil, nil
+	}
+
+	config, err := k8s.LoadGatewayClassConfig(ctx, v.Client, gateway)
+	if err != nil {
+		// the Gateway reconciler reports invalid GatewayClass parameters on the Gateway
+		return admission.Warnings{errors.Wrap(err, "failed to load the GatewayClass parameters").Error()}, nil
+	}
+	if errs := routing.ValidateGateway(gateway, config.Domain); len(errs) > 0 {
+		return nil, apierrors.NewInvalid(gatewayv1.SchemeGroupVersion.WithKind(string(routing.KindGateway)).GroupKind(), gateway.Name, errs)
+	}
+	return nil, nil
+}
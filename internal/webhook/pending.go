@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// pendingReader reads the cluster as if the object under admission had already been applied, so that the
+// routing table of a Gateway can be built with it
+type pendingReader struct {
+	client.Reader
+	scheme  *runtime.Scheme
+	pending client.Object
+	gvk     schema.GroupVersionKind
+}
+
+func newPendingReader(c client.Reader, scheme *runtime.Scheme, pending client.Object) (*pendingReader, error) {
+	gvk, err := apiutil.GVKForObject(pending, scheme)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the kind of the object")
+	}
+	return &pendingReader{Reader: c, scheme: scheme, pending: pending, gvk: gvk}, nil
+}
+
+// List lists the objects of the cluster, replacing or adding the pending object in lists of its kind
+func (r *pendingReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if err := r.Reader.List(ctx, list, opts...); err != nil {
+		return err
+	}
+	gvk, err := apiutil.GVKForObject(list, r.scheme)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the kind of the list")
+	}
+	if gvk.GroupVersion() != r.gvk.GroupVersion() || gvk.Kind != r.gvk.Kind+"List" {
+		return nil
+	}
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	if listOpts.Namespace != "" && listOpts.Namespace != r.pending.GetNamespace() {
+		return nil
+	}
+
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return errors.Wrap(err, "failed to read the list")
+	}
+	merged := make([]runtime.Object, 0, len(items)+1)
+	for _, item := range items {
+		obj, ok := item.(client.Object)
+		if ok && client.ObjectKeyFromObject(obj) == client.ObjectKeyFromObject(r.pending) {
+			continue
+		}
+		merged = append(merged, item)
+	}
+	merged = append(merged, r.pending)
+	return meta.SetList(list, merged)
+}
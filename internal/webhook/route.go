@@ -0,0 +1,194 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/clients/k8s"
+	"github.com/cyclingwithelephants/cloudflare-gateway-controller/internal/controller/routing"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+)
+
+// +kubebuilder:webhook:path=/validate-gateway-networking-k8s-io-v1-httproute,mutating=false,failurePolicy=ignore,sideEffects=None,groups=gateway.networking.k8s.io,resources=httproutes,verbs=create;update,versions=v1,name=vhttproute-v1.cloudflare.adamland.xyz,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-gateway-networking-k8s-io-v1-grpcroute,mutating=false,failurePolicy=ignore,sideEffects=None,groups=gateway.networking.k8s.io,resources=grpcroutes,verbs=create;update,versions=v1,name=vgrpcroute-v1.cloudflare.adamland.xyz,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-gateway-networking-k8s-io-v1alpha2-tcproute,mutating=false,failurePolicy=ignore,sideEffects=None,groups=gateway.networking.k8s.io,resources=tcproutes,verbs=create;update,versions=v1alpha2,name=vtcproute-v1alpha2.cloudflare.adamland.xyz,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-gateway-networking-k8s-io-v1alpha2-tlsroute,mutating=false,failurePolicy=ignore,sideEffects=None,groups=gateway.networking.k8s.io,resources=tlsroutes,verbs=create;update,versions=v1alpha2,name=vtlsroute-v1alpha2.cloudflare.adamland.xyz,admissionReviewVersions=v1
+
+// RouteValidator rejects routes of any kind that are bound to a Gateway of this controller and that the Gateway
+// can't serve: hostnames outside of the domain of the GatewayClass, regular expressions that don't compile,
+// unsupported filters and hostnames and paths that another route already claims or serves. The fields cloudflared
+// only approximates and the backendRefs that don't resolve yet are returned as warnings. Routes bound to
+// other controllers are never rejected
+type RouteValidator struct {
+	Client client.Client
+}
+
+var _ admission.CustomValidator = &RouteValidator{}
+
+// SetupWithManager registers the validating webhook of the route kind of obj with the Manager
+func (v *RouteValidator) SetupWithManager(mgr ctrl.Manager, obj client.Object) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(obj).
+		WithValidator(v).
+		Complete()
+}
+
+func (v *RouteValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return v.validate(ctx, obj)
+}
+
+func (v *RouteValidator) ValidateUpdate(ctx context.Context, _ runtime.Object, newObj runtime.Object) (admission.Warnings, error) {
+	return v.validate(ctx, newObj)
+}
+
+func (v *RouteValidator) ValidateDelete(context.Context, runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *RouteValidator) validate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	object, ok := obj.(client.Object)
+	if !ok {
+		return nil, errors.Errorf("unexpected object %T", obj)
+	}
+	// the routing table orders routes by age, a route being created is the newest of them all
+	object = object.DeepCopyObject().(client.Object)
+	if created := object.GetCreationTimestamp(); created.IsZero() {
+		object.SetCreationTimestamp(metav1.Now())
+	}
+	route, ok := routing.FromObject(object)
+	if !ok {
+		return nil, errors.Errorf("unexpected route %T", obj)
+	}
+
+	gateways, err := v.managedGateways(ctx, route)
+	if err != nil {
+		return nil, err
+	}
+	var warnings admission.Warnings
+	var errs field.ErrorList
+	for _, gateway := range gateways {
+		domain := ""
+		if config, err := k8s.LoadGatewayClassConfig(ctx, v.Client, gateway); err == nil {
+			domain = config.Domain
+		}
+		switch route := object.(type) {
+		case *gatewayv1.HTTPRoute:
+			errs = appendErrors(errs, routing.ValidateHTTPRoute(route, domain))
+		case *gatewayv1.GRPCRoute:
+			errs = appendErrors(errs, routing.ValidateGRPCRoute(route, domain))
+		case *gatewayv1alpha2.TLSRoute:
+			errs = appendErrors(errs, routing.ValidateTLSRoute(route, domain))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, v.invalid(object, errs)
+	}
+
+	for _, gateway := range gateways {
+		pending, err := newPendingReader(v.Client, v.Client.Scheme(), object)
+		if err != nil {
+			return nil, err
+		}
+		result, err := routing.NewBuilder(pending, gateway).Build(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to build routing table of gateway %s", client.ObjectKeyFromObject(gateway))
+		}
+		for _, conflict := range result.Conflicts(route) {
+			errs = appendErrors(errs, field.ErrorList{field.Forbidden(field.NewPath("spec", "rules").Index(conflict.RuleIndex), conflict.String())})
+		}
+		// the route may also take hostnames and paths from routes that already serve them, as it keeps its age
+		current, err := routing.NewBuilder(v.Client, gateway).Build(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to build routing table of gateway %s", client.ObjectKeyFromObject(gateway))
+		}
+		errs = appendErrors(errs, takenErrors(routing.TakenConflicts(current, result, route)))
+		for _, unsupported := range result.Unsupported(route) {
+			warnings = append(warnings, fmt.Sprintf("gateway %s: %s", client.ObjectKeyFromObject(gateway), unsupported))
+		}
+		for _, unresolved := range result.UnresolvedRefs(route) {
+			warnings = append(warnings, fmt.Sprintf("gateway %s: %s", client.ObjectKeyFromObject(gateway), unresolved.Message))
+		}
+	}
+	if len(errs) > 0 {
+		return warnings, v.invalid(object, errs)
+	}
+	return warnings, nil
+}
+
+// takenErrors rejects the hostnames and paths that the route would take from the routes that serve them
+func takenErrors(taken map[routing.RouteKey][]routing.Conflict) field.ErrorList {
+	losers := make([]routing.RouteKey, 0, len(taken))
+	for loser := range taken {
+		losers = append(losers, loser)
+	}
+	sort.Slice(losers, func(i, j int) bool {
+		return fmt.Sprint(losers[i]) < fmt.Sprint(losers[j])
+	})
+	var errs field.ErrorList
+	for _, loser := range losers {
+		for _, conflict := range taken[loser] {
+			path := conflict.Path
+			if path == "" {
+				path = "/"
+			}
+			errs = append(errs, field.Forbidden(field.NewPath("spec"), fmt.Sprintf(
+				"hostname %s path %s would be taken from rule %d of %s %s/%s, which already serves it",
+				conflict.Hostname, path, conflict.RuleIndex, loser.Kind, loser.Namespace, loser.Name,
+			)))
+		}
+	}
+	return errs
+}
+
+// managedGateways returns the Gateways of this controller that the parentRefs of route point at
+func (v *RouteValidator) managedGateways(ctx context.Context, route routing.Route) ([]*gatewayv1.Gateway, error) {
+	seen := map[types.NamespacedName]bool{}
+	var gateways []*gatewayv1.Gateway
+	for _, ref := range route.ParentRefs {
+		gateway, err := routing.ManagedGateway(ctx, v.Client, ref, route.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		if gateway == nil || seen[client.ObjectKeyFromObject(gateway)] {
+			continue
+		}
+		seen[client.ObjectKeyFromObject(gateway)] = true
+		gateways = append(gateways, gateway)
+	}
+	return gateways, nil
+}
+
+func (v *RouteValidator) invalid(obj client.Object, errs field.ErrorList) error {
+	gvk, err := apiutil.GVKForObject(obj, v.Client.Scheme())
+	if err != nil {
+		return errors.Wrap(err, "failed to get the kind of the route")
+	}
+	return apierrors.NewInvalid(gvk.GroupKind(), obj.GetName(), errs)
+}
+
+// appendErrors appends the errors of errs that are not in all yet, routes bound to several Gateways
+// are validated once for each of them
+func appendErrors(all field.ErrorList, errs field.ErrorList) field.ErrorList {
+	for _, err := range errs {
+		duplicate := false
+		for _, existing := range all {
+			duplicate = duplicate || existing.Error() == err.Error()
+		}
+		if !duplicate {
+			all = append(all, err)
+		}
+	}
+	return all
+}